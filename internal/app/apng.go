@@ -0,0 +1,300 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"time"
+)
+
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+
+	apngBlendSource = 0
+	apngBlendOver   = 1
+
+	// maxAPNGFrames caps the fcTL chunks read from an animation, before their size is known.
+	maxAPNGFrames = 2000
+)
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+type apngFrameControl struct {
+	width, height      int
+	xOffset, yOffset   int
+	delayNum, delayDen uint16
+	disposeOp          byte
+	blendOp            byte
+}
+
+type apngFrame struct {
+	control apngFrameControl
+	data    [][]byte
+}
+
+// IsAPNG reports whether path is a PNG with an animation control chunk.
+func IsAPNG(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(f, signature); err != nil || string(signature) != pngSignature {
+		return false
+	}
+
+	// acTL must come before the first IDAT.
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(f, header); err != nil {
+			return false
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		switch string(header[4:8]) {
+		case "acTL":
+			return true
+		case "IDAT", "IEND":
+			return false
+		}
+		if _, err := f.Seek(int64(length)+4, io.SeekCurrent); err != nil {
+			return false
+		}
+	}
+}
+
+// SplitAPNG decodes an animated PNG and returns composited frames plus per-frame durations.
+// Like SplitAnimatedGIF, frames are drawn onto a full-size RGBA canvas honoring the dispose and blend
+// operations, and the canvas is cloned after each frame.
+func SplitAPNG(r io.Reader) (frames []image.Image, delays []time.Duration, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic while decoding apng: %v", rec)
+		}
+	}()
+
+	chunks, err := readPNGChunks(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		ihdr       []byte
+		shared     []pngChunk
+		animFrames []apngFrame
+		current    *apngFrame
+		hasACTL    bool
+		seenIDAT   bool
+	)
+	for _, chunk := range chunks {
+		switch chunk.typ {
+		case "IHDR":
+			if len(chunk.data) != 13 {
+				return nil, nil, fmt.Errorf("apng: invalid IHDR")
+			}
+			ihdr = chunk.data
+		case "acTL":
+			hasACTL = true
+		case "fcTL":
+			if len(animFrames) >= maxAPNGFrames {
+				return nil, nil, fmt.Errorf("apng: animation has more than %d frames", maxAPNGFrames)
+			}
+			control, err := parseFrameControl(chunk.data)
+			if err != nil {
+				return nil, nil, err
+			}
+			animFrames = append(animFrames, apngFrame{control: control})
+			current = &animFrames[len(animFrames)-1]
+		case "IDAT":
+			seenIDAT = true
+			// IDAT before the first fcTL is a default image that is not part of the animation.
+			if current != nil {
+				current.data = append(current.data, chunk.data)
+			}
+		case "fdAT":
+			if current == nil || len(chunk.data) < 4 {
+				return nil, nil, fmt.Errorf("apng: fdAT without frame control")
+			}
+			current.data = append(current.data, chunk.data[4:])
+		case "IEND":
+		default:
+			// Palette, transparency and color space chunks precede IDAT and apply to every frame.
+			if !seenIDAT {
+				shared = append(shared, chunk)
+			}
+		}
+	}
+	if ihdr == nil {
+		return nil, nil, fmt.Errorf("apng: missing IHDR")
+	}
+	if !hasACTL || len(animFrames) == 0 {
+		return nil, nil, fmt.Errorf("png is not animated")
+	}
+
+	w := int(binary.BigEndian.Uint32(ihdr[0:4]))
+	h := int(binary.BigEndian.Uint32(ihdr[4:8]))
+	// Every frame is composited onto a full canvas copy, so the canvas size bounds each frame.
+	var budget frameBudget
+	if err := budget.reserve(len(animFrames), w, h); err != nil {
+		return nil, nil, fmt.Errorf("apng: %w", err)
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for i, frame := range animFrames {
+		c := frame.control
+		bounds := image.Rect(c.xOffset, c.yOffset, c.xOffset+c.width, c.yOffset+c.height)
+		if c.width <= 0 || c.height <= 0 || !bounds.In(canvas.Bounds()) {
+			return nil, nil, fmt.Errorf("apng: frame %d is outside the canvas", i)
+		}
+		if len(frame.data) == 0 {
+			return nil, nil, fmt.Errorf("apng: frame %d has no image data", i)
+		}
+
+		src, err := decodeAPNGFrame(ihdr, shared, frame)
+		if err != nil {
+			return nil, nil, fmt.Errorf("apng: frame %d: %w", i, err)
+		}
+
+		disposeOp := c.disposeOp
+		if i == 0 && disposeOp == apngDisposePrevious {
+			disposeOp = apngDisposeBackground
+		}
+
+		var prevCanvas *image.RGBA
+		if disposeOp == apngDisposePrevious {
+			prevCanvas = cloneRGBA(canvas)
+		}
+
+		op := draw.Src
+		if c.blendOp == apngBlendOver {
+			op = draw.Over
+		}
+		draw.Draw(canvas, bounds, src, src.Bounds().Min, op)
+		frames = append(frames, cloneRGBA(canvas))
+		delays = append(delays, apngDelay(c.delayNum, c.delayDen))
+
+		switch disposeOp {
+		case apngDisposeBackground:
+			draw.Draw(canvas, bounds, image.Transparent, image.Point{}, draw.Src)
+		case apngDisposePrevious:
+			canvas = prevCanvas
+		}
+	}
+
+	return frames, delays, nil
+}
+
+func readPNGChunks(r io.Reader) ([]pngChunk, error) {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(r, signature); err != nil {
+		return nil, err
+	}
+	if string(signature) != pngSignature {
+		return nil, fmt.Errorf("not a png file")
+	}
+
+	var chunks []pngChunk
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, fmt.Errorf("png: reading chunk header: %w", err)
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		if length > 1<<30 {
+			return nil, fmt.Errorf("png: chunk too large")
+		}
+		data := make([]byte, length+4)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("png: reading %s chunk: %w", header[4:8], err)
+		}
+
+		crc := crc32.NewIEEE()
+		crc.Write(header[4:8])
+		crc.Write(data[:length])
+		if crc.Sum32() != binary.BigEndian.Uint32(data[length:]) {
+			return nil, fmt.Errorf("png: %s chunk checksum mismatch", header[4:8])
+		}
+
+		chunk := pngChunk{typ: string(header[4:8]), data: data[:length]}
+		chunks = append(chunks, chunk)
+		if chunk.typ == "IEND" {
+			return chunks, nil
+		}
+	}
+}
+
+func parseFrameControl(data []byte) (apngFrameControl, error) {
+	if len(data) != 26 {
+		return apngFrameControl{}, fmt.Errorf("apng: invalid fcTL")
+	}
+	return apngFrameControl{
+		width:     int(binary.BigEndian.Uint32(data[4:8])),
+		height:    int(binary.BigEndian.Uint32(data[8:12])),
+		xOffset:   int(binary.BigEndian.Uint32(data[12:16])),
+		yOffset:   int(binary.BigEndian.Uint32(data[16:20])),
+		delayNum:  binary.BigEndian.Uint16(data[20:22]),
+		delayDen:  binary.BigEndian.Uint16(data[22:24]),
+		disposeOp: data[24],
+		blendOp:   data[25],
+	}, nil
+}
+
+// decodeAPNGFrame rebuilds a standalone PNG for one frame (IHDR with the frame size, the shared
+// chunks and the frame data as IDAT) and decodes it with image/png.
+func decodeAPNGFrame(ihdr []byte, shared []pngChunk, frame apngFrame) (image.Image, error) {
+	var buf bytes.Buffer
+	buf.WriteString(pngSignature)
+
+	frameHeader := bytes.Clone(ihdr)
+	binary.BigEndian.PutUint32(frameHeader[0:4], uint32(frame.control.width))
+	binary.BigEndian.PutUint32(frameHeader[4:8], uint32(frame.control.height))
+	writePNGChunk(&buf, "IHDR", frameHeader)
+
+	for _, chunk := range shared {
+		writePNGChunk(&buf, chunk.typ, chunk.data)
+	}
+	for _, data := range frame.data {
+		writePNGChunk(&buf, "IDAT", data)
+	}
+	writePNGChunk(&buf, "IEND", nil)
+
+	return png.Decode(&buf)
+}
+
+func writePNGChunk(w io.Writer, typ string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:8])
+	crc.Write(data)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+
+	_, _ = w.Write(header[:])
+	_, _ = w.Write(data)
+	_, _ = w.Write(sum[:])
+}
+
+// apngDelay converts the delay fraction in seconds, a zero denominator means 1/100 s.
+func apngDelay(num, den uint16) time.Duration {
+	if den == 0 {
+		den = 100
+	}
+	return time.Duration(num) * time.Second / time.Duration(den)
+}
@@ -3,6 +3,8 @@ package app
 import (
 	"strings"
 
+	"github.com/joaoheitorgarcia/Mezzotone/mezzotone"
+
 	"charm.land/lipgloss/v2"
 )
 
@@ -17,7 +19,7 @@ func buildRenderHelpText(style styleVariables) string {
 	separatorString := "                                                                                   "
 	separator := lipgloss.NewStyle().Underline(true).Render(separatorString)
 
-	return strings.Join([]string{
+	lines := []string{
 		sectionStyle.Render("CONTROLS"),
 		"",
 		sectionStyle.Render("* Global"),
@@ -31,6 +33,7 @@ func buildRenderHelpText(style styleVariables) string {
 		helpBinding("pgup", "Go To Top", keyStyle, descriptionStyle),
 		helpBinding("enter/right", "Open directory or select image", keyStyle, descriptionStyle),
 		helpBinding("left/backspace", "Go back directory", keyStyle, descriptionStyle),
+		helpBinding("s", "Use current directory as image sequence", keyStyle, descriptionStyle),
 		"",
 		sectionStyle.Render("* Render Options"),
 		helpBinding("j/k or up/down", "Navigate options", keyStyle, descriptionStyle),
@@ -42,7 +45,7 @@ func buildRenderHelpText(style styleVariables) string {
 		helpBinding("esc", "Cancel edit or go back to file picker", keyStyle, descriptionStyle),
 		"",
 		sectionStyle.Render("* Render View"),
-		helpBinding("arrows", "Scroll output/help", keyStyle, descriptionStyle),
+		helpBinding("arrows, j/k", "Scroll output/help", keyStyle, descriptionStyle),
 		helpBinding("h", "Hide help", keyStyle, descriptionStyle),
 		helpBinding("f", "Toggle Fullscreen", keyStyle, descriptionStyle),
 		helpBinding("pgdown", "Go To Bottom", keyStyle, descriptionStyle),
@@ -52,11 +55,32 @@ func buildRenderHelpText(style styleVariables) string {
 		helpBinding("shift+left", "Go To Left", keyStyle, descriptionStyle),
 		helpBinding("shift+right", "Go To Right", keyStyle, descriptionStyle),
 		"",
-		helpBinding("c", "Copy to clipboard", keyStyle, descriptionStyle),
-		helpBinding("t", "Export to txt", keyStyle, descriptionStyle),
+		helpBinding("c", "Copy plain text to clipboard", keyStyle, descriptionStyle),
+		helpBinding("C", "Copy ANSI colored text to clipboard", keyStyle, descriptionStyle),
+		helpBinding("t", "Export to plain txt", keyStyle, descriptionStyle),
+		helpBinding("T", "Export to ans (ANSI colors)", keyStyle, descriptionStyle),
+		helpBinding("A", "Export to ANSI art (CP437, SAUCE)", keyStyle, descriptionStyle),
 		helpBinding("i", "Export to image", keyStyle, descriptionStyle),
 		helpBinding("g", "Export to gif", keyStyle, descriptionStyle),
-		helpBinding("v", "Export to video", keyStyle, descriptionStyle),
+		helpBinding("r", "Export to asciinema recording (.cast)", keyStyle, descriptionStyle),
+		helpBinding("a", "Export to animated png", keyStyle, descriptionStyle),
+		helpBinding("S", "Export to svg (selectable text, embedded font)", keyStyle, descriptionStyle),
+		helpBinding("p", "Export to pdf (vector, embedded font)", keyStyle, descriptionStyle),
+		helpBinding("m", "Export cell grid to json (M for binary .mzg)", keyStyle, descriptionStyle),
+		helpBinding("H", "Export to html page (selectable text)", keyStyle, descriptionStyle),
+		helpBinding("P", "Export to html <pre> fragment", keyStyle, descriptionStyle),
+		helpBinding("w", "Export animation to svg (CSS keyframes)", keyStyle, descriptionStyle),
+		helpBinding("W", "Export animation to html page", keyStyle, descriptionStyle),
+		helpBinding("v", "Export to video (Motion-JPEG .avi)", keyStyle, descriptionStyle),
+		helpBinding("V", "Export to lossless video (.y4m)", keyStyle, descriptionStyle),
+		helpBinding("o", "Pick the export folder", keyStyle, descriptionStyle),
+		"",
+		sectionStyle.Render("* Export Options"),
+		"  " + descriptionStyle.Render("Every export except grids and the clipboard opens this panel first."),
+		"  " + descriptionStyle.Render("Each export only lists the settings it uses."),
+		helpBinding("enter", "Edit fields / run the export on confirm", keyStyle, descriptionStyle),
+		helpBinding("esc", "Cancel edit or cancel the export", keyStyle, descriptionStyle),
+		helpBinding("o", "Pick the export folder (enter selects, s uses the current one)", keyStyle, descriptionStyle),
 		"",
 		separator,
 		"",
@@ -84,8 +108,99 @@ func buildRenderHelpText(style styleVariables) string {
 		"  " + descriptionStyle.Render("Applies stronger luminance contrast before glyph mapping."),
 		"  " + descriptionStyle.Render("May improve render quality or edge detection depending on images."),
 		"",
+		sectionStyle.Render("Color Estimator"),
+		"  " + descriptionStyle.Render("How the pixels of a cell are reduced to one color."),
+		"  " + descriptionStyle.Render("MEAN: arithmetic mean, mixed cells turn muddy."),
+		"  " + descriptionStyle.Render("MEDIAN: per channel median, ignores small outliers."),
+		"  " + descriptionStyle.Render("SATURATED: the most saturated pixel of the cell."),
+		"  " + descriptionStyle.Render("DOMINANT: biggest k-means cluster (k=2..3), keeps edges crisp."),
+		"  " + descriptionStyle.Render("INK: color sampled where the chosen glyph has ink."),
+		"",
+		sectionStyle.Render("Color Mode"),
+		"  " + descriptionStyle.Render("How cell colors are picked when Render Color is on."),
+		"  " + descriptionStyle.Render("AVERAGE: raw average color of the cell."),
+		"  " + descriptionStyle.Render("TINT: luminance mapped from black to one color (e.g. #33FF66)."),
+		"  " + descriptionStyle.Render("DUOTONE: luminance mapped from a shadow color to a highlight color."),
+		"  " + descriptionStyle.Render("GRADIENT: luminance mapped through 2 or more evenly spaced colors."),
+		"",
+		sectionStyle.Render("Color Stops"),
+		"  " + descriptionStyle.Render("Comma separated hex colors used by TINT, DUOTONE and GRADIENT."),
+		"  " + descriptionStyle.Render("Leave empty to use the mode defaults."),
+		"",
+		sectionStyle.Render("Saturation"),
+		"  " + descriptionStyle.Render("AVERAGE only. 1 keeps colors, 0 is grayscale, >1 boosts them."),
+		"",
+		sectionStyle.Render("Vibrance"),
+		"  " + descriptionStyle.Render("AVERAGE only. -1..1, boosts dull colors more than saturated ones."),
+		"",
+		sectionStyle.Render("Sequence FPS"),
+		"  " + descriptionStyle.Render("Playback rate of numbered image sequences (frame_0001.png ...)."),
+		"  " + descriptionStyle.Render("GIF and Y4M keep their own timing."),
+		"",
+		sectionStyle.Render("Export Colors"),
+		"  " + descriptionStyle.Render("Foreground and Background are hex colors (#RGB or #RRGGBB)."),
+		"  " + descriptionStyle.Render("Cells with their own colors keep them when Render Color is on."),
+		"",
+		sectionStyle.Render("Fallback Fonts"),
+		"  " + descriptionStyle.Render("Fonts for runes the export font lacks, separated like PATH (.ttf .otf .ttc)."),
+		"  " + descriptionStyle.Render("Runes no font has are listed when the export finishes."),
+		"",
+		sectionStyle.Render("Export Padding and Scale"),
+		"  " + descriptionStyle.Render("Padding adds background pixels around image, gif and video exports."),
+		"  " + descriptionStyle.Render("Scale resizes them last, whole numbers keep pixels sharp."),
+		"",
+		sectionStyle.Render("Aspect Correction"),
+		"  " + descriptionStyle.Render("Stretches exports so cells match the Font Aspect of the render."),
+		"",
+		sectionStyle.Render("Font Weight and Width"),
+		"  " + descriptionStyle.Render("wght and wdth of variable export fonts, empty Weight keeps the default."),
+		"  " + descriptionStyle.Render("Width AUTO narrows glyphs to the aspect correction instead of stretching."),
+		"",
+		sectionStyle.Render("Cell Size and Line Spacing"),
+		"  " + descriptionStyle.Render("Cell Size is WxH in pixels (10x24, 10x or x24), empty uses the font."),
+		"  " + descriptionStyle.Render("A cell width skips the stretch, Line Spacing multiplies the line height."),
+		"",
+		sectionStyle.Render("Output Dir and File Name"),
+		"  " + descriptionStyle.Render("Where every export goes, empty Output Dir is the home directory."),
+		"  " + descriptionStyle.Render("File Name placeholders: {name} {mode} {cols} {rows} {date} {n} {uuid}."),
+		"",
+		sectionStyle.Render("On Conflict"),
+		"  " + descriptionStyle.Render("INCREMENT counts {n} up to a free name, OVERWRITE replaces the file."),
+		"",
+		sectionStyle.Render("GIF Palette and Dither"),
+		"  " + descriptionStyle.Render("MEDIAN_CUT and OCTREE build the palette from the colors the glyphs use."),
+		"  " + descriptionStyle.Render("PLAN9 is the fixed palette. Dither spreads the error (Floyd-Steinberg)."),
+		"",
+		sectionStyle.Render("GIF Loops, Timing, FPS and Speed"),
+		"  " + descriptionStyle.Render("Loops: empty keeps the source, 0 loops forever, N plays N times."),
+		"  " + descriptionStyle.Render("KEEP stores the delays, BROWSER raises 0-10 ms to the 100 ms browsers use."),
+		"  " + descriptionStyle.Render("FIXED_FPS gives every frame 1/FPS. Speed divides every duration."),
+		"  " + descriptionStyle.Render("Size Report also encodes the frames unoptimized to show the bytes saved."),
+		"",
+		sectionStyle.Render("Line Ending, Trim Spaces, Final Reset and Color Depth"),
+		"  " + descriptionStyle.Render("Text settings of txt, ans and asciicast exports, c/C copy with them too."),
+		"  " + descriptionStyle.Render("Color Depth picks TRUECOLOR, 256 or 16 color SGR codes."),
+		"",
+		sectionStyle.Render("SAUCE Author, Group, Font and iCE Colors"),
+		"  " + descriptionStyle.Render("ANSI art metadata, the title is the source file name."),
+		"  " + descriptionStyle.Render("iCE Colors allows 16 backgrounds, off keeps them to the 8 dark ones."),
+		"",
+		sectionStyle.Render("Paper Size, Landscape and Margin"),
+		"  " + descriptionStyle.Render("PDF page, FIT sizes it to the grid, paper sizes scale and center it."),
+		"  " + descriptionStyle.Render("Margin is in points around the grid."),
+		"",
+		sectionStyle.Render("HTML Theme, Font Family and Embed Font"),
+		"  " + descriptionStyle.Render("AUTO follows the viewer color scheme, DARK and LIGHT fix it."),
+		"  " + descriptionStyle.Render("Font Family is the CSS font stack of svg and html, empty uses Noto Sans Mono."),
+		"  " + descriptionStyle.Render("Embed Font puts the used glyphs in the file, off only references the stack."),
+		"",
 		sectionStyle.Render("Rune Mode"),
 		"  " + descriptionStyle.Render("Selector for what type of characters will be renderer."),
-		"  " + descriptionStyle.Render("Available options: ASCII, UNICODE, DOTS, RECTANGLES, BARS."),
-	}, "\n")
+		"  " + descriptionStyle.Render("Available options:"),
+	}
+	for _, renderer := range mezzotone.Renderers() {
+		lines = append(lines, helpBinding(renderer.Name(), renderer.Description(), keyStyle, descriptionStyle))
+	}
+
+	return strings.Join(lines, "\n")
 }
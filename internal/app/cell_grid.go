@@ -0,0 +1,139 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/export"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/services"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/ui"
+	"github.com/joaoheitorgarcia/Mezzotone/mezzotone"
+)
+
+const (
+	gridFormatJSON   = "json"
+	gridFormatBinary = "binary"
+)
+
+type gridExportDoneMsg struct {
+	outPath string
+	err     error
+}
+
+// isGridFile reports whether path has a cell grid extension, those open without a conversion.
+func isGridFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == export.GridJSONExtension || ext == export.GridBinaryExtension
+}
+
+// gridDocument collects the current render, its ramp and render settings for a cell grid export.
+func (m *MezzotoneModel) gridDocument() export.GridDocument {
+	doc := export.GridDocument{Options: make(map[string]string, len(m.renderSettings.Items))}
+	for _, item := range m.renderSettings.Items {
+		doc.Options[item.Key] = item.Value
+		if item.Key == "runeMode" {
+			if renderer, ok := mezzotone.LookupRenderer(item.Value); ok {
+				if ramp, ok := renderer.(mezzotone.RampRenderer); ok {
+					doc.Ramp = ramp.Ramp()
+				}
+			}
+		}
+	}
+
+	if m.renderedImgOutput.renderedCanvas != nil {
+		doc.Frames = []export.ASCIIGIFFrame{{Canvas: m.renderedImgOutput.renderedCanvas}}
+	} else {
+		doc.Frames = m.animationExportFrames()
+	}
+	return doc
+}
+
+func exportGridCmd(outPath string, format string, doc export.GridDocument) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				msg = gridExportDoneMsg{
+					outPath: outPath,
+					err:     fmt.Errorf("grid export panic: %v", rec),
+				}
+			}
+		}()
+
+		var err error
+		switch format {
+		case gridFormatJSON:
+			err = export.GridToJSON(doc, outPath)
+		case gridFormatBinary:
+			err = export.GridToBinary(doc, outPath)
+		default:
+			err = fmt.Errorf("unknown grid format: %s", format)
+		}
+
+		msg = gridExportDoneMsg{
+			outPath: outPath,
+			err:     err,
+		}
+		return msg
+	}
+}
+
+// openGridFile loads a cell grid export straight into the render view. Render settings stored in the file
+// are restored, so a new render of the same source matches the imported one.
+func (m *MezzotoneModel) openGridFile(path string) (tea.Cmd, error) {
+	doc, err := export.ReadGridFile(path)
+	if err != nil {
+		return nil, err
+	}
+	_ = services.Logger().Info(fmt.Sprintf("Opened Grid: %s (%d frames)", path, len(doc.Frames)))
+
+	for i := range m.renderSettings.Items {
+		if value, ok := doc.Options[m.renderSettings.Items[i].Key]; ok {
+			m.renderSettings.Items[i].Value = value
+		}
+	}
+
+	m.selectedFile = path
+	m.currentActiveMenu = renderView
+	m.updateMessageTextOnMenuChange()
+
+	if len(doc.Frames) == 1 {
+		m.renderedImgOutput.renderedCanvas = doc.Frames[0].Canvas
+		m.renderedGifOutput = renderedGifOutput{}
+		m.gifAnimation.StopAnimation()
+
+		m.renderContent = gridViewContent(doc.Frames[0].Canvas)
+		if !m.helpVisible {
+			m.renderView.SetContent(m.renderContent)
+		}
+		return nil, nil
+	}
+
+	m.renderedImgOutput.renderedCanvas = nil
+	m.renderedGifOutput = renderedGifOutput{}
+	animationFrames := make([]ui.AnimationFrame, 0, len(doc.Frames))
+	for _, frame := range doc.Frames {
+		m.renderedGifOutput.renderedFrames = append(m.renderedGifOutput.renderedFrames, frame.Canvas)
+		m.renderedGifOutput.delayTimes = append(m.renderedGifOutput.delayTimes, frame.Duration)
+		animationFrames = append(animationFrames, ui.AnimationFrame{
+			Frame:    gridViewContent(frame.Canvas),
+			Duration: frame.Duration,
+		})
+	}
+	m.gifAnimation = ui.NewAnimationRenderer(animationFrames, []string{"esc"})
+	return m.gifAnimation.StartAnimation, nil
+}
+
+// gridViewContent renders c like a conversion result, with ANSI styling only when cells carry colors.
+func gridViewContent(c *canvas.Canvas) string {
+	for y := 0; y < c.Height(); y++ {
+		for _, cell := range c.Row(y) {
+			if cell.FG.A > 0 || cell.BG.A > 0 || cell.Attrs != 0 {
+				return c.ANSI()
+			}
+		}
+	}
+	return c.PlainText()
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/export"
+	"github.com/joaoheitorgarcia/Mezzotone/mezzotone"
+)
+
+// ExportAPNG renders config.InputPath with the default render options and writes it to outPath as an
+// animated PNG without starting the TUI. Still images become a single frame.
+func ExportAPNG(config MezzotoneModelConfig, outPath string) error {
+	if config.InputPath == "" {
+		return fmt.Errorf("no input to export")
+	}
+
+	m := NewMezzotoneModelWithConfig(config)
+	options, err := normalizeRenderOptionsForService(m.renderSettings.Items)
+	if err != nil {
+		return err
+	}
+
+	images, delays, _, isAnimation, err := loadAnimationFrames(config.InputPath, m.getSequenceFPS())
+	if err != nil {
+		return err
+	}
+	if !isAnimation {
+		img, err := decodeImageFile(config.InputPath)
+		if err != nil {
+			return err
+		}
+		images = append(images, img)
+		delays = append(delays, stillVideoDuration)
+	}
+
+	frames := make([]export.ASCIIGIFFrame, 0, len(images))
+	for i, img := range images {
+		result, err := mezzotone.Convert(img, mezzotone.WithOptions(options))
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", i, err)
+		}
+		frames = append(frames, export.ASCIIGIFFrame{Canvas: result.Canvas(), Duration: delays[i]})
+	}
+
+	exportOptions, err := m.asciiExportOptions()
+	if err != nil {
+		return err
+	}
+	return export.ASCIIFramesToAPNG(frames, outPath, exportOptions)
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+const (
+	conflictIncrement = "INCREMENT"
+	conflictOverwrite = "OVERWRITE"
+)
+
+// defaultFileNameTemplate keeps the Mezzotone_<uuid> names exports always had.
+const defaultFileNameTemplate = "Mezzotone_{uuid}"
+
+// maxExportCounter bounds the search for a free {n}.
+const maxExportCounter = 10000
+
+// exportPath resolves where an export with extension ext is written, from the Output Dir, File Name and
+// On Conflict export settings. An empty Output Dir is the home directory, it is created when missing.
+func (m *MezzotoneModel) exportPath(ext string) (string, error) {
+	dir, err := m.exportOutputDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	template := m.exportSetting("fileName")
+	policy := m.exportSetting("onConflict")
+	return resolveExportPath(dir, template, ext, policy, m.fileNameFields())
+}
+
+// exportOutputDir returns the Output Dir export setting with ~ expanded, or the home directory when empty.
+func (m *MezzotoneModel) exportOutputDir() (string, error) {
+	dir := strings.TrimSpace(m.exportSetting("outputDir"))
+	if dir != "" && dir != "~" && !strings.HasPrefix(dir, "~"+string(filepath.Separator)) {
+		return dir, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(dir, "~")), nil
+}
+
+func (m *MezzotoneModel) exportSetting(key string) string {
+	for _, item := range m.exportSettings.Items {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return ""
+}
+
+func (m *MezzotoneModel) setExportSetting(key, value string) {
+	for i := range m.exportSettings.Items {
+		if m.exportSettings.Items[i].Key == key {
+			m.exportSettings.Items[i].Value = value
+		}
+	}
+}
+
+// fileNameFields are the placeholder values of the file name template, {n} is filled in by resolveExportPath.
+func (m *MezzotoneModel) fileNameFields() map[string]string {
+	name := "Mezzotone"
+	if m.selectedFile != "" {
+		base := filepath.Base(m.selectedFile)
+		name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+
+	cols, rows := 0, 0
+	if c := m.currentRenderCanvas(); c != nil {
+		cols, rows = c.Width(), c.Height()
+	}
+
+	mode := ""
+	for _, item := range m.renderSettings.Items {
+		if item.Key == "runeMode" {
+			mode = item.Value
+		}
+	}
+
+	return map[string]string{
+		"{name}": sanitizeFileNamePart(name),
+		"{mode}": sanitizeFileNamePart(mode),
+		"{cols}": strconv.Itoa(cols),
+		"{rows}": strconv.Itoa(rows),
+		"{date}": time.Now().Format("2006-01-02"),
+		"{uuid}": newUUID().String(),
+	}
+}
+
+// resolveExportPath expands template in dir. With the increment policy {n} counts up from 1 until the
+// path is free, templates without {n} get a _2, _3 ... suffix instead. Overwrite uses n = 1.
+// Increment reserves the free path by creating it empty, so an export started before the previous one
+// has written its file still gets a name of its own. releaseExportPath undoes that for a failed export.
+func resolveExportPath(dir, template, ext, policy string, fields map[string]string) (string, error) {
+	template = strings.TrimSpace(template)
+	if template == "" {
+		template = defaultFileNameTemplate
+	}
+	if strings.ContainsAny(template, `/\`) {
+		return "", fmt.Errorf("file name template must not contain path separators, use Output Dir")
+	}
+
+	hasCounter := strings.Contains(template, "{n}")
+	for n := 1; n <= maxExportCounter; n++ {
+		name := expandFileNameTemplate(template, fields, n)
+		if !hasCounter && n > 1 {
+			name += "_" + strconv.Itoa(n)
+		}
+		path := filepath.Join(dir, name+ext)
+		if policy == conflictOverwrite {
+			return path, nil
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			return path, f.Close()
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no free file name for %q in %s", template, dir)
+}
+
+// releaseExportPath removes the file at path when it is still empty, the reservation of an export that
+// failed before writing anything.
+func releaseExportPath(path string) {
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.Size() == 0 {
+		_ = os.Remove(path)
+	}
+}
+
+func expandFileNameTemplate(template string, fields map[string]string, n int) string {
+	oldNew := make([]string, 0, 2*len(fields)+2)
+	for placeholder, value := range fields {
+		oldNew = append(oldNew, placeholder, value)
+	}
+	oldNew = append(oldNew, "{n}", strconv.Itoa(n))
+	return strings.NewReplacer(oldNew...).Replace(template)
+}
+
+// sanitizeFileNamePart replaces characters that are not portable in file names, like the * of a glob input.
+func sanitizeFileNamePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|%`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// openSaveAs shows the directory picker in place of the file picker, the picked folder becomes Output Dir.
+func (m *MezzotoneModel) openSaveAs() tea.Cmd {
+	if dir, err := m.exportOutputDir(); err == nil {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			m.savePicker.CurrentDirectory = dir
+		}
+	}
+
+	m.saveAsPreviousMenu = m.currentActiveMenu
+	m.currentActiveMenu = saveAsMenu
+	m.updateMessageTextOnMenuChange()
+	return m.savePicker.Init()
+}
+
+func (m *MezzotoneModel) closeSaveAs(dir string) {
+	m.currentActiveMenu = m.saveAsPreviousMenu
+	m.updateMessageTextOnMenuChange()
+	if dir != "" {
+		m.setExportSetting("outputDir", dir)
+		m.updateMessageViewPortContent("Exports will be saved to "+dir, false)
+	}
+}
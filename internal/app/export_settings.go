@@ -0,0 +1,650 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/export"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/ui"
+	"github.com/joaoheitorgarcia/Mezzotone/mezzotone"
+)
+
+// fontWidthAuto lets raster exports pick the width axis of a variable export font that matches the
+// aspect correction, so cells need no horizontal stretching.
+const fontWidthAuto = "AUTO"
+
+const (
+	// fontExportKeys are the exports drawn with the export font, they read asciiExportOptions.
+	fontExportKeys = "iSpHPwWgavV"
+	// rasterExportKeys are the font exports that rasterize the canvas.
+	rasterExportKeys = "igavV"
+	// exportPanelKeys are all exports that open the export settings first.
+	exportPanelKeys = fontExportKeys + "AtTr"
+)
+
+// Text export choices of the export panel.
+const (
+	lineEndingLF   = "LF"
+	lineEndingCRLF = "CRLF"
+
+	colorDepthTrue = "TRUECOLOR"
+	colorDepth256  = "256"
+	colorDepth16   = "16"
+
+	htmlThemeAuto  = "AUTO"
+	htmlThemeDark  = "DARK"
+	htmlThemeLight = "LIGHT"
+
+	// paperSizeFit sizes pdf pages to the grid instead of a paper size.
+	paperSizeFit = "FIT"
+)
+
+// exportSettingScopes lists the exports that show a setting, settings missing here show for every export.
+var exportSettingScopes = map[string]string{
+	"fontSize":         fontExportKeys,
+	"dpi":              fontExportKeys,
+	"fg":               fontExportKeys,
+	"bg":               fontExportKeys,
+	"padding":          rasterExportKeys,
+	"fontPath":         fontExportKeys,
+	"fallbackFonts":    fontExportKeys,
+	"fontWeight":       rasterExportKeys,
+	"fontWidth":        rasterExportKeys,
+	"cellSize":         rasterExportKeys,
+	"lineSpacing":      rasterExportKeys,
+	"scale":            rasterExportKeys,
+	"aspectCorrection": fontExportKeys,
+	"gifPalette":       "g",
+	"gifDither":        "g",
+	"gifLoops":         "g",
+	"gifTiming":        "g",
+	"gifFPS":           "g",
+	"gifSpeed":         "g",
+	"gifSizeReport":    "g",
+	"lineEnding":       "tT",
+	"trimSpaces":       "tT",
+	"finalReset":       "T",
+	"colorDepth":       "Tr",
+	"sauceAuthor":      "A",
+	"sauceGroup":       "A",
+	"sauceFont":        "A",
+	"iceColors":        "A",
+	"paperSize":        "p",
+	"landscape":        "p",
+	"pdfMargin":        "p",
+	"htmlTheme":        "HP",
+	"fontFamily":       "SHPwW",
+	"embedFont":        "SHwW",
+}
+
+// exportSettingShown reports whether the setting key belongs in the export panel of exportKey.
+func exportSettingShown(key, exportKey string) bool {
+	scope, ok := exportSettingScopes[key]
+	return !ok || strings.Contains(scope, exportKey)
+}
+
+// newExportSettingsPanel builds the options of the exports that open the export panel, and the
+// destination every export is written to. Each export only shows the settings it reads. The panel lives on the model, so its values carry over from one export to the next.
+func newExportSettingsPanel(config MezzotoneModelConfig, styles ui.RenderSettingsStyles) ui.SettingsPanel {
+	fileNameTemplate := defaultFileNameTemplate
+	if template := strings.TrimSpace(config.FileNameTemplate); template != "" {
+		fileNameTemplate = template
+	}
+	items := []ui.SettingItem{
+		{Label: "Font Size", Key: "fontSize", Type: ui.TypeInt, Value: "14"},
+		{Label: "DPI", Key: "dpi", Type: ui.TypeInt, Value: "300"},
+		{Label: "Foreground", Key: "fg", Type: ui.TypeString, Value: "#FFFFFF"},
+		{Label: "Background", Key: "bg", Type: ui.TypeString, Value: "#000000"},
+		{Label: "Padding", Key: "padding", Type: ui.TypeInt, Value: "0"},
+		{Label: "Font Path", Key: "fontPath", Type: ui.TypeString, Value: strings.TrimSpace(config.ExportFontTTFPath)},
+		{Label: "Fallback Fonts", Key: "fallbackFonts", Type: ui.TypeString, Value: strings.TrimSpace(config.ExportFallbackFonts)},
+		{Label: "Font Weight", Key: "fontWeight", Type: ui.TypeString, Value: ""},
+		{Label: "Font Width", Key: "fontWidth", Type: ui.TypeString, Value: fontWidthAuto},
+		{Label: "Cell Size", Key: "cellSize", Type: ui.TypeString, Value: ""},
+		{Label: "Line Spacing", Key: "lineSpacing", Type: ui.TypeFloat, Value: "1.0"},
+		{Label: "Scale", Key: "scale", Type: ui.TypeFloat, Value: "1.0"},
+		{Label: "Aspect Correction", Key: "aspectCorrection", Type: ui.TypeBool, Value: "TRUE"},
+		{Label: "Output Dir", Key: "outputDir", Type: ui.TypeString, Value: strings.TrimSpace(config.OutputDir)},
+		{Label: "File Name", Key: "fileName", Type: ui.TypeString, Value: fileNameTemplate},
+		{Label: "On Conflict", Key: "onConflict", Type: ui.TypeEnum, Value: conflictIncrement, Enum: []string{conflictIncrement, conflictOverwrite}},
+		{Label: "GIF Palette", Key: "gifPalette", Type: ui.TypeEnum, Value: export.GIFPaletteMedianCut, Enum: export.GIFPalettes()},
+		{Label: "GIF Dither", Key: "gifDither", Type: ui.TypeBool, Value: "FALSE"},
+		{Label: "GIF Loops", Key: "gifLoops", Type: ui.TypeString, Value: ""},
+		{Label: "GIF Timing", Key: "gifTiming", Type: ui.TypeEnum, Value: export.GIFDelayBrowser, Enum: export.GIFDelayPolicies()},
+		{Label: "GIF FPS", Key: "gifFPS", Type: ui.TypeFloat, Value: "10"},
+		{Label: "GIF Speed", Key: "gifSpeed", Type: ui.TypeFloat, Value: "1.0"},
+		{Label: "GIF Size Report", Key: "gifSizeReport", Type: ui.TypeBool, Value: "FALSE"},
+		{Label: "Line Ending", Key: "lineEnding", Type: ui.TypeEnum, Value: lineEndingLF, Enum: []string{lineEndingLF, lineEndingCRLF}},
+		{Label: "Trim Spaces", Key: "trimSpaces", Type: ui.TypeBool, Value: "TRUE"},
+		{Label: "Final Reset", Key: "finalReset", Type: ui.TypeBool, Value: "TRUE"},
+		{Label: "Color Depth", Key: "colorDepth", Type: ui.TypeEnum, Value: colorDepthTrue, Enum: []string{colorDepthTrue, colorDepth256, colorDepth16}},
+		{Label: "SAUCE Author", Key: "sauceAuthor", Type: ui.TypeString, Value: ""},
+		{Label: "SAUCE Group", Key: "sauceGroup", Type: ui.TypeString, Value: ""},
+		{Label: "SAUCE Font", Key: "sauceFont", Type: ui.TypeString, Value: export.DefaultANSIArtFont},
+		{Label: "iCE Colors", Key: "iceColors", Type: ui.TypeBool, Value: "TRUE"},
+		{Label: "Paper Size", Key: "paperSize", Type: ui.TypeEnum, Value: paperSizeFit, Enum: append([]string{paperSizeFit}, export.PDFPaperSizes()...)},
+		{Label: "Landscape", Key: "landscape", Type: ui.TypeBool, Value: "FALSE"},
+		{Label: "Margin", Key: "pdfMargin", Type: ui.TypeFloat, Value: strconv.Itoa(defaultPDFMargin)},
+		{Label: "HTML Theme", Key: "htmlTheme", Type: ui.TypeEnum, Value: htmlThemeAuto, Enum: []string{htmlThemeAuto, htmlThemeDark, htmlThemeLight}},
+		{Label: "Font Family", Key: "fontFamily", Type: ui.TypeString, Value: ""},
+		{Label: "Embed Font", Key: "embedFont", Type: ui.TypeBool, Value: "TRUE"},
+	}
+	panel := ui.NewSettingsPanel("Export Options", items, styles)
+	panel.ClearActive()
+	return panel
+}
+
+// asciiExportOptions builds the rasterizer options from the export settings panel.
+// Aspect correction turns the render font aspect into TargetAspect. Empty Font Weight and Cell Size keep
+// the font defaults.
+func (m *MezzotoneModel) asciiExportOptions() (export.ASCIIExportOptions, error) {
+	exportOptions := export.ASCIIExportOptions{RenderColor: m.getRenderColor()}
+
+	for _, item := range m.exportSettings.Items {
+		switch item.Key {
+		case "fontSize":
+			exportOptions.FontSize, _ = strconv.Atoi(item.Value)
+			if exportOptions.FontSize <= 0 {
+				return export.ASCIIExportOptions{}, fmt.Errorf("font size must be positive")
+			}
+		case "dpi":
+			exportOptions.DPI, _ = strconv.Atoi(item.Value)
+			if exportOptions.DPI <= 0 {
+				return export.ASCIIExportOptions{}, fmt.Errorf("dpi must be positive")
+			}
+		case "fg", "bg":
+			c, err := mezzotone.ParseHexColor(item.Value)
+			if err != nil {
+				return export.ASCIIExportOptions{}, err
+			}
+			if item.Key == "fg" {
+				exportOptions.FG = c
+			} else {
+				exportOptions.BG = c
+			}
+		case "padding":
+			exportOptions.Padding, _ = strconv.Atoi(item.Value)
+			if exportOptions.Padding < 0 {
+				return export.ASCIIExportOptions{}, fmt.Errorf("padding must not be negative")
+			}
+		case "fontPath":
+			exportOptions.FontTTFPath = strings.TrimSpace(item.Value)
+			if exportOptions.FontTTFPath != "" {
+				if _, err := os.Stat(exportOptions.FontTTFPath); err != nil {
+					return export.ASCIIExportOptions{}, fmt.Errorf("export font: %w", err)
+				}
+			}
+		case "fallbackFonts":
+			for _, path := range filepath.SplitList(item.Value) {
+				path = strings.TrimSpace(path)
+				if path == "" {
+					continue
+				}
+				if _, err := os.Stat(path); err != nil {
+					return export.ASCIIExportOptions{}, fmt.Errorf("fallback font: %w", err)
+				}
+				exportOptions.FallbackFontPaths = append(exportOptions.FallbackFontPaths, path)
+			}
+		case "fontWeight":
+			if weight := strings.TrimSpace(item.Value); weight != "" {
+				exportOptions.FontWeight, _ = strconv.ParseFloat(weight, 64)
+				if exportOptions.FontWeight <= 0 {
+					return export.ASCIIExportOptions{}, fmt.Errorf("font weight must be empty or positive")
+				}
+			}
+		case "fontWidth":
+			width := strings.TrimSpace(item.Value)
+			if width == "" || strings.EqualFold(width, fontWidthAuto) {
+				exportOptions.FitWidthToAspect = true
+				continue
+			}
+			exportOptions.FontWidth, _ = strconv.ParseFloat(width, 64)
+			if exportOptions.FontWidth <= 0 {
+				return export.ASCIIExportOptions{}, fmt.Errorf("font width must be %s or positive", fontWidthAuto)
+			}
+		case "cellSize":
+			var err error
+			exportOptions.CellWidth, exportOptions.CellHeight, err = parseCellSize(item.Value)
+			if err != nil {
+				return export.ASCIIExportOptions{}, err
+			}
+		case "lineSpacing":
+			exportOptions.LineSpacing, _ = strconv.ParseFloat(item.Value, 64)
+			if exportOptions.LineSpacing <= 0 {
+				return export.ASCIIExportOptions{}, fmt.Errorf("line spacing must be positive")
+			}
+		case "scale":
+			exportOptions.Scale, _ = strconv.ParseFloat(item.Value, 64)
+			if exportOptions.Scale <= 0 {
+				return export.ASCIIExportOptions{}, fmt.Errorf("scale must be positive")
+			}
+		case "fontFamily":
+			// The stack is written into CSS as it is, so it must not end the declaration or the markup around it.
+			exportOptions.FontFamily = strings.TrimSpace(item.Value)
+			if strings.ContainsAny(exportOptions.FontFamily, `;{}<>&"`) {
+				return export.ASCIIExportOptions{}, fmt.Errorf(`font family must be a CSS font stack without ;{}<>&"`)
+			}
+		case "embedFont":
+			exportOptions.EmbedFont, _ = strconv.ParseBool(item.Value)
+		case "aspectCorrection":
+			if aspectCorrection, _ := strconv.ParseBool(item.Value); aspectCorrection {
+				// Font Aspect is height/width (2.3). Export wants width/height.
+				exportOptions.TargetAspect = 1.0 / m.getFontAspect()
+			}
+		}
+	}
+	return exportOptions, nil
+}
+
+// parseCellSize reads a WxH cell size in pixels. Either side may be left out, like 10x or x24, to keep
+// the font metrics for it.
+func parseCellSize(value string) (width, height int, err error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return 0, 0, nil
+	}
+
+	w, h, _ := strings.Cut(value, "x")
+	for _, side := range []struct {
+		text string
+		size *int
+	}{{w, &width}, {h, &height}} {
+		if side.text = strings.TrimSpace(side.text); side.text == "" {
+			continue
+		}
+		*side.size, _ = strconv.Atoi(side.text)
+		if *side.size <= 0 {
+			return 0, 0, fmt.Errorf("cell size must be WxH in pixels, like 10x24, 10x or x24")
+		}
+	}
+	return width, height, nil
+}
+
+// gifExportOptions reads the gif only settings of the export panel. An empty GIF Loops keeps the loop
+// count of the source, otherwise it is the number of plays with 0 looping forever.
+func (m *MezzotoneModel) gifExportOptions() (export.GIFExportOptions, error) {
+	gifOptions := export.GIFExportOptions{
+		Palette:     m.exportSetting("gifPalette"),
+		LoopCount:   m.renderedGifOutput.loopCount,
+		DelayPolicy: m.exportSetting("gifTiming"),
+	}
+	gifOptions.Dither, _ = strconv.ParseBool(m.exportSetting("gifDither"))
+	gifOptions.CompareSize, _ = strconv.ParseBool(m.exportSetting("gifSizeReport"))
+
+	if loops := strings.TrimSpace(m.exportSetting("gifLoops")); loops != "" {
+		plays, err := strconv.Atoi(loops)
+		if err != nil || plays < 0 {
+			return export.GIFExportOptions{}, fmt.Errorf("gif loops must be empty, 0 (forever) or a play count")
+		}
+		switch plays {
+		case 0:
+			gifOptions.LoopCount = 0
+		case 1:
+			gifOptions.LoopCount = -1
+		default:
+			gifOptions.LoopCount = plays - 1
+		}
+	}
+
+	gifOptions.FPS, _ = strconv.ParseFloat(m.exportSetting("gifFPS"), 64)
+	if gifOptions.DelayPolicy == export.GIFDelayFixedFPS && gifOptions.FPS <= 0 {
+		return export.GIFExportOptions{}, fmt.Errorf("gif fps must be positive")
+	}
+	gifOptions.Speed, _ = strconv.ParseFloat(m.exportSetting("gifSpeed"), 64)
+	if gifOptions.Speed <= 0 {
+		return export.GIFExportOptions{}, fmt.Errorf("gif speed must be positive")
+	}
+	return gifOptions, nil
+}
+
+// textExportOptions reads the text settings of the export panel, used by the txt, ans, asciicast and
+// clipboard exports.
+func (m *MezzotoneModel) textExportOptions() export.TextExportOptions {
+	textOptions := export.TextExportOptions{LineEnding: export.LineEndingLF, ColorDepth: export.ANSITrueColor}
+	if m.exportSetting("lineEnding") == lineEndingCRLF {
+		textOptions.LineEnding = export.LineEndingCRLF
+	}
+	textOptions.TrimTrailingSpace, _ = strconv.ParseBool(m.exportSetting("trimSpaces"))
+	textOptions.FinalReset, _ = strconv.ParseBool(m.exportSetting("finalReset"))
+	switch m.exportSetting("colorDepth") {
+	case colorDepth256:
+		textOptions.ColorDepth = export.ANSI256Color
+	case colorDepth16:
+		textOptions.ColorDepth = export.ANSI16Color
+	}
+	return textOptions
+}
+
+// pdfExportOptions reads the page settings of the export panel, the margin is in points.
+func (m *MezzotoneModel) pdfExportOptions() (export.PDFExportOptions, error) {
+	pdfOptions := export.PDFExportOptions{}
+	if paperSize := m.exportSetting("paperSize"); paperSize != paperSizeFit {
+		pdfOptions.PaperSize = paperSize
+	}
+	pdfOptions.Landscape, _ = strconv.ParseBool(m.exportSetting("landscape"))
+
+	margin, err := strconv.ParseFloat(strings.TrimSpace(m.exportSetting("pdfMargin")), 64)
+	if err != nil || margin < 0 {
+		return export.PDFExportOptions{}, fmt.Errorf("pdf margin must not be negative")
+	}
+	pdfOptions.Margin = margin
+	return pdfOptions, nil
+}
+
+// htmlTheme maps the HTML Theme setting of the export panel to its export theme.
+func (m *MezzotoneModel) htmlTheme() string {
+	switch m.exportSetting("htmlTheme") {
+	case htmlThemeDark:
+		return export.HTMLThemeDark
+	case htmlThemeLight:
+		return export.HTMLThemeLight
+	default:
+		return export.HTMLThemeAuto
+	}
+}
+
+// ansiArtOptions reads the SAUCE settings of the export panel, the title is the source file name.
+func (m *MezzotoneModel) ansiArtOptions() (export.ANSIArtOptions, error) {
+	ansiOptions := export.ANSIArtOptions{
+		Title:  strings.TrimSuffix(filepath.Base(m.selectedFile), filepath.Ext(m.selectedFile)),
+		Author: strings.TrimSpace(m.exportSetting("sauceAuthor")),
+		Group:  strings.TrimSpace(m.exportSetting("sauceGroup")),
+		Font:   strings.TrimSpace(m.exportSetting("sauceFont")),
+	}
+	ansiOptions.ICEColors, _ = strconv.ParseBool(m.exportSetting("iceColors"))
+
+	// SAUCE fields have a fixed size, longer values would be cut silently.
+	for _, field := range []struct {
+		name, value string
+		size        int
+	}{{"author", ansiOptions.Author, 20}, {"group", ansiOptions.Group, 20}, {"font", ansiOptions.Font, 22}} {
+		if n := len([]rune(field.value)); n > field.size {
+			return export.ANSIArtOptions{}, fmt.Errorf("sauce %s must be at most %d characters, got %d", field.name, field.size, n)
+		}
+	}
+	return ansiOptions, nil
+}
+
+// openExportSettings shows the export settings of exportKey in place of the render options, exportKey runs on confirm.
+func (m *MezzotoneModel) openExportSettings(exportKey string) {
+	m.pendingExport = exportKey
+	for i := range m.exportSettings.Items {
+		m.exportSettings.Items[i].Hidden = !exportSettingShown(m.exportSettings.Items[i].Key, exportKey)
+	}
+	m.exportSettings.SetActive(0)
+	m.exportSettings.Confirm = false
+	m.currentActiveMenu = exportOptionsMenu
+	m.updateMessageTextOnMenuChange()
+}
+
+func (m *MezzotoneModel) closeExportSettings() {
+	m.pendingExport = ""
+	m.exportSettings.ClearActive()
+	m.exportSettings.Confirm = false
+	m.currentActiveMenu = renderView
+	m.updateMessageTextOnMenuChange()
+}
+
+// exportSettingsRows is the height of the tallest export panel.
+func (m *MezzotoneModel) exportSettingsRows() int {
+	rows := 0
+	for _, exportKey := range exportPanelKeys {
+		n := 0
+		for _, item := range m.exportSettings.Items {
+			if exportSettingShown(item.Key, string(exportKey)) {
+				n++
+			}
+		}
+		rows = max(rows, n)
+	}
+	return rows
+}
+
+// confirmExportSettings starts the pending export, invalid settings keep the panel open. Only the
+// settings the pending export reads are checked.
+func (m *MezzotoneModel) confirmExportSettings() tea.Cmd {
+	var exportOptions export.ASCIIExportOptions
+	var err error
+	switch {
+	case strings.Contains(fontExportKeys, m.pendingExport):
+		exportOptions, err = m.asciiExportOptions()
+		if err == nil && m.pendingExport == "g" {
+			_, err = m.gifExportOptions()
+		}
+		if err == nil && m.pendingExport == "p" {
+			_, err = m.pdfExportOptions()
+		}
+	case m.pendingExport == "A":
+		_, err = m.ansiArtOptions()
+	}
+	if err != nil {
+		m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+		return nil
+	}
+
+	exportKey := m.pendingExport
+	m.closeExportSettings()
+	return m.startExport(exportKey, exportOptions)
+}
+
+// startExport runs the export bound to exportKey in the render view.
+func (m *MezzotoneModel) startExport(exportKey string, exportOptions export.ASCIIExportOptions) tea.Cmd {
+	switch exportKey {
+	case "t", "T":
+		renderCanvas := m.currentRenderCanvas()
+		if renderCanvas == nil {
+			m.updateMessageViewPortContent("⚠ nothing to export (render output is empty)", true)
+			return nil
+		}
+
+		ext := ".txt"
+		if exportKey == "T" {
+			ext = ".ans"
+		}
+		outPath, err := m.exportPath(ext)
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		if exportKey == "T" {
+			err = export.ASCIIToANSI(renderCanvas, outPath, m.textExportOptions())
+		} else {
+			err = export.ASCIItToTxT(outPath, export.ASCIIToPlainText(renderCanvas, m.textExportOptions()))
+		}
+		if err != nil {
+			releaseExportPath(outPath)
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		m.updateMessageViewPortContent("Successfully exported to "+outPath+" !", false)
+		return nil
+	case "r":
+		outPath, err := m.exportPath(".cast")
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		castFrames := m.animationExportFrames()
+		if m.renderedImgOutput.renderedCanvas != nil {
+			castFrames = []export.ASCIIGIFFrame{{Canvas: m.renderedImgOutput.renderedCanvas, Duration: stillVideoDuration}}
+		}
+
+		m.updateMessageViewPortContent("Exporting asciicast to "+outPath+" ...", false)
+		return exportAsciiToAsciicastCmd(outPath, castFrames, m.textExportOptions())
+	case "A":
+		renderCanvas := m.currentRenderCanvas()
+		if renderCanvas == nil {
+			m.updateMessageViewPortContent("⚠ nothing to export (render output is empty)", true)
+			return nil
+		}
+
+		ansiOptions, err := m.ansiArtOptions()
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		outPath, err := m.exportPath(".ans")
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		if err := export.ASCIIToANSIArt(renderCanvas, outPath, ansiOptions); err != nil {
+			releaseExportPath(outPath)
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		m.updateMessageViewPortContent("Successfully exported to "+outPath+" !", false)
+		return nil
+	case "i":
+		renderCanvas := m.currentRenderCanvas()
+		if renderCanvas == nil {
+			m.updateMessageViewPortContent("⚠ nothing to export (render output is empty)", true)
+			return nil
+		}
+
+		outPath, err := m.exportPath(".png")
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		m.updateMessageViewPortContent("Exporting image to "+outPath+" ...", false)
+		return exportAsciiToPngCmd(outPath, renderedImgOutput{renderedCanvas: renderCanvas}, exportOptions)
+	case "S":
+		renderCanvas := m.currentRenderCanvas()
+		if renderCanvas == nil {
+			m.updateMessageViewPortContent("⚠ nothing to export (render output is empty)", true)
+			return nil
+		}
+
+		outPath, err := m.exportPath(".svg")
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		m.updateMessageViewPortContent("Exporting svg to "+outPath+" ...", false)
+		return exportAsciiToSvgCmd(outPath, renderedImgOutput{renderedCanvas: renderCanvas}, exportOptions)
+	case "p":
+		renderCanvas := m.currentRenderCanvas()
+		if renderCanvas == nil {
+			m.updateMessageViewPortContent("⚠ nothing to export (render output is empty)", true)
+			return nil
+		}
+
+		pdfOptions, err := m.pdfExportOptions()
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		outPath, err := m.exportPath(".pdf")
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		m.updateMessageViewPortContent("Exporting pdf to "+outPath+" ...", false)
+		return exportAsciiToPdfCmd(outPath, renderCanvas, exportOptions, pdfOptions)
+	case "H", "P":
+		renderCanvas := m.currentRenderCanvas()
+		if renderCanvas == nil {
+			m.updateMessageViewPortContent("⚠ nothing to export (render output is empty)", true)
+			return nil
+		}
+
+		outPath, err := m.exportPath(".html")
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		htmlOptions := export.HTMLExportOptions{
+			Fragment:   exportKey == "P",
+			FontAspect: m.getFontAspect(),
+			Theme:      m.htmlTheme(),
+		}
+
+		m.updateMessageViewPortContent("Exporting html to "+outPath+" ...", false)
+		return exportAsciiToHtmlCmd(outPath, renderedImgOutput{renderedCanvas: renderCanvas}, exportOptions, htmlOptions)
+	case "w", "W":
+		format := webFormatSVG
+		if exportKey == "W" {
+			format = webFormatHTML
+		}
+
+		outPath, err := m.exportPath("." + format)
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		webFrames := m.animationExportFrames()
+		if m.renderedImgOutput.renderedCanvas != nil {
+			webFrames = []export.ASCIIGIFFrame{{Canvas: m.renderedImgOutput.renderedCanvas, Duration: stillVideoDuration}}
+		}
+
+		m.updateMessageViewPortContent("Exporting animated "+format+" to "+outPath+" ...", false)
+		return exportAsciiToWebAnimationCmd(outPath, format, webFrames, exportOptions)
+	case "g":
+		gifOptions, err := m.gifExportOptions()
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		outPath, err := m.exportPath(".gif")
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+		gifFrames := m.animationExportFrames()
+
+		m.updateMessageViewPortContent("Exporting gif to "+outPath+" ...", false)
+		return exportAsciiToGifCmd(outPath, gifFrames, exportOptions, gifOptions)
+	case "a":
+		outPath, err := m.exportPath(".png")
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		apngFrames := m.animationExportFrames()
+		if m.renderedImgOutput.renderedCanvas != nil {
+			apngFrames = []export.ASCIIGIFFrame{{Canvas: m.renderedImgOutput.renderedCanvas, Duration: stillVideoDuration}}
+		}
+
+		m.updateMessageViewPortContent("Exporting animated png to "+outPath+" ...", false)
+		return exportAsciiToAPNGCmd(outPath, apngFrames, exportOptions)
+	case "v", "V":
+		format := videoFormatAVI
+		if exportKey == "V" {
+			format = videoFormatY4M
+		}
+
+		outPath, err := m.exportPath("." + format)
+		if err != nil {
+			m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+			return nil
+		}
+
+		videoFrames := m.animationExportFrames()
+		if m.renderedImgOutput.renderedCanvas != nil {
+			videoFrames = []export.ASCIIGIFFrame{{Canvas: m.renderedImgOutput.renderedCanvas, Duration: stillVideoDuration}}
+		}
+
+		m.updateMessageViewPortContent("Exporting video to "+outPath+" ...", false)
+		return exportAsciiToVideoCmd(outPath, format, videoFrames, exportOptions)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package app
+
+import "fmt"
+
+const (
+	// maxFramePixels caps the size of one decoded animation frame.
+	maxFramePixels = 1 << 26
+	// maxFrameBytes caps the decoded frames of one animation together. Decoders keep every frame as
+	// RGBA for playback, so a file of small delta frames can expand far beyond its own size.
+	maxFrameBytes = 2 << 30
+)
+
+// frameBudget counts the memory the decoded frames of an animation take, so decoders can refuse a
+// file before allocating its frames.
+type frameBudget struct {
+	used int64
+}
+
+// reserve adds count RGBA frames of w x h pixels, it fails when a frame is over maxFramePixels or the
+// animation grows past maxFrameBytes. A count of 0 only checks the frame size.
+func (b *frameBudget) reserve(count, w, h int) error {
+	if w <= 0 || h <= 0 || w > maxFramePixels/h {
+		return fmt.Errorf("frame size %dx%d is too large", w, h)
+	}
+	b.used += int64(count) * int64(w) * int64(h) * 4
+	if b.used > maxFrameBytes {
+		return fmt.Errorf("decoded frames need %d MiB, more than the %d MiB limit", b.used>>20, maxFrameBytes>>20)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSequenceFrames caps how many numbered frames a sequence resolves to.
+const maxSequenceFrames = 2000
+
+var sequenceImageExtensions = []string{".png", ".jpg", ".jpeg", ".bmp", ".webp", ".tiff"}
+
+var (
+	sequencePrintfVerb = regexp.MustCompile(`%0?(\d*)d`)
+	sequenceTrailingNo = regexp.MustCompile(`(\d+)\D*$`)
+)
+
+// IsImageSequence reports whether source names a frame sequence instead of a single file:
+// a directory, a glob (frame_*.png) or a printf pattern (frame_%04d.png).
+func IsImageSequence(source string) bool {
+	if info, err := os.Stat(source); err == nil {
+		return info.IsDir()
+	}
+	return strings.ContainsAny(source, "*?[") || sequencePrintfVerb.MatchString(source)
+}
+
+// ResolveImageSequence lists the frame files of source in playback order.
+// Directories and globs are ordered by the last number in the file name, so frame_2 plays before frame_10.
+func ResolveImageSequence(source string) ([]string, error) {
+	var paths []string
+
+	switch info, err := os.Stat(source); {
+	case err == nil && info.IsDir():
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !isSequenceImage(entry.Name()) {
+				continue
+			}
+			paths = append(paths, filepath.Join(source, entry.Name()))
+		}
+		sortSequencePaths(paths)
+
+	case sequencePrintfVerb.MatchString(source):
+		paths, err = expandSequencePattern(source)
+		if err != nil {
+			return nil, err
+		}
+
+	default:
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sequence pattern %q: %w", source, err)
+		}
+		for _, match := range matches {
+			if isSequenceImage(match) {
+				paths = append(paths, match)
+			}
+		}
+		sortSequencePaths(paths)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no frames found for %s", source)
+	}
+	if len(paths) > maxSequenceFrames {
+		return nil, fmt.Errorf("sequence has more than %d frames", maxSequenceFrames)
+	}
+	return paths, nil
+}
+
+// LoadImageSequence decodes every frame of source, each one shown for 1/fps seconds.
+// Durations are rounded so they add up without drift.
+func LoadImageSequence(source string, fps float64) (frames []image.Image, delays []time.Duration, err error) {
+	if fps <= 0 {
+		return nil, nil, fmt.Errorf("sequence fps must be > 0")
+	}
+
+	paths, err := ResolveImageSequence(source)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Frame sizes are read from the file headers first, so an oversized sequence fails before decoding.
+	var budget frameBudget
+	for _, path := range paths {
+		config, err := decodeImageConfig(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("frame %s: %w", filepath.Base(path), err)
+		}
+		if err := budget.reserve(1, config.Width, config.Height); err != nil {
+			return nil, nil, fmt.Errorf("frame %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	frames = make([]image.Image, 0, len(paths))
+	delays = make([]time.Duration, 0, len(paths))
+	for i, path := range paths {
+		img, err := decodeImageFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("frame %s: %w", filepath.Base(path), err)
+		}
+		frames = append(frames, img)
+
+		start := time.Duration(float64(i) * float64(time.Second) / fps)
+		end := time.Duration(float64(i+1) * float64(time.Second) / fps)
+		delays = append(delays, end-start)
+	}
+
+	return frames, delays, nil
+}
+
+func decodeImageFile(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+func decodeImageConfig(path string) (image.Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	return config, err
+}
+
+func isSequenceImage(name string) bool {
+	return slices.Contains(sequenceImageExtensions, strings.ToLower(filepath.Ext(name)))
+}
+
+// expandSequencePattern fills the printf verb with 0, 1, 2... and stops at the first missing frame.
+// Sequences may start at 0 or 1.
+func expandSequencePattern(pattern string) ([]string, error) {
+	loc := sequencePrintfVerb.FindStringSubmatchIndex(pattern)
+	width := 0
+	if loc[2] != loc[3] {
+		width, _ = strconv.Atoi(pattern[loc[2]:loc[3]])
+	}
+	format := func(n int) string {
+		number := strconv.Itoa(n)
+		if len(number) < width {
+			number = strings.Repeat("0", width-len(number)) + number
+		}
+		return pattern[:loc[0]] + number + pattern[loc[1]:]
+	}
+
+	start := 0
+	if _, err := os.Stat(format(0)); err != nil {
+		start = 1
+	}
+
+	var paths []string
+	for n := start; len(paths) <= maxSequenceFrames; n++ {
+		path := format(n)
+		if _, err := os.Stat(path); err != nil {
+			break
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func sortSequencePaths(paths []string) {
+	frameNumber := func(path string) int {
+		match := sequenceTrailingNo.FindStringSubmatch(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		if match == nil {
+			return -1
+		}
+		n, _ := strconv.Atoi(match[1])
+		return n
+	}
+
+	slices.SortStableFunc(paths, func(a, b string) int {
+		if na, nb := frameNumber(a), frameNumber(b); na != nb {
+			return na - nb
+		}
+		return strings.Compare(a, b)
+	})
+}
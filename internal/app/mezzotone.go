@@ -9,15 +9,16 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
 	"github.com/joaoheitorgarcia/Mezzotone/internal/export"
 	"github.com/joaoheitorgarcia/Mezzotone/internal/services"
 	"github.com/joaoheitorgarcia/Mezzotone/internal/termtext"
 	"github.com/joaoheitorgarcia/Mezzotone/internal/ui"
+	"github.com/joaoheitorgarcia/Mezzotone/mezzotone"
 
 	"charm.land/bubbles/v2/filepicker"
 	"charm.land/bubbles/v2/key"
@@ -31,10 +32,14 @@ import (
 type MezzotoneModel struct {
 	filePicker   filepicker.Model
 	selectedFile string
+	// savePicker picks the export folder, it only selects directories.
+	savePicker         filepicker.Model
+	saveAsPreviousMenu int
 
 	renderView      viewport.Model
 	leftColumn      viewport.Model
 	renderSettings  ui.SettingsPanel
+	exportSettings  ui.SettingsPanel
 	messageViewPort viewport.Model
 
 	style styleVariables
@@ -44,12 +49,15 @@ type MezzotoneModel struct {
 	helpPreviousMenu  int
 	isQuitting        bool
 	renderContent     string
-	exportFontTTFPath string
+	// pendingExport is the render view key of the export waiting on the export settings.
+	pendingExport string
 
 	renderedImgOutput renderedImgOutput
 	renderedGifOutput renderedGifOutput
 
 	gifAnimation ui.AnimationRenderer
+	// startupCmd starts the animation of a grid file opened with -input.
+	startupCmd tea.Cmd
 
 	width  int
 	height int
@@ -57,25 +65,66 @@ type MezzotoneModel struct {
 	err error
 }
 
+// The raster export messages carry a glyph coverage warning, see glyphCoverageWarning.
 type gifExportDoneMsg struct {
 	outPath string
+	report  export.GIFSizeReport
+	warning string
 	err     error
 }
 
 type pngExportDoneMsg struct {
+	outPath string
+	warning string
+	err     error
+}
+
+type videoExportDoneMsg struct {
+	outPath string
+	warning string
+	err     error
+}
+
+type apngExportDoneMsg struct {
+	outPath string
+	warning string
+	err     error
+}
+
+type svgExportDoneMsg struct {
+	outPath string
+	err     error
+}
+
+type webAnimationExportDoneMsg struct {
+	outPath string
+	err     error
+}
+
+type htmlExportDoneMsg struct {
+	outPath string
+	err     error
+}
+
+type asciicastExportDoneMsg struct {
+	outPath string
+	err     error
+}
+
+type pdfExportDoneMsg struct {
 	outPath string
 	err     error
 }
 
 type renderedImgOutput struct {
-	renderedRunes [][]rune
-	renderedColor [][]color.NRGBA
+	renderedCanvas *canvas.Canvas
 }
 
 type renderedGifOutput struct {
-	renderedRunes [][][]rune
-	renderedColor [][][]color.NRGBA
-	delayTimes    []time.Duration
+	renderedFrames []*canvas.Canvas
+	delayTimes     []time.Duration
+	// loopCount is the loop count of a gif source in image/gif terms, 0 (loop forever) for other sources.
+	loopCount int
 }
 
 type styleVariables struct {
@@ -132,14 +181,46 @@ var clipboardCommands = [][]string{
 
 var newUUID = uuid.New
 
+const defaultSequenceFPS = 12.0
+
+const (
+	videoFormatAVI = "avi"
+	videoFormatY4M = "y4m"
+)
+
+const (
+	webFormatSVG  = "svg"
+	webFormatHTML = "html"
+)
+
+// stillVideoDuration is the length of a video exported from a still image.
+const stillVideoDuration = time.Second
+
+// defaultPDFMargin is the page margin of pdf exports, in points.
+const defaultPDFMargin = 36
+
 const (
 	filePickerMenu = iota
 	renderOptionsMenu
 	renderView
+	exportOptionsMenu
+	saveAsMenu
 )
 
 type MezzotoneModelConfig struct {
 	ExportFontTTFPath string
+	// ExportFallbackFonts is a path list (like PATH) of fonts tried in order for runes the export font lacks.
+	ExportFallbackFonts string
+	// DefaultRuneMode preselects a registered rune mode in the render options, empty keeps ASCII.
+	DefaultRuneMode string
+	// InputPath skips the file picker: an image, gif, y4m, frame directory, glob or printf frame pattern.
+	InputPath string
+	// SequenceFPS is the playback rate of image sequences, zero keeps defaultSequenceFPS.
+	SequenceFPS float64
+	// OutputDir is where exports are written, empty means the home directory.
+	OutputDir string
+	// FileNameTemplate names exported files, see resolveExportPath. Empty keeps defaultFileNameTemplate.
+	FileNameTemplate string
 }
 
 func NewMezzotoneModel() *MezzotoneModel {
@@ -234,7 +315,15 @@ func NewMezzotoneModelWithConfig(config MezzotoneModelConfig) *MezzotoneModel {
 		renderSettingsStyle: renderSettingsStyles,
 	}
 
-	runeMode := []string{"ASCII", "UNICODE", "DOTS", "RECTANGLES", "BARS"}
+	runeModes := mezzotone.RuneModes()
+	defaultRuneMode := "ASCII"
+	if _, ok := mezzotone.LookupRenderer(config.DefaultRuneMode); ok {
+		defaultRuneMode = config.DefaultRuneMode
+	}
+	sequenceFPS := defaultSequenceFPS
+	if config.SequenceFPS > 0 {
+		sequenceFPS = config.SequenceFPS
+	}
 	renderSettingsItems := []ui.SettingItem{
 		{Label: "Text Size", Key: "textSize", Type: ui.TypeInt, Value: "10"},
 		{Label: "Font Aspect", Key: "fontAspect", Type: ui.TypeFloat, Value: "2.3"},
@@ -243,14 +332,20 @@ func NewMezzotoneModelWithConfig(config MezzotoneModelConfig) *MezzotoneModel {
 		{Label: "Reverse Chars", Key: "reverseChars", Type: ui.TypeBool, Value: "TRUE"},
 		{Label: "High Contrast", Key: "highContrast", Type: ui.TypeBool, Value: "TRUE"},
 		{Label: "Render Color", Key: "renderColor", Type: ui.TypeBool, Value: "FALSE"},
-		{Label: "Rune Mode", Key: "runeMode", Type: ui.TypeEnum, Value: "ASCII", Enum: runeMode},
+		{Label: "Rune Mode", Key: "runeMode", Type: ui.TypeEnum, Value: defaultRuneMode, Enum: runeModes},
+		{Label: "Color Estimator", Key: "colorEstimator", Type: ui.TypeEnum, Value: mezzotone.ColorEstimatorMean, Enum: mezzotone.ColorEstimators()},
+		{Label: "Color Mode", Key: "colorMode", Type: ui.TypeEnum, Value: mezzotone.ColorModeAverage, Enum: mezzotone.ColorModes()},
+		{Label: "Color Stops", Key: "colorStops", Type: ui.TypeString, Value: ""},
+		{Label: "Saturation", Key: "saturation", Type: ui.TypeFloat, Value: "1.0"},
+		{Label: "Vibrance", Key: "vibrance", Type: ui.TypeFloat, Value: "0.0"},
+		{Label: "Sequence FPS", Key: "sequenceFPS", Type: ui.TypeFloat, Value: strconv.FormatFloat(sequenceFPS, 'f', -1, 64)},
 	}
 	renderSettingsItemsSize = len(renderSettingsItems)
 	renderSettingsModel := ui.NewSettingsPanel("Render Options", renderSettingsItems, windowStyles.renderSettingsStyle.settingsPanelInactiveStyle)
 	renderSettingsModel.ClearActive()
 
 	fp := filepicker.New()
-	fp.AllowedTypes = []string{".png", ".jpg", ".jpeg", ".bmp", ".webp", ".tiff", ".gif"}
+	fp.AllowedTypes = []string{".png", ".apng", ".jpg", ".jpeg", ".bmp", ".webp", ".tiff", ".gif", ".y4m", export.GridJSONExtension, export.GridBinaryExtension}
 	fp.CurrentDirectory, _ = os.UserHomeDir()
 	fp.ShowPermissions = false
 	fp.ShowSize = true
@@ -265,6 +360,15 @@ func NewMezzotoneModelWithConfig(config MezzotoneModelConfig) *MezzotoneModel {
 	}
 	fp.Styles = windowStyles.filePickerStyle.filePickerActiveStyle
 
+	savePicker := filepicker.New()
+	savePicker.CurrentDirectory = fp.CurrentDirectory
+	savePicker.DirAllowed = true
+	savePicker.FileAllowed = false
+	savePicker.ShowPermissions = false
+	savePicker.ShowSize = false
+	savePicker.KeyMap = fp.KeyMap
+	savePicker.Styles = windowStyles.filePickerStyle.filePickerActiveStyle
+
 	renderViewPort := viewport.New(viewport.WithWidth(0), viewport.WithHeight(0))
 	leftColumn := viewport.New(viewport.WithWidth(0), viewport.WithHeight(0))
 
@@ -277,13 +381,26 @@ func NewMezzotoneModelWithConfig(config MezzotoneModelConfig) *MezzotoneModel {
 		style:             windowStyles,
 		leftColumn:        leftColumn,
 		renderSettings:    renderSettingsModel,
+		exportSettings:    newExportSettingsPanel(config, windowStyles.renderSettingsStyle.settingsPanelInactiveStyle),
+		savePicker:        savePicker,
 		currentActiveMenu: filePickerMenu,
 		helpPreviousMenu:  filePickerMenu,
 		isQuitting:        false,
-		exportFontTTFPath: strings.TrimSpace(config.ExportFontTTFPath),
 	}
 	model.updateMessageViewPortContent("Select image or gif to convert:", false)
 
+	if inputPath := strings.TrimSpace(config.InputPath); inputPath != "" && isGridFile(inputPath) {
+		startupCmd, err := model.openGridFile(inputPath)
+		if err != nil {
+			model.updateMessageViewPortContent("⚠ "+err.Error(), true)
+		}
+		model.startupCmd = startupCmd
+	} else if inputPath != "" {
+		model.selectedFile = inputPath
+		model.renderSettings.SetActive(0)
+		model.incrementCurrentActiveMenu()
+	}
+
 	if err := clipboard.Init(); err == nil {
 		clipboardOK = true
 	}
@@ -292,7 +409,7 @@ func NewMezzotoneModelWithConfig(config MezzotoneModelConfig) *MezzotoneModel {
 }
 
 func (m *MezzotoneModel) Init() tea.Cmd {
-	return m.filePicker.Init()
+	return tea.Batch(m.filePicker.Init(), m.startupCmd)
 }
 
 func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -304,14 +421,88 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case gifExportDoneMsg:
 		if msg.err != nil {
+			releaseExportPath(msg.outPath)
 			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
 			return m, nil
 		}
-		m.updateMessageTextOnMenuChange()
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !\nSize: "+msg.report.String()+msg.warning, false)
 		return m, nil
 
 	case pngExportDoneMsg:
 		if msg.err != nil {
+			releaseExportPath(msg.outPath)
+			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !"+msg.warning, false)
+		return m, nil
+
+	case videoExportDoneMsg:
+		if msg.err != nil {
+			releaseExportPath(msg.outPath)
+			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !"+msg.warning, false)
+		return m, nil
+
+	case apngExportDoneMsg:
+		if msg.err != nil {
+			releaseExportPath(msg.outPath)
+			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !"+msg.warning, false)
+		return m, nil
+
+	case svgExportDoneMsg:
+		if msg.err != nil {
+			releaseExportPath(msg.outPath)
+			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !", false)
+		return m, nil
+
+	case webAnimationExportDoneMsg:
+		if msg.err != nil {
+			releaseExportPath(msg.outPath)
+			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !", false)
+		return m, nil
+
+	case htmlExportDoneMsg:
+		if msg.err != nil {
+			releaseExportPath(msg.outPath)
+			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !", false)
+		return m, nil
+
+	case asciicastExportDoneMsg:
+		if msg.err != nil {
+			releaseExportPath(msg.outPath)
+			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !", false)
+		return m, nil
+
+	case pdfExportDoneMsg:
+		if msg.err != nil {
+			releaseExportPath(msg.outPath)
+			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
+			return m, nil
+		}
+		m.updateMessageViewPortContent("Successfully exported to "+msg.outPath+" !", false)
+		return m, nil
+
+	case gridExportDoneMsg:
+		if msg.err != nil {
+			releaseExportPath(msg.outPath)
 			m.updateMessageViewPortContent("⚠ "+msg.err.Error(), true)
 			return m, nil
 		}
@@ -337,17 +528,20 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.renderSettings.SetWidth(m.style.leftColumnWidth)
 		m.renderSettings.SetHeight(renderSettingsItemsSize)
+		m.exportSettings.SetWidth(m.style.leftColumnWidth)
+		m.exportSettings.SetHeight(m.exportSettingsRows())
 
 		m.messageViewPort.SetWidth(max(1, m.style.leftColumnWidth-2))
 
 		m.renderView.SetHeight(m.height - m.style.windowMargin)
 
 		computedFilePickerHeight := m.renderView.Height() -
-			(renderSettingsItemsSize + 4) - //renderSettings header and end
+			(max(renderSettingsItemsSize, m.exportSettingsRows()) + 4) - //settings header and end
 			(m.messageViewPort.Height() + 2) - //message render view
 			(m.style.windowMargin + 3) //inputFile Title
 
 		m.filePicker.SetHeight(computedFilePickerHeight)
+		m.savePicker.SetHeight(computedFilePickerHeight)
 
 		m.toggleRenderViewFullscreen()
 		m.updateMessageViewPortContent(currentMessage, false)
@@ -363,116 +557,70 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.updateMessageViewPortContent("Select image or gif to convert:", false)
 		}
 		switch msg.String() {
-		case "c":
-			if m.currentActiveMenu == renderView {
-				if err := copyTextToClipboard(m.renderContent); err != nil {
-					m.updateMessageViewPortContent("⚠ "+err.Error(), true)
-					return m, nil
-				}
-				m.updateMessageViewPortContent("Successfully sent to clipboard !", false)
+		case "s":
+			if m.currentActiveMenu == saveAsMenu {
+				m.closeSaveAs(m.savePicker.CurrentDirectory)
 				return m, nil
 			}
-		case "t":
-			if m.currentActiveMenu == renderView {
-				homeDir, err := os.UserHomeDir()
+			if m.currentActiveMenu == filePickerMenu {
+				paths, err := ResolveImageSequence(m.filePicker.CurrentDirectory)
 				if err != nil {
 					m.updateMessageViewPortContent("⚠ "+err.Error(), true)
 					return m, nil
 				}
-				generatedUuid := newUUID()
-				outPath := filepath.Join(homeDir, "Mezzotone_"+generatedUuid.String()+".txt")
+				m.selectedFile = m.filePicker.CurrentDirectory
+				_ = services.Logger().Info(fmt.Sprintf("Selected Sequence: %s (%d frames)", m.selectedFile, len(paths)))
 
-				err = export.ASCIItToTxT(outPath, m.renderContent)
-				if err != nil {
+				m.renderSettings.SetActive(0)
+				m.renderSettings.Confirm = false
+				m.incrementCurrentActiveMenu()
+				return m, nil
+			}
+		case "c", "C":
+			if m.currentActiveMenu == renderView {
+				content := export.ASCIIToPlainText(m.currentRenderCanvas(), m.textExportOptions())
+				if msg.String() == "C" {
+					content = export.ASCIIToANSIText(m.currentRenderCanvas(), m.textExportOptions())
+				}
+				if err := copyTextToClipboard(content); err != nil {
 					m.updateMessageViewPortContent("⚠ "+err.Error(), true)
 					return m, nil
 				}
-
-				m.updateMessageViewPortContent("Successfully exported to "+outPath+" !", false)
+				m.updateMessageViewPortContent("Successfully sent to clipboard !", false)
 				return m, nil
 			}
-		case "i":
+		case "o":
+			if m.currentActiveMenu == renderView || (m.currentActiveMenu == exportOptionsMenu && !m.exportSettings.Editing) {
+				return m, m.openSaveAs()
+			}
+		case "i", "S", "p", "H", "P", "w", "W", "g", "a", "v", "V", "A", "t", "T", "r":
 			if m.currentActiveMenu == renderView {
-				homeDir, _ := os.UserHomeDir()
-				generatedUuid := newUUID()
-				outPath := filepath.Join(homeDir, "Mezzotone_"+generatedUuid.String()+".png")
-
-				fontAspect := 1.0
-				for i := range m.renderSettings.Items {
-					if m.renderSettings.Items[i].Key == "fontAspect" {
-						fontAspect, _ = strconv.ParseFloat(m.renderSettings.Items[i].Value, 2)
-					}
-				}
-
-				// Font Aspect is height/width (2.3). Export wants width/height.
-				targetAspect := 1.0 / fontAspect
-
-				exportOptions := export.ASCIIExportOptions{
-					FontSize:     14,
-					DPI:          300,
-					BG:           color.Black,
-					FG:           color.White,
-					FontTTFPath:  m.exportFontTTFPath,
-					TargetAspect: targetAspect,
-					RenderColor:  m.getRenderColor(),
-				}
-
-				m.updateMessageViewPortContent("Exporting image to "+outPath+" ...", false)
-
-				var render renderedImgOutput
-				if m.renderedImgOutput.renderedRunes == nil {
-					i := m.gifAnimation.GetcurrentFrameIndex()
-					render = renderedImgOutput{
-						renderedRunes: m.renderedGifOutput.renderedRunes[i],
-						renderedColor: m.renderedGifOutput.renderedColor[i],
-					}
-				} else {
-					render = m.renderedImgOutput
-				}
-				return m, exportAsciiToPngCmd(outPath, render, exportOptions)
+				m.openExportSettings(msg.String())
+				return m, nil
 			}
-		case "g":
+		case "m", "M":
 			if m.currentActiveMenu == renderView {
-				homeDir, _ := os.UserHomeDir()
-				generatedUuid := newUUID()
-				outPath := filepath.Join(homeDir, "Mezzotone_"+generatedUuid.String()+".gif")
-
-				fontAspect := 1.0
-				for i := range m.renderSettings.Items {
-					if m.renderSettings.Items[i].Key == "fontAspect" {
-						fontAspect, _ = strconv.ParseFloat(m.renderSettings.Items[i].Value, 2)
-					}
-				}
-
-				// Font Aspect is height/width (2.3). Export wants width/height.
-				targetAspect := 1.0 / fontAspect
-
-				exportOptions := export.ASCIIExportOptions{
-					FontSize:     14,
-					DPI:          300,
-					BG:           color.Black,
-					FG:           color.White,
-					FontTTFPath:  m.exportFontTTFPath,
-					TargetAspect: targetAspect,
-					RenderColor:  m.getRenderColor(),
+				format, ext := gridFormatJSON, export.GridJSONExtension
+				if msg.String() == "M" {
+					format, ext = gridFormatBinary, export.GridBinaryExtension
 				}
 
-				gifFrames := make([]export.ASCIIGIFFrame, 0, len(m.renderedGifOutput.renderedRunes))
-				for i := range m.renderedGifOutput.renderedRunes {
-					gifFrames = append(gifFrames, export.ASCIIGIFFrame{
-						FrameRunes:  m.renderedGifOutput.renderedRunes[i],
-						Duration:    m.renderedGifOutput.delayTimes[i],
-						FrameColors: m.renderedGifOutput.renderedColor[i],
-					})
+				outPath, err := m.exportPath(ext)
+				if err != nil {
+					m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+					return m, nil
 				}
 
-				m.updateMessageViewPortContent("Exporting gif to "+outPath+" ...", false)
-				return m, exportAsciiToGifCmd(outPath, gifFrames, exportOptions)
+				m.updateMessageViewPortContent("Exporting cell grid to "+outPath+" ...", false)
+				return m, exportGridCmd(outPath, format, m.gridDocument())
 			}
 		case "h":
 			if m.currentActiveMenu == renderOptionsMenu && m.renderSettings.Editing {
 				break
 			}
+			if m.currentActiveMenu == exportOptionsMenu && m.exportSettings.Editing {
+				break
+			}
 			if m.helpVisible {
 				m.helpVisible = false
 				m.currentActiveMenu = m.helpPreviousMenu
@@ -494,6 +642,10 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.renderView.SetContent(m.renderContent)
 				return m, nil
 			}
+			if m.currentActiveMenu == saveAsMenu {
+				m.closeSaveAs("")
+				return m, nil
+			}
 			if m.currentActiveMenu == filePickerMenu {
 				if m.isQuitting {
 					return m, tea.Quit
@@ -513,7 +665,14 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.decrementCurrentActiveMenu()
 				return m, cmd
 			}
+			if m.currentActiveMenu == exportOptionsMenu && !m.exportSettings.Editing {
+				m.closeExportSettings()
+				return m, nil
+			}
 		case "enter":
+			if m.currentActiveMenu == exportOptionsMenu && !m.exportSettings.Editing && m.exportSettings.Confirm {
+				return m, m.confirmExportSettings()
+			}
 			if m.currentActiveMenu == renderOptionsMenu {
 				if !m.renderSettings.Editing && m.renderSettings.Confirm {
 					m.incrementCurrentActiveMenu()
@@ -521,49 +680,40 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					normalizedOptions, err := normalizeRenderOptionsForService(m.renderSettings.Items)
 					if err != nil {
 						m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+						return m, cmd
 					}
 
-					f, err := os.Open(m.selectedFile)
+					frameArray, frameDelays, loopCount, isAnimation, err := loadAnimationFrames(m.selectedFile, m.getSequenceFPS())
 					if err != nil {
 						m.updateMessageViewPortContent("⚠ "+err.Error(), true)
 						return m, cmd
 					}
-					defer func() { _ = f.Close() }()
+					if isAnimation {
+						_ = services.Logger().Info(fmt.Sprintf("Successfully Loaded %d frames: %s", len(frameArray), m.selectedFile))
 
-					_ = services.Logger().Info(fmt.Sprintf("Successfully Loaded: %s", m.selectedFile))
-
-					if IsGIF(m.selectedFile) {
-						frameArray, delays, err := SplitAnimatedGIF(f)
-						if err != nil {
-							m.updateMessageViewPortContent("⚠ "+err.Error(), true)
-							return m, cmd
-						}
-						var gifRuneArrays [][][]rune
-						var gifColorArrays [][][]color.NRGBA
-						var gifDelaysDuration []time.Duration
-						for i, frame := range frameArray {
-							runeArray, colorArray, err := services.ConvertImageToString(frame, normalizedOptions)
+						var gifResults []*mezzotone.Result
+						var gifCanvases []*canvas.Canvas
+						for _, frame := range frameArray {
+							result, err := mezzotone.Convert(frame, mezzotone.WithOptions(normalizedOptions))
 							if err != nil {
 								m.updateMessageViewPortContent("⚠ "+err.Error(), true)
 								return m, cmd
 							}
-							gifRuneArrays = append(gifRuneArrays, runeArray)
-							gifColorArrays = append(gifColorArrays, colorArray)
-
-							gifDelaysDuration = append(gifDelaysDuration, time.Duration(delays[i])*10*time.Millisecond)
+							gifResults = append(gifResults, result)
+							gifCanvases = append(gifCanvases, result.Canvas())
 						}
-						m.renderedGifOutput.renderedRunes = gifRuneArrays
-						m.renderedGifOutput.renderedColor = gifColorArrays
-						m.renderedGifOutput.delayTimes = gifDelaysDuration
+						m.renderedGifOutput.renderedFrames = gifCanvases
+						m.renderedGifOutput.delayTimes = frameDelays
+						m.renderedGifOutput.loopCount = loopCount
 
 						var animationFrames []ui.AnimationFrame
-						for i, frameRuneArray := range gifRuneArrays {
-							frameASCII := services.ImageRuneArrayIntoString(frameRuneArray, gifColorArrays[i], normalizedOptions.RenderColor)
+						for i, result := range gifResults {
+							frameASCII := result.String()
 							animationFrames = append(
 								animationFrames,
 								ui.AnimationFrame{
 									Frame:    frameASCII,
-									Duration: time.Duration(delays[i]) * 10 * time.Millisecond,
+									Duration: frameDelays[i],
 								},
 							)
 						}
@@ -574,12 +724,20 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						gifAnimation := ui.NewAnimationRenderer(animationFrames, escapeKeys)
 						m.gifAnimation = gifAnimation
 
-						m.renderedImgOutput.renderedRunes = nil
-						m.renderedImgOutput.renderedColor = nil
+						m.renderedImgOutput.renderedCanvas = nil
 
 						return m, m.gifAnimation.StartAnimation
 					}
 
+					f, err := os.Open(m.selectedFile)
+					if err != nil {
+						m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+						return m, cmd
+					}
+					defer func() { _ = f.Close() }()
+
+					_ = services.Logger().Info(fmt.Sprintf("Successfully Loaded: %s", m.selectedFile))
+
 					// else is Image
 					inputImg, format, err := image.Decode(f)
 					if err != nil {
@@ -588,18 +746,17 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 					_ = services.Logger().Info(fmt.Sprintf("format: %s", format))
 
-					runeArray, colorArray, err := services.ConvertImageToString(inputImg, normalizedOptions)
+					result, err := mezzotone.Convert(inputImg, mezzotone.WithOptions(normalizedOptions))
 					if err != nil {
 						m.updateMessageViewPortContent("⚠ "+err.Error(), true)
 						return m, cmd
 					}
 
-					m.renderedImgOutput.renderedRunes = runeArray
-					m.renderedImgOutput.renderedColor = colorArray
+					m.renderedImgOutput.renderedCanvas = result.Canvas()
 
 					m.gifAnimation.StopAnimation()
 
-					m.renderContent = services.ImageRuneArrayIntoString(runeArray, colorArray, normalizedOptions.RenderColor)
+					m.renderContent = result.String()
 					_ = services.Logger().Info(fmt.Sprintf("%s", m.renderContent))
 
 					if !m.helpVisible {
@@ -634,6 +791,11 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.renderSettings.Confirm = true
 				return m, cmd
 			}
+			if m.currentActiveMenu == exportOptionsMenu {
+				m.exportSettings.SetActive(len(m.exportSettings.Items))
+				m.exportSettings.Confirm = true
+				return m, cmd
+			}
 			if m.currentActiveMenu == renderView {
 				m.renderView.PageDown()
 				return m, cmd
@@ -644,6 +806,11 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.renderSettings.Confirm = false
 				return m, cmd
 			}
+			if m.currentActiveMenu == exportOptionsMenu {
+				m.exportSettings.SetActive(0)
+				m.exportSettings.Confirm = false
+				return m, cmd
+			}
 			if m.currentActiveMenu == renderView {
 				m.renderView.PageUp()
 				return m, cmd
@@ -680,6 +847,15 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.filePicker, cmd = m.filePicker.Update(msg)
 		cmds = append(cmds, cmd)
 		if didSelect, path := m.filePicker.DidSelectFile(msg); didSelect {
+			if isGridFile(path) {
+				gridCmd, err := m.openGridFile(path)
+				if err != nil {
+					m.updateMessageViewPortContent("⚠ "+err.Error(), true)
+					return m, cmd
+				}
+				return m, tea.Batch(cmd, gridCmd)
+			}
+
 			m.selectedFile = path
 			_ = services.Logger().Info(fmt.Sprintf("Selected File: %s", m.selectedFile))
 
@@ -705,6 +881,22 @@ func (m *MezzotoneModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, cmd
 	}
+	if m.currentActiveMenu == saveAsMenu {
+		m.savePicker, cmd = m.savePicker.Update(msg)
+		if didSelect, path := m.savePicker.DidSelectFile(msg); didSelect {
+			m.closeSaveAs(path)
+		}
+		return m, cmd
+	}
+	if m.currentActiveMenu == exportOptionsMenu {
+		m.exportSettings, cmd = m.exportSettings.Update(msg)
+		if errMsg := m.exportSettings.ErrorMessage(); errMsg != "" {
+			m.updateMessageViewPortContent("⚠ "+errMsg, true)
+		} else {
+			m.updateMessageViewPortContent("Edit export options and confirm:", false)
+		}
+		return m, cmd
+	}
 	if m.currentActiveMenu == renderView {
 		m.renderView, cmd = m.renderView.Update(msg)
 		return m, cmd
@@ -724,6 +916,13 @@ func (m *MezzotoneModel) View() tea.View {
 	case filePickerMenu:
 		m.filePicker.Styles = m.style.filePickerStyle.filePickerActiveStyle
 		m.renderSettings.Styles = m.style.renderSettingsStyle.settingsPanelInactiveStyle
+	case exportOptionsMenu:
+		m.filePicker.Styles = m.style.filePickerStyle.filePickerInactiveStyle
+		m.exportSettings.Styles = m.style.renderSettingsStyle.settingsPanelActiveStyle
+	case saveAsMenu:
+		m.savePicker.Styles = m.style.filePickerStyle.filePickerActiveStyle
+		m.renderSettings.Styles = m.style.renderSettingsStyle.settingsPanelInactiveStyle
+		m.exportSettings.Styles = m.style.renderSettingsStyle.settingsPanelInactiveStyle
 	}
 
 	if m.style.isRenderViewFullscreen {
@@ -735,10 +934,19 @@ func (m *MezzotoneModel) View() tea.View {
 	innerW := m.style.leftColumnWidth - 2
 	messageViewportRender := m.style.messageViewStyle.renderStyle.Width(m.style.leftColumnWidth).Render(m.messageViewPort.View())
 
-	fpView := termtext.TruncateLinesANSI(m.filePicker.View(), innerW)
+	pickerView := m.filePicker.View()
+	if m.currentActiveMenu == saveAsMenu {
+		pickerView = m.savePicker.View()
+	}
+	fpView := termtext.TruncateLinesANSI(pickerView, innerW)
 	filePickerRender := m.style.filePickerStyle.renderStyle.Width(m.style.leftColumnWidth).Render(fpView)
 
-	renderSettingsRender := m.style.renderSettingsStyle.renderStyle.Width(m.style.leftColumnWidth).Render(m.renderSettings.View())
+	// The export settings take the place of the render options while an export waits on them.
+	settingsView := m.renderSettings.View()
+	if m.currentActiveMenu == exportOptionsMenu || (m.currentActiveMenu == saveAsMenu && m.saveAsPreviousMenu == exportOptionsMenu) {
+		settingsView = m.exportSettings.View()
+	}
+	renderSettingsRender := m.style.renderSettingsStyle.renderStyle.Width(m.style.leftColumnWidth).Render(settingsView)
 
 	lefColumnRender := lipgloss.JoinVertical(lipgloss.Top, messageViewportRender, filePickerRender, renderSettingsRender)
 
@@ -749,35 +957,61 @@ func (m *MezzotoneModel) View() tea.View {
 	return v
 }
 
-func normalizeRenderOptionsForService(settingsValues []ui.SettingItem) (services.RenderOptions, error) {
-	var textSize int
-	var fontAspect, edgeThreshold float64
-	var directionalRender, reverseChars, highContrast, renderColor bool
-	var runeMode string
+// Maps the render settings panel onto library options, invalid values are reported as *mezzotone.OptionError.
+func normalizeRenderOptionsForService(settingsValues []ui.SettingItem) (mezzotone.Options, error) {
+	options := mezzotone.DefaultOptions()
+	var colorMode, colorStops string
 
 	for _, item := range settingsValues {
 		switch item.Key {
 		case "textSize":
-			textSize, _ = strconv.Atoi(item.Value)
+			textSize, _ := strconv.Atoi(item.Value)
+			options.SetTextSize(textSize)
 		case "fontAspect":
-			fontAspect, _ = strconv.ParseFloat(item.Value, 2)
+			fontAspect, _ := strconv.ParseFloat(item.Value, 64)
+			options.SetFontAspect(fontAspect)
 		case "edgeThreshold":
-			edgeThreshold, _ = strconv.ParseFloat(item.Value, 2)
+			edgeThreshold, _ := strconv.ParseFloat(item.Value, 64)
+			options.SetEdgeThreshold(edgeThreshold)
 		case "directionalRender":
-			directionalRender, _ = strconv.ParseBool(item.Value)
+			directionalRender, _ := strconv.ParseBool(item.Value)
+			options.SetDirectionalRender(directionalRender)
 		case "reverseChars":
-			reverseChars, _ = strconv.ParseBool(item.Value)
+			reverseChars, _ := strconv.ParseBool(item.Value)
+			options.SetReverseChars(reverseChars)
 		case "highContrast":
-			highContrast, _ = strconv.ParseBool(item.Value)
+			highContrast, _ := strconv.ParseBool(item.Value)
+			options.SetHighContrast(highContrast)
 		case "renderColor":
-			renderColor, _ = strconv.ParseBool(item.Value)
+			renderColor, _ := strconv.ParseBool(item.Value)
+			options.SetRenderColor(renderColor)
 		case "runeMode":
-			runeMode = item.Value
+			options.SetRuneMode(item.Value)
+		case "colorEstimator":
+			options.SetColorEstimator(item.Value)
+		case "colorMode":
+			colorMode = item.Value
+		case "colorStops":
+			colorStops = item.Value
+		case "saturation":
+			saturation, _ := strconv.ParseFloat(item.Value, 64)
+			options.SetSaturation(saturation)
+		case "vibrance":
+			vibrance, _ := strconv.ParseFloat(item.Value, 64)
+			options.SetVibrance(vibrance)
 		}
 	}
-	options, err := services.NewRenderOptions(textSize, fontAspect, directionalRender, edgeThreshold, reverseChars, highContrast, renderColor, runeMode)
+
+	stops, err := mezzotone.ParseHexColorList(colorStops)
 	if err != nil {
-		return services.RenderOptions{}, err
+		return mezzotone.Options{}, err
+	}
+	if colorMode != "" {
+		options.SetColorMode(colorMode, stops...)
+	}
+
+	if err := options.Validate(); err != nil {
+		return mezzotone.Options{}, err
 	}
 	return options, nil
 }
@@ -792,6 +1026,53 @@ func (m *MezzotoneModel) getRenderColor() bool {
 	return false
 }
 
+// currentRenderCanvas returns the rendered still, or the animation frame on screen. It is nil before a render.
+func (m *MezzotoneModel) currentRenderCanvas() *canvas.Canvas {
+	if m.renderedImgOutput.renderedCanvas != nil {
+		return m.renderedImgOutput.renderedCanvas
+	}
+	frames := m.renderedGifOutput.renderedFrames
+	if len(frames) == 0 {
+		return nil
+	}
+	i := m.gifAnimation.GetcurrentFrameIndex()
+	if i < 0 || i >= len(frames) {
+		return frames[0]
+	}
+	return frames[i]
+}
+
+func (m *MezzotoneModel) getFontAspect() float64 {
+	fontAspect := 1.0
+	for i := range m.renderSettings.Items {
+		if m.renderSettings.Items[i].Key == "fontAspect" {
+			fontAspect, _ = strconv.ParseFloat(m.renderSettings.Items[i].Value, 2)
+		}
+	}
+	return fontAspect
+}
+
+func (m *MezzotoneModel) animationExportFrames() []export.ASCIIGIFFrame {
+	frames := make([]export.ASCIIGIFFrame, 0, len(m.renderedGifOutput.renderedFrames))
+	for i := range m.renderedGifOutput.renderedFrames {
+		frames = append(frames, export.ASCIIGIFFrame{
+			Canvas:   m.renderedGifOutput.renderedFrames[i],
+			Duration: m.renderedGifOutput.delayTimes[i],
+		})
+	}
+	return frames
+}
+
+func (m *MezzotoneModel) getSequenceFPS() float64 {
+	for _, item := range m.renderSettings.Items {
+		if item.Key == "sequenceFPS" {
+			value, _ := strconv.ParseFloat(item.Value, 64)
+			return value
+		}
+	}
+	return defaultSequenceFPS
+}
+
 func (m *MezzotoneModel) incrementCurrentActiveMenu() {
 	m.currentActiveMenu++
 	m.updateMessageTextOnMenuChange()
@@ -813,6 +1094,12 @@ func (m *MezzotoneModel) updateMessageTextOnMenuChange() {
 	case renderView:
 		m.updateMessageViewPortContent("Press f for fullscreen, see export options with h", false)
 		break
+	case exportOptionsMenu:
+		m.updateMessageViewPortContent("Edit export options and confirm:", false)
+		break
+	case saveAsMenu:
+		m.updateMessageViewPortContent("Pick the export folder, enter selects, s uses the current one:", false)
+		break
 	}
 }
 
@@ -833,6 +1120,66 @@ func (m *MezzotoneModel) updateMessageViewPortContent(messageViewContent string,
 	)
 }
 
+// loadAnimationFrames decodes multi-frame sources (image sequences, GIF, APNG, animated WebP and Y4M) into frames and durations.
+// loopCount is the GIF loop count in image/gif terms, other sources loop forever (0).
+// isAnimation is false for single images, which are decoded by the caller.
+func loadAnimationFrames(path string, sequenceFPS float64) (frames []image.Image, delays []time.Duration, loopCount int, isAnimation bool, err error) {
+	switch {
+	case IsImageSequence(path):
+		frames, delays, err = LoadImageSequence(path, sequenceFPS)
+		return frames, delays, 0, true, err
+
+	case IsGIF(path):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, 0, true, err
+		}
+		defer func() { _ = f.Close() }()
+
+		frames, gifDelays, loopCount, err := SplitAnimatedGIF(f)
+		if err != nil {
+			return nil, nil, 0, true, err
+		}
+		delays = make([]time.Duration, len(gifDelays))
+		for i, delay := range gifDelays {
+			delays[i] = time.Duration(delay) * 10 * time.Millisecond
+		}
+		return frames, delays, loopCount, true, nil
+
+	case IsAPNG(path):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, 0, true, err
+		}
+		defer func() { _ = f.Close() }()
+
+		frames, delays, err = SplitAPNG(f)
+		return frames, delays, 0, true, err
+
+	case IsAnimatedWebP(path):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, 0, true, err
+		}
+		defer func() { _ = f.Close() }()
+
+		frames, delays, err = SplitAnimatedWebP(f)
+		return frames, delays, 0, true, err
+
+	case IsY4M(path):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, 0, true, err
+		}
+		defer func() { _ = f.Close() }()
+
+		frames, delays, err = SplitY4M(f)
+		return frames, delays, 0, true, err
+	}
+
+	return nil, nil, 0, false, nil
+}
+
 func IsGIF(path string) bool {
 	f, err := os.Open(path)
 	if err != nil {
@@ -848,10 +1195,10 @@ func IsGIF(path string) bool {
 	return format == "gif"
 }
 
-// SplitAnimatedGIF decodes an animated GIF and returns frames plus per-frame delayTimes.
+// SplitAnimatedGIF decodes an animated GIF and returns frames plus per-frame delayTimes and the loop count.
 // GIF frames are often partial/offset “patches”, so playback is simulated by drawing each frame onto a
 // full-size RGBA canvas and then clone the canvas after each draw so frames don’t share the same pixel buffer.
-func SplitAnimatedGIF(r io.Reader) (frames []image.Image, delays []int, err error) {
+func SplitAnimatedGIF(r io.Reader) (frames []image.Image, delays []int, loopCount int, err error) {
 	defer func() {
 		if rec := recover(); rec != nil {
 			err = fmt.Errorf("panic while decoding gif: %v", rec)
@@ -860,10 +1207,10 @@ func SplitAnimatedGIF(r io.Reader) (frames []image.Image, delays []int, err erro
 
 	g, err := gif.DecodeAll(r)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 	if len(g.Image) == 0 {
-		return nil, nil, fmt.Errorf("gif has no frames")
+		return nil, nil, 0, fmt.Errorf("gif has no frames")
 	}
 
 	w, h := g.Config.Width, g.Config.Height
@@ -911,7 +1258,7 @@ func SplitAnimatedGIF(r io.Reader) (frames []image.Image, delays []int, err erro
 		}
 	}
 
-	return frames, delays, nil
+	return frames, delays, g.LoopCount, nil
 }
 
 func cloneRGBA(src *image.RGBA) *image.RGBA {
@@ -920,7 +1267,7 @@ func cloneRGBA(src *image.RGBA) *image.RGBA {
 	return dst
 }
 
-func exportAsciiToGifCmd(outPath string, frames []export.ASCIIGIFFrame, exportOptions export.ASCIIExportOptions) tea.Cmd {
+func exportAsciiToGifCmd(outPath string, frames []export.ASCIIGIFFrame, exportOptions export.ASCIIExportOptions, gifOptions export.GIFExportOptions) tea.Cmd {
 	return func() (msg tea.Msg) {
 		defer func() {
 			if rec := recover(); rec != nil {
@@ -938,16 +1285,223 @@ func exportAsciiToGifCmd(outPath string, frames []export.ASCIIGIFFrame, exportOp
 			}
 		}
 
-		err := export.ASCIIFramesToGIF(frames, outPath, exportOptions)
+		report, err := export.ASCIIFramesToGIF(frames, outPath, exportOptions, gifOptions)
 
 		msg = gifExportDoneMsg{
 			outPath: outPath,
+			report:  report,
+			warning: glyphCoverageWarning(frameCanvases(frames), exportOptions),
+			err:     err,
+		}
+		return msg
+	}
+}
+
+func exportAsciiToAsciicastCmd(outPath string, frames []export.ASCIIGIFFrame, textOptions export.TextExportOptions) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				msg = asciicastExportDoneMsg{
+					outPath: outPath,
+					err:     fmt.Errorf("asciicast export panic: %v", rec),
+				}
+			}
+		}()
+
+		if len(frames) == 0 {
+			return asciicastExportDoneMsg{
+				outPath: outPath,
+				err:     fmt.Errorf("no rendered frames available to export"),
+			}
+		}
+
+		msg = asciicastExportDoneMsg{
+			outPath: outPath,
+			err:     export.ASCIIFramesToAsciicast(frames, outPath, textOptions),
+		}
+		return msg
+	}
+}
+
+func exportAsciiToVideoCmd(outPath string, format string, frames []export.ASCIIGIFFrame, exportOptions export.ASCIIExportOptions) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				msg = videoExportDoneMsg{
+					outPath: outPath,
+					err:     fmt.Errorf("video export panic: %v", rec),
+				}
+			}
+		}()
+
+		if len(frames) == 0 {
+			return videoExportDoneMsg{
+				outPath: outPath,
+				err:     fmt.Errorf("no rendered frames available to export"),
+			}
+		}
+
+		var err error
+		switch format {
+		case videoFormatY4M:
+			err = export.ASCIIFramesToY4M(frames, outPath, exportOptions)
+		default:
+			err = export.ASCIIFramesToAVI(frames, outPath, exportOptions)
+		}
+
+		msg = videoExportDoneMsg{
+			outPath: outPath,
+			warning: glyphCoverageWarning(frameCanvases(frames), exportOptions),
 			err:     err,
 		}
 		return msg
 	}
 }
 
+// maxWarnedRunes bounds the runes listed by glyphCoverageWarning, the message view is a few lines high.
+const maxWarnedRunes = 16
+
+// glyphCoverageWarning names the runes no export or fallback font has a glyph for, raster exports draw
+// them as boxes. It is empty when every rune is covered.
+func glyphCoverageWarning(canvases []*canvas.Canvas, exportOptions export.ASCIIExportOptions) string {
+	uncovered, err := export.UncoveredRunes(canvases, exportOptions)
+	if err != nil || len(uncovered) == 0 {
+		return ""
+	}
+
+	listed := string(uncovered[:min(len(uncovered), maxWarnedRunes)])
+	if len(uncovered) > maxWarnedRunes {
+		listed += " ..."
+	}
+	return fmt.Sprintf("\n⚠ %d runes missing from the export fonts: %s", len(uncovered), listed)
+}
+
+func frameCanvases(frames []export.ASCIIGIFFrame) []*canvas.Canvas {
+	canvases := make([]*canvas.Canvas, 0, len(frames))
+	for _, frame := range frames {
+		canvases = append(canvases, frame.Canvas)
+	}
+	return canvases
+}
+
+func exportAsciiToAPNGCmd(outPath string, frames []export.ASCIIGIFFrame, exportOptions export.ASCIIExportOptions) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				msg = apngExportDoneMsg{
+					outPath: outPath,
+					err:     fmt.Errorf("apng export panic: %v", rec),
+				}
+			}
+		}()
+
+		if len(frames) == 0 {
+			return apngExportDoneMsg{
+				outPath: outPath,
+				err:     fmt.Errorf("no rendered frames available to export"),
+			}
+		}
+
+		msg = apngExportDoneMsg{
+			outPath: outPath,
+			warning: glyphCoverageWarning(frameCanvases(frames), exportOptions),
+			err:     export.ASCIIFramesToAPNG(frames, outPath, exportOptions),
+		}
+		return msg
+	}
+}
+
+func exportAsciiToSvgCmd(outPath string, imgOutput renderedImgOutput, exportOptions export.ASCIIExportOptions) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				msg = svgExportDoneMsg{
+					outPath: outPath,
+					err:     fmt.Errorf("svg export panic: %v", rec),
+				}
+			}
+		}()
+
+		msg = svgExportDoneMsg{
+			outPath: outPath,
+			err:     export.ASCIIToSVG(imgOutput.renderedCanvas, outPath, exportOptions),
+		}
+		return msg
+	}
+}
+
+func exportAsciiToWebAnimationCmd(outPath string, format string, frames []export.ASCIIGIFFrame, exportOptions export.ASCIIExportOptions) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				msg = webAnimationExportDoneMsg{
+					outPath: outPath,
+					err:     fmt.Errorf("%s export panic: %v", format, rec),
+				}
+			}
+		}()
+
+		if len(frames) == 0 {
+			return webAnimationExportDoneMsg{
+				outPath: outPath,
+				err:     fmt.Errorf("no rendered frames available to export"),
+			}
+		}
+
+		var err error
+		switch format {
+		case webFormatHTML:
+			err = export.ASCIIFramesToAnimatedHTML(frames, outPath, exportOptions)
+		default:
+			err = export.ASCIIFramesToAnimatedSVG(frames, outPath, exportOptions)
+		}
+
+		msg = webAnimationExportDoneMsg{
+			outPath: outPath,
+			err:     err,
+		}
+		return msg
+	}
+}
+
+func exportAsciiToPdfCmd(outPath string, renderCanvas *canvas.Canvas, exportOptions export.ASCIIExportOptions, pdfOptions export.PDFExportOptions) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				msg = pdfExportDoneMsg{
+					outPath: outPath,
+					err:     fmt.Errorf("pdf export panic: %v", rec),
+				}
+			}
+		}()
+
+		msg = pdfExportDoneMsg{
+			outPath: outPath,
+			err:     export.ASCIIToPDF(renderCanvas, outPath, exportOptions, pdfOptions),
+		}
+		return msg
+	}
+}
+
+func exportAsciiToHtmlCmd(outPath string, imgOutput renderedImgOutput, exportOptions export.ASCIIExportOptions, htmlOptions export.HTMLExportOptions) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				msg = htmlExportDoneMsg{
+					outPath: outPath,
+					err:     fmt.Errorf("html export panic: %v", rec),
+				}
+			}
+		}()
+
+		msg = htmlExportDoneMsg{
+			outPath: outPath,
+			err:     export.ASCIIToHTML(imgOutput.renderedCanvas, outPath, exportOptions, htmlOptions),
+		}
+		return msg
+	}
+}
+
 func exportAsciiToPngCmd(outPath string, imgOutput renderedImgOutput, exportOptions export.ASCIIExportOptions) tea.Cmd {
 	return func() (msg tea.Msg) {
 		defer func() {
@@ -959,9 +1513,17 @@ func exportAsciiToPngCmd(outPath string, imgOutput renderedImgOutput, exportOpti
 			}
 		}()
 
-		err := export.ASCIIToPNG(imgOutput.renderedRunes, imgOutput.renderedColor, outPath, exportOptions)
+		if imgOutput.renderedCanvas == nil {
+			return pngExportDoneMsg{
+				outPath: outPath,
+				err:     fmt.Errorf("no rendered image available to export"),
+			}
+		}
+
+		err := export.ASCIIToPNG(imgOutput.renderedCanvas, outPath, exportOptions)
 		msg = pngExportDoneMsg{
 			outPath: outPath,
+			warning: glyphCoverageWarning([]*canvas.Canvas{imgOutput.renderedCanvas}, exportOptions),
 			err:     err,
 		}
 		return msg
@@ -1,15 +1,21 @@
 package app
 
 import (
+	"bytes"
+	"image"
 	"image/color"
 	"image/gif"
 	"image/png"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/export"
+
 	tea "charm.land/bubbletea/v2"
 	"github.com/google/uuid"
 	"golang.design/x/clipboard"
@@ -23,6 +29,22 @@ func keyChar(ch string) tea.KeyPressMsg {
 	return tea.KeyPressMsg(tea.Key{Text: ch, Code: code})
 }
 
+// confirmExport presses an export key in the render view and confirms the export settings it opens.
+func confirmExport(t *testing.T, m *MezzotoneModel, exportKey string) tea.Cmd {
+	t.Helper()
+
+	_, cmd := m.Update(keyChar(exportKey))
+	if cmd != nil || m.currentActiveMenu != exportOptionsMenu {
+		t.Fatalf("expected %q to open the export settings", exportKey)
+	}
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyPgDown}))
+	_, cmd = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter}))
+	if m.currentActiveMenu != renderView {
+		t.Fatalf("expected confirm to return to the render view, got menu %d", m.currentActiveMenu)
+	}
+	return cmd
+}
+
 func TestMezzotoneModelExportTxtSavesRenderedContentToHome(t *testing.T) {
 	tmpHome := t.TempDir()
 	t.Setenv("HOME", tmpHome)
@@ -38,12 +60,12 @@ func TestMezzotoneModelExportTxtSavesRenderedContentToHome(t *testing.T) {
 	m.messageViewPort.SetWidth(120)
 	m.messageViewPort.SetHeight(3)
 	m.renderedImgOutput = renderedImgOutput{
-		renderedRunes: [][]rune{
+		renderedCanvas: canvas.FromRunes([][]rune{
 			[]rune("rendered-output"),
-		},
+		}, nil),
 	}
 
-	_, _ = m.Update(keyChar("t"))
+	confirmExport(t, m, "t")
 
 	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".txt")
 	t.Cleanup(func() {
@@ -55,8 +77,50 @@ func TestMezzotoneModelExportTxtSavesRenderedContentToHome(t *testing.T) {
 	if err != nil {
 		t.Fatalf("expected exported file at %q, got read error: %v", exportPath, err)
 	}
-	if string(got) != m.renderContent {
-		t.Fatalf("expected exported file content %q, got %q", m.renderContent, string(got))
+	if string(got) != "rendered-output\n" {
+		t.Fatalf("expected exported file content %q, got %q", "rendered-output\n", string(got))
+	}
+}
+
+func TestMezzotoneModelExportAnsWritesColorCodes(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("7c4e2a10-9b3d-4f6e-8a1c-2d5f0e3b4c71")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("ab")}, [][]color.NRGBA{
+			{{R: 255, A: 255}, {R: 255, A: 255}},
+		}),
+	}
+
+	confirmExport(t, m, "T")
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".ans")
+	got, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected exported file at %q, got read error: %v", exportPath, err)
+	}
+	want := "\x1b[0;38;2;255;0;0mab\n\x1b[0m"
+	if string(got) != want {
+		t.Fatalf("expected ans content %q, got %q", want, string(got))
+	}
+
+	m.setExportSetting("onConflict", conflictOverwrite)
+	m.setExportSetting("colorDepth", colorDepth16)
+	m.setExportSetting("lineEnding", lineEndingCRLF)
+	m.setExportSetting("finalReset", "FALSE")
+	confirmExport(t, m, "T")
+	got, err = os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected exported file at %q, got read error: %v", exportPath, err)
+	}
+	if want := "\x1b[0;31mab\r\n"; string(got) != want {
+		t.Fatalf("expected 16 color ans with CRLF and no final reset %q, got %q", want, string(got))
 	}
 }
 
@@ -75,12 +139,12 @@ func TestMezzotoneModelExportPngCreatesValidPNG(t *testing.T) {
 	m.messageViewPort.SetWidth(120)
 	m.messageViewPort.SetHeight(3)
 	m.renderedImgOutput = renderedImgOutput{
-		renderedRunes: [][]rune{
+		renderedCanvas: canvas.FromRunes([][]rune{
 			[]rune("rendered-output"),
-		},
+		}, nil),
 	}
 
-	_, cmd := m.Update(keyChar("i"))
+	cmd := confirmExport(t, m, "i")
 	if cmd == nil {
 		t.Fatalf("expected png export command")
 	}
@@ -101,6 +165,108 @@ func TestMezzotoneModelExportPngCreatesValidPNG(t *testing.T) {
 	}
 }
 
+func TestMezzotoneModelExportWithEmptyRenderShowsError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for _, exportKey := range []string{"i", "S", "p", "H"} {
+		m := NewMezzotoneModel()
+		m.currentActiveMenu = renderView
+		m.style.leftColumnWidth = 120
+		m.messageViewPort.SetWidth(120)
+		m.messageViewPort.SetHeight(3)
+		// An animation whose frames are not rendered yet must not be indexed.
+		m.renderedGifOutput = renderedGifOutput{}
+
+		if cmd := confirmExport(t, m, exportKey); cmd != nil {
+			t.Fatalf("expected no %q export command for an empty render", exportKey)
+		}
+		if !strings.Contains(m.messageViewPort.View(), "nothing to export") {
+			t.Fatalf("expected empty render error for %q, got %q", exportKey, m.messageViewPort.View())
+		}
+	}
+}
+
+func TestMezzotoneModelExportPngWarnsAboutUncoveredRunes(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("ab\u0378\u0379")}, nil),
+	}
+
+	m.setExportSetting("fallbackFonts", filepath.Join(tmpHome, "missing.ttf"))
+	_, _ = m.Update(keyChar("i"))
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyPgDown}))
+	if _, cmd := m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter})); cmd != nil || m.currentActiveMenu != exportOptionsMenu {
+		t.Fatalf("expected a missing fallback font to keep the export settings open")
+	}
+	if !strings.Contains(currentMessage, "fallback font") {
+		t.Fatalf("expected a fallback font error, got %q", currentMessage)
+	}
+	m.closeExportSettings()
+	m.setExportSetting("fallbackFonts", "")
+
+	cmd := confirmExport(t, m, "i")
+	if cmd == nil {
+		t.Fatalf("expected png export command")
+	}
+	_, _ = m.Update(cmd())
+	if !strings.Contains(currentMessage, "Successfully exported") || !strings.Contains(currentMessage, "2 runes missing from the export fonts: \u0378\u0379") {
+		t.Fatalf("expected a success message warning about the uncovered runes, got %q", currentMessage)
+	}
+}
+
+func TestMezzotoneModelExportPngUsesCellSizeAndFontAxes(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("#@M"), []rune("W%y")}, nil),
+	}
+
+	m.setExportSetting("cellSize", "0x20")
+	_, _ = m.Update(keyChar("i"))
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyPgDown}))
+	if _, cmd := m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter})); cmd != nil || !strings.Contains(currentMessage, "cell size") {
+		t.Fatalf("expected an invalid cell size to keep the export settings open, got %q", currentMessage)
+	}
+	m.closeExportSettings()
+
+	m.setExportSetting("fileName", "cells")
+	m.setExportSetting("cellSize", "30x")
+	m.setExportSetting("lineSpacing", "1.5")
+	m.setExportSetting("fontWeight", "700")
+	m.setExportSetting("fontWidth", "80")
+	exportOptions, err := m.asciiExportOptions()
+	if err != nil {
+		t.Fatalf("asciiExportOptions failed: %v", err)
+	}
+	if exportOptions.CellWidth != 30 || exportOptions.CellHeight != 0 || exportOptions.FontWeight != 700 ||
+		exportOptions.FontWidth != 80 || exportOptions.FitWidthToAspect {
+		t.Fatalf("unexpected cell and axis options: %+v", exportOptions)
+	}
+
+	cmd := confirmExport(t, m, "i")
+	_, _ = m.Update(cmd())
+	f, err := os.Open(filepath.Join(tmpHome, "cells.png"))
+	if err != nil {
+		t.Fatalf("expected png export file, got error: %v (%q)", err, currentMessage)
+	}
+	defer f.Close()
+	cfg, err := png.DecodeConfig(f)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	// 14pt at 300 dpi gives an 80 px font line, 1.5 line spacing makes it 120 px.
+	if cfg.Width != 3*30 || cfg.Height != 2*120 {
+		t.Fatalf("expected 30x120 cells without aspect stretching, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
 func TestMezzotoneModelExportGifCreatesValidGIF(t *testing.T) {
 	tmpHome := t.TempDir()
 	t.Setenv("HOME", tmpHome)
@@ -116,18 +282,15 @@ func TestMezzotoneModelExportGifCreatesValidGIF(t *testing.T) {
 	m.messageViewPort.SetWidth(120)
 	m.messageViewPort.SetHeight(3)
 	m.renderedGifOutput = renderedGifOutput{
-		renderedRunes: [][][]rune{
-			{[]rune("rendered-output")},
-		},
-		renderedColor: [][][]color.NRGBA{
-			{{{R: 255, G: 255, B: 255, A: 255}}},
+		renderedFrames: []*canvas.Canvas{
+			canvas.FromRunes([][]rune{[]rune("rendered-output")}, [][]color.NRGBA{{{R: 255, G: 255, B: 255, A: 255}}}),
 		},
 		delayTimes: []time.Duration{
 			50 * time.Millisecond,
 		},
 	}
 
-	_, cmd := m.Update(keyChar("g"))
+	cmd := confirmExport(t, m, "g")
 	if cmd == nil {
 		t.Fatalf("expected gif export command")
 	}
@@ -163,13 +326,9 @@ func TestMezzotoneModelExportGifFromAnimationExportsMultipleFrames(t *testing.T)
 	m.messageViewPort.SetWidth(120)
 	m.messageViewPort.SetHeight(3)
 	m.renderedGifOutput = renderedGifOutput{
-		renderedRunes: [][][]rune{
-			{[]rune("frame-one")},
-			{[]rune("frame-two")},
-		},
-		renderedColor: [][][]color.NRGBA{
-			{{{R: 255, G: 255, B: 255, A: 255}}},
-			{{{R: 255, G: 255, B: 255, A: 255}}},
+		renderedFrames: []*canvas.Canvas{
+			canvas.FromRunes([][]rune{[]rune("frame-one")}, [][]color.NRGBA{{{R: 255, G: 255, B: 255, A: 255}}}),
+			canvas.FromRunes([][]rune{[]rune("frame-two")}, [][]color.NRGBA{{{R: 255, G: 255, B: 255, A: 255}}}),
 		},
 		delayTimes: []time.Duration{
 			40 * time.Millisecond,
@@ -177,7 +336,7 @@ func TestMezzotoneModelExportGifFromAnimationExportsMultipleFrames(t *testing.T)
 		},
 	}
 
-	_, cmd := m.Update(keyChar("g"))
+	cmd := confirmExport(t, m, "g")
 	if cmd == nil {
 		t.Fatalf("expected gif export command")
 	}
@@ -199,6 +358,77 @@ func TestMezzotoneModelExportGifFromAnimationExportsMultipleFrames(t *testing.T)
 	}
 }
 
+func TestMezzotoneModelExportGifKeepsSourceLoopCountUnlessOverridden(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	// A source gif that plays 3 times with delays browsers raise to 100 ms.
+	src := &gif.GIF{LoopCount: 2}
+	for i := 0; i < 2; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+		frame.SetColorIndex(i, i, 1)
+		src.Image = append(src.Image, frame)
+		src.Delay = append(src.Delay, i)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, src); err != nil {
+		t.Fatalf("failed to encode source gif: %v", err)
+	}
+	frames, delays, loopCount, err := SplitAnimatedGIF(&buf)
+	if err != nil || loopCount != 2 {
+		t.Fatalf("expected loop count 2 from the source, got %d (%v)", loopCount, err)
+	}
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedGifOutput.loopCount = loopCount
+	for i := range frames {
+		m.renderedGifOutput.renderedFrames = append(m.renderedGifOutput.renderedFrames,
+			canvas.FromRunes([][]rune{[]rune(strings.Repeat("#", i+1))}, nil))
+		m.renderedGifOutput.delayTimes = append(m.renderedGifOutput.delayTimes, time.Duration(delays[i])*10*time.Millisecond)
+	}
+
+	m.setExportSetting("fileName", "loop")
+	m.setExportSetting("onConflict", conflictOverwrite)
+	exportGif := func() *gif.GIF {
+		t.Helper()
+		cmd := confirmExport(t, m, "g")
+		if cmd == nil {
+			t.Fatalf("expected gif export command, got %q", currentMessage)
+		}
+		_, _ = m.Update(cmd())
+		data, err := os.ReadFile(filepath.Join(tmpHome, "loop.gif"))
+		if err != nil {
+			t.Fatalf("expected gif export, got %q: %v", currentMessage, err)
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to decode gif export: %v", err)
+		}
+		return g
+	}
+
+	g := exportGif()
+	if g.LoopCount != 2 {
+		t.Fatalf("expected the source loop count 2, got %d", g.LoopCount)
+	}
+	if !slices.Equal(g.Delay, []int{10, 10}) {
+		t.Fatalf("expected browser compatible delays [10 10], got %v", g.Delay)
+	}
+
+	m.setExportSetting("gifLoops", "1")
+	m.setExportSetting("gifTiming", export.GIFDelayFixedFPS)
+	m.setExportSetting("gifFPS", "25")
+	m.setExportSetting("gifSpeed", "2")
+	g = exportGif()
+	if g.LoopCount != -1 {
+		t.Fatalf("expected a single play (loop count -1), got %d", g.LoopCount)
+	}
+	if !slices.Equal(g.Delay, []int{2, 2}) {
+		t.Fatalf("expected 25 FPS at double speed to give [2 2], got %v", g.Delay)
+	}
+}
+
 func TestMezzotoneModelCopyToClipboardWhenUnavailableShowsError(t *testing.T) {
 	previousClipboardOK := clipboardOK
 	t.Cleanup(func() { clipboardOK = previousClipboardOK })
@@ -208,6 +438,9 @@ func TestMezzotoneModelCopyToClipboardWhenUnavailableShowsError(t *testing.T) {
 	m := NewMezzotoneModel()
 	m.currentActiveMenu = renderView
 	m.renderContent = "rendered-output"
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("rendered-output")}, nil),
+	}
 	m.style.leftColumnWidth = 120
 	m.messageViewPort.SetWidth(120)
 	m.messageViewPort.SetHeight(3)
@@ -276,3 +509,665 @@ func TestCopyTextToClipboardKeepsContentAsIs(t *testing.T) {
 		t.Fatalf("expected copied content %q, got %q", colored, string(gotData))
 	}
 }
+
+func TestMezzotoneModelExportVideoFromAnimationCreatesAVI(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("5b0e1c8a-3f7d-4c1e-9a52-6d1f0c7e2a11")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.style.leftColumnWidth = 120
+	m.messageViewPort.SetWidth(120)
+	m.messageViewPort.SetHeight(3)
+	m.renderedGifOutput = renderedGifOutput{
+		renderedFrames: []*canvas.Canvas{
+			canvas.FromRunes([][]rune{[]rune("frame-one")}, nil),
+			canvas.FromRunes([][]rune{[]rune("frame-two")}, nil),
+		},
+		delayTimes: []time.Duration{
+			40 * time.Millisecond,
+			80 * time.Millisecond,
+		},
+	}
+
+	cmd := confirmExport(t, m, "v")
+	if cmd == nil {
+		t.Fatalf("expected video export command")
+	}
+	if !strings.Contains(m.messageViewPort.View(), "Exporting video to") {
+		t.Fatalf("expected exporting video message before command completion, got %q", m.messageViewPort.View())
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".avi")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected avi export file at %q, got error: %v", exportPath, err)
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "AVI " {
+		t.Fatalf("expected avi file, got header %q", data[:12])
+	}
+	if !strings.Contains(m.messageViewPort.View(), "Successfully exported to") {
+		t.Fatalf("expected success message, got %q", m.messageViewPort.View())
+	}
+}
+
+func TestMezzotoneModelExportVideoFromStillCreatesY4M(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("0c6f2d4e-8b1a-4f3e-a7c9-2e5d8f1b3c44")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("still")}, nil),
+	}
+
+	cmd := confirmExport(t, m, "V")
+	if cmd == nil {
+		t.Fatalf("expected video export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".y4m")
+	f, err := os.Open(exportPath)
+	if err != nil {
+		t.Fatalf("expected y4m export file at %q, got error: %v", exportPath, err)
+	}
+	defer f.Close()
+
+	frames, delays, err := SplitY4M(f)
+	if err != nil {
+		t.Fatalf("expected exported y4m to decode: %v", err)
+	}
+	var total time.Duration
+	for _, d := range delays {
+		total += d
+	}
+	if len(frames) == 0 || total != stillVideoDuration {
+		t.Fatalf("expected %v of video, got %d frames lasting %v", stillVideoDuration, len(frames), total)
+	}
+}
+
+func TestMezzotoneModelExportAPNGFromAnimation(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("7d3a9e21-4c6b-4f08-b1d5-93a8e0c6f257")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.style.leftColumnWidth = 120
+	m.messageViewPort.SetWidth(120)
+	m.messageViewPort.SetHeight(3)
+	m.renderedGifOutput = renderedGifOutput{
+		renderedFrames: []*canvas.Canvas{
+			canvas.FromRunes([][]rune{[]rune("frame-one")}, nil),
+			canvas.FromRunes([][]rune{[]rune("frame-two")}, nil),
+		},
+		delayTimes: []time.Duration{
+			40 * time.Millisecond,
+			80 * time.Millisecond,
+		},
+	}
+
+	cmd := confirmExport(t, m, "a")
+	if cmd == nil {
+		t.Fatalf("expected apng export command")
+	}
+	if !strings.Contains(m.messageViewPort.View(), "Exporting animated png to") {
+		t.Fatalf("expected exporting message before command completion, got %q", m.messageViewPort.View())
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".png")
+	f, err := os.Open(exportPath)
+	if err != nil {
+		t.Fatalf("expected apng export file at %q, got error: %v", exportPath, err)
+	}
+	defer f.Close()
+
+	frames, delays, err := SplitAPNG(f)
+	if err != nil {
+		t.Fatalf("expected exported apng to decode: %v", err)
+	}
+	if len(frames) != 2 || delays[0] != 40*time.Millisecond || delays[1] != 80*time.Millisecond {
+		t.Fatalf("expected 2 frames of 40ms and 80ms, got %d frames %v", len(frames), delays)
+	}
+	if !strings.Contains(m.messageViewPort.View(), "Successfully exported to") {
+		t.Fatalf("expected success message, got %q", m.messageViewPort.View())
+	}
+}
+
+func TestMezzotoneModelExportSvgCreatesTextSVG(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("a4f1c2d3-5e6b-4a7c-8d9e-0f1a2b3c4d5e")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("<svg&>")}, nil),
+	}
+
+	cmd := confirmExport(t, m, "S")
+	if cmd == nil {
+		t.Fatalf("expected svg export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".svg")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected svg export file at %q, got error: %v", exportPath, err)
+	}
+	if !strings.Contains(string(data), "&lt;svg&amp;&gt;") {
+		t.Fatalf("expected escaped text in svg")
+	}
+	if !strings.Contains(string(data), "data:font/woff;base64,") {
+		t.Fatalf("expected embedded font in svg")
+	}
+}
+
+func TestMezzotoneModelExportSvgReferencesFontFamilyWithoutEmbedding(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("7d8e9f0a-1b2c-4d3e-8f4a-5b6c7d8e9f0a")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("svg")}, nil),
+	}
+
+	m.setExportSetting("embedFont", "FALSE")
+	m.setExportSetting("fontFamily", "'Iosevka', monospace")
+	cmd := confirmExport(t, m, "S")
+	if cmd == nil {
+		t.Fatalf("expected svg export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".svg")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected svg export file at %q, got error: %v", exportPath, err)
+	}
+	if strings.Contains(string(data), "@font-face") {
+		t.Fatalf("expected no embedded font in svg")
+	}
+	if !strings.Contains(string(data), "font-family:'Iosevka', monospace;") {
+		t.Fatalf("expected font family from the export settings, got %q", data)
+	}
+}
+
+func TestMezzotoneModelExportAnimationToHTML(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("c8b2e4f6-1a3d-4e5f-9b7c-2d4e6f8a0b1c")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedGifOutput = renderedGifOutput{
+		renderedFrames: []*canvas.Canvas{
+			canvas.FromRunes([][]rune{[]rune("frame-one")}, nil),
+			canvas.FromRunes([][]rune{[]rune("frame-two")}, nil),
+			canvas.FromRunes([][]rune{[]rune("frame-one")}, nil),
+		},
+		delayTimes: []time.Duration{
+			40 * time.Millisecond,
+			80 * time.Millisecond,
+			40 * time.Millisecond,
+		},
+	}
+
+	cmd := confirmExport(t, m, "W")
+	if cmd == nil {
+		t.Fatalf("expected html export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".html")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected html export file at %q, got error: %v", exportPath, err)
+	}
+	html := string(data)
+	if !strings.Contains(html, "animation:160ms step-end infinite") {
+		t.Fatalf("expected timing from delayTimes in html")
+	}
+	if n := strings.Count(html, `class="frame"`); n != 2 {
+		t.Fatalf("expected repeated frame to be emitted once, got %d groups", n)
+	}
+}
+
+func TestMezzotoneModelExportHtmlFragment(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("e1d2c3b4-a596-4788-9a0b-1c2d3e4f5a6b")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("a<b")}, nil),
+	}
+
+	cmd := confirmExport(t, m, "P")
+	if cmd == nil {
+		t.Fatalf("expected html export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".html")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected html export file at %q, got error: %v", exportPath, err)
+	}
+	if !strings.HasPrefix(string(data), "<pre style=") || !strings.Contains(string(data), "a&lt;b</pre>") {
+		t.Fatalf("expected pre fragment with escaped text, got %q", data)
+	}
+}
+
+func TestMezzotoneModelExportHtmlUsesThemeAndFontFamily(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("5c6d7e8f-9a0b-4c1d-8e2f-3a4b5c6d7e8f")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.style.leftColumnWidth = 120
+	m.messageViewPort.SetWidth(120)
+	m.messageViewPort.SetHeight(3)
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("ab")}, nil),
+	}
+
+	m.setExportSetting("fontFamily", "Iosevka; color:red")
+	_, _ = m.Update(keyChar("H"))
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyPgDown}))
+	if _, cmd := m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter})); cmd != nil || m.currentActiveMenu != exportOptionsMenu {
+		t.Fatalf("expected an invalid font family to keep the export settings open")
+	}
+	if !strings.Contains(currentMessage, "font family") {
+		t.Fatalf("expected font family error, got %q", currentMessage)
+	}
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEscape}))
+
+	m.setExportSetting("fontFamily", "'Iosevka', monospace")
+	m.setExportSetting("htmlTheme", htmlThemeLight)
+	m.setExportSetting("embedFont", "FALSE")
+	cmd := confirmExport(t, m, "H")
+	if cmd == nil {
+		t.Fatalf("expected html export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".html")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected html export file at %q, got error: %v", exportPath, err)
+	}
+	html := string(data)
+	if !strings.Contains(html, `<meta name="color-scheme" content="light">`) {
+		t.Fatalf("expected light theme, got %q", html)
+	}
+	if strings.Contains(html, "@font-face") || !strings.Contains(html, "font-family:'Iosevka', monospace;") {
+		t.Fatalf("expected font family from the export settings, got %q", html)
+	}
+}
+
+func TestMezzotoneModelExportANSIArtUsesFileNameAsTitle(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("3a9f6c21-0d4b-4e8a-b7c5-91e2f04d6a38")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.selectedFile = filepath.Join("images", "sunset.png")
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("█▓")}, nil),
+	}
+
+	m.setExportSetting("sauceAuthor", "an author name longer than twenty")
+	_, _ = m.Update(keyChar("A"))
+	for _, item := range m.exportSettings.Items {
+		if (item.Key == "fontSize" && !item.Hidden) || (item.Key == "sauceAuthor" && item.Hidden) {
+			t.Fatalf("expected the ANSI art panel to show SAUCE settings and hide font settings, %s hidden=%v", item.Key, item.Hidden)
+		}
+	}
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyPgDown}))
+	if _, cmd := m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter})); cmd != nil || !strings.Contains(currentMessage, "sauce author") {
+		t.Fatalf("expected a too long SAUCE author to keep the panel open, got %q", currentMessage)
+	}
+	m.closeExportSettings()
+
+	m.setExportSetting("sauceAuthor", "mezzo")
+	m.setExportSetting("sauceGroup", "crew")
+	m.setExportSetting("sauceFont", "IBM VGA50")
+	m.setExportSetting("iceColors", "FALSE")
+	confirmExport(t, m, "A")
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".ans")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected ans export file at %q, got error: %v", exportPath, err)
+	}
+	if !strings.Contains(string(data), "\xdb\xb2") {
+		t.Fatalf("expected CP437 encoded glyphs, got %q", data)
+	}
+	if len(data) < 128 || !strings.HasPrefix(string(data[len(data)-128:]), "SAUCE00sunset ") {
+		t.Fatalf("expected SAUCE record titled sunset, got %q", data)
+	}
+	sauce := data[len(data)-128:]
+	if author, group := strings.TrimRight(string(sauce[42:62]), " "), strings.TrimRight(string(sauce[62:82]), " "); author != "mezzo" || group != "crew" {
+		t.Fatalf("expected SAUCE author mezzo and group crew, got %q %q", author, group)
+	}
+	if font := strings.TrimRight(string(sauce[106:]), "\x00"); font != "IBM VGA50" || sauce[105]&1 != 0 {
+		t.Fatalf("expected font IBM VGA50 without iCE colors, got %q flags %d", font, sauce[105])
+	}
+}
+
+func TestMezzotoneModelExportAsciicastFromAnimation(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("c0a8012e-5b7d-4f3a-9e61-08d4b2f7a953")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderedGifOutput = renderedGifOutput{
+		renderedFrames: []*canvas.Canvas{
+			canvas.FromRunes([][]rune{[]rune("one")}, nil),
+			canvas.FromRunes([][]rune{[]rune("two")}, nil),
+		},
+		delayTimes: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+	}
+
+	cmd := confirmExport(t, m, "r")
+	if cmd == nil {
+		t.Fatalf("expected asciicast export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".cast")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected cast export file at %q, got error: %v", exportPath, err)
+	}
+	if !strings.HasPrefix(string(data), `{"version":2,"width":3,"height":1`) {
+		t.Fatalf("expected asciicast v2 header, got %q", data)
+	}
+	if !strings.Contains(string(data), `[0.100000,"o","\u001b[H\u001b[2Jtwo\u001b[0m"]`) {
+		t.Fatalf("expected second frame event at 0.1s, got %q", data)
+	}
+}
+
+func TestMezzotoneModelExportPdfFitsGrid(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("f4b1d7a2-6c3e-4a9b-8d05-7e2c1a9f3b64")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.style.leftColumnWidth = 120
+	m.messageViewPort.SetWidth(120)
+	m.messageViewPort.SetHeight(3)
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("pdf")}, nil),
+	}
+
+	cmd := confirmExport(t, m, "p")
+	if cmd == nil {
+		t.Fatalf("expected pdf export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".pdf")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected pdf export file at %q, got error: %v", exportPath, err)
+	}
+	if !strings.HasPrefix(string(data), "%PDF-") || !strings.Contains(string(data), "/FontFile2") {
+		t.Fatalf("expected pdf with embedded TrueType font")
+	}
+	if !strings.Contains(m.messageViewPort.View(), "Successfully exported") {
+		t.Fatalf("expected success message, got %q", m.messageViewPort.View())
+	}
+}
+
+func TestMezzotoneModelExportPdfUsesPaperSettings(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("3b8c2d41-7e5f-4a60-9b1c-2d3e4f5a6b7c")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.style.leftColumnWidth = 120
+	m.messageViewPort.SetWidth(120)
+	m.messageViewPort.SetHeight(3)
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("pdf")}, nil),
+	}
+
+	m.setExportSetting("pdfMargin", "-1")
+	_, _ = m.Update(keyChar("p"))
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyPgDown}))
+	if _, cmd := m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter})); cmd != nil || m.currentActiveMenu != exportOptionsMenu {
+		t.Fatalf("expected a negative margin to keep the export settings open")
+	}
+	if !strings.Contains(currentMessage, "pdf margin must not be negative") {
+		t.Fatalf("expected margin error, got %q", currentMessage)
+	}
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEscape}))
+
+	m.setExportSetting("pdfMargin", "18")
+	m.setExportSetting("paperSize", "A4")
+	m.setExportSetting("landscape", "TRUE")
+	cmd := confirmExport(t, m, "p")
+	if cmd == nil {
+		t.Fatalf("expected pdf export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".pdf")
+	data, err := os.ReadFile(exportPath)
+	if err != nil {
+		t.Fatalf("expected pdf export file at %q, got error: %v", exportPath, err)
+	}
+	if !strings.Contains(string(data), "/MediaBox [0 0 841.89 595.28]") {
+		t.Fatalf("expected a landscape A4 page")
+	}
+}
+
+func TestMezzotoneModelExportGridReopensInRenderView(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("9d2e4f60-1a3b-4c5d-8e7f-0a1b2c3d4e5f")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	for i := range m.renderSettings.Items {
+		if m.renderSettings.Items[i].Key == "runeMode" {
+			m.renderSettings.Items[i].Value = "RECTANGLES"
+		}
+	}
+	m.renderedGifOutput = renderedGifOutput{
+		renderedFrames: []*canvas.Canvas{
+			canvas.FromRunes([][]rune{[]rune("█▓")}, nil),
+			canvas.FromRunes([][]rune{[]rune("░ ")}, nil),
+		},
+		delayTimes: []time.Duration{100 * time.Millisecond, 50 * time.Millisecond},
+	}
+
+	_, cmd := m.Update(keyChar("M"))
+	if cmd == nil {
+		t.Fatalf("expected grid export command")
+	}
+	_, _ = m.Update(cmd())
+
+	exportPath := filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".mzg")
+	if _, err := os.Stat(exportPath); err != nil {
+		t.Fatalf("expected binary grid at %q, got error: %v", exportPath, err)
+	}
+
+	reopened := NewMezzotoneModel()
+	if _, err := reopened.openGridFile(exportPath); err != nil {
+		t.Fatalf("openGridFile failed: %v", err)
+	}
+	if reopened.currentActiveMenu != renderView {
+		t.Fatalf("expected grid to open in the render view")
+	}
+	frames := reopened.renderedGifOutput.renderedFrames
+	if len(frames) != 2 || frames[1].PlainText() != "░ \n" {
+		t.Fatalf("expected both frames to be restored, got %d", len(frames))
+	}
+	if reopened.renderedGifOutput.delayTimes[1] != 50*time.Millisecond {
+		t.Fatalf("expected frame durations to be restored, got %v", reopened.renderedGifOutput.delayTimes)
+	}
+	for _, item := range reopened.renderSettings.Items {
+		if item.Key == "runeMode" && item.Value != "RECTANGLES" {
+			t.Fatalf("expected rune mode to be restored, got %q", item.Value)
+		}
+	}
+}
+
+func TestMezzotoneModelExportSettingsApplyAndPersist(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	fixedUUID := uuid.MustParse("3c6a9e12-4b7d-4f80-a1c2-5d8e9f0a1b2c")
+	previousNewUUID := newUUID
+	newUUID = func() uuid.UUID { return fixedUUID }
+	t.Cleanup(func() { newUUID = previousNewUUID })
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.style.leftColumnWidth = 120
+	m.messageViewPort.SetWidth(120)
+	m.messageViewPort.SetHeight(3)
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("#")}, nil),
+	}
+	setExportSetting := func(key, value string) {
+		for i := range m.exportSettings.Items {
+			if m.exportSettings.Items[i].Key == key {
+				m.exportSettings.Items[i].Value = value
+			}
+		}
+	}
+
+	_, _ = m.Update(keyChar("i"))
+	setExportSetting("fg", "nothex")
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyPgDown}))
+	if _, cmd := m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter})); cmd != nil {
+		t.Fatalf("expected no export with an invalid color")
+	}
+	if m.currentActiveMenu != exportOptionsMenu || !strings.Contains(m.messageViewPort.View(), "invalid hex color") {
+		t.Fatalf("expected the panel to stay open with an error, got %q", m.messageViewPort.View())
+	}
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEscape}))
+	if m.currentActiveMenu != renderView {
+		t.Fatalf("expected esc to cancel the export")
+	}
+
+	setExportSetting("fg", "#000")
+	setExportSetting("bg", "#FFFFFF")
+	setExportSetting("padding", "3")
+	cmd := confirmExport(t, m, "i")
+	_, _ = m.Update(cmd())
+
+	f, err := os.Open(filepath.Join(tmpHome, "Mezzotone_"+fixedUUID.String()+".png"))
+	if err != nil {
+		t.Fatalf("expected png export file, got error: %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+	if r, g, b, _ := img.At(0, 0).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+		t.Fatalf("expected white padding, got %v", img.At(0, 0))
+	}
+
+	_, _ = m.Update(keyChar("i"))
+	for _, item := range m.exportSettings.Items {
+		if item.Key == "bg" && item.Value != "#FFFFFF" {
+			t.Fatalf("expected export settings to keep their last values, got bg %q", item.Value)
+		}
+	}
+}
+
+func TestMezzotoneModelSaveAsPicksOutputDirForTemplatedExports(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+	outDir := filepath.Join(tmpHome, "out")
+	if err := os.Mkdir(outDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.selectedFile = filepath.Join(tmpHome, "photo.png")
+	m.renderedImgOutput = renderedImgOutput{
+		renderedCanvas: canvas.FromRunes([][]rune{[]rune("abc")}, nil),
+	}
+	m.setExportSetting("fileName", "{name}_{mode}_{cols}x{rows}_{n}")
+
+	_, cmd := m.Update(keyChar("o"))
+	if m.currentActiveMenu != saveAsMenu || cmd == nil {
+		t.Fatalf("expected o to open the save-as picker")
+	}
+	_, _ = m.Update(cmd())
+	_, _ = m.Update(tea.KeyPressMsg(tea.Key{Code: tea.KeyEnter}))
+	if m.currentActiveMenu != renderView {
+		t.Fatalf("expected picking a folder to return to the render view")
+	}
+	if got := m.exportSetting("outputDir"); got != outDir {
+		t.Fatalf("expected output dir %q, got %q", outDir, got)
+	}
+
+	confirmExport(t, m, "t")
+	confirmExport(t, m, "t")
+	for _, name := range []string{"photo_ASCII_3x1_1.txt", "photo_ASCII_3x1_2.txt"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Fatalf("expected %s in the picked folder: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,521 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/export"
+)
+
+func buildY4M(w, h int, fps, chroma string, frames [][3]byte) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "YUV4MPEG2 W%d H%d F%s Ip A1:1 C%s\n", w, h, fps, chroma)
+
+	chromaW, chromaH := (w+1)/2, (h+1)/2
+	if chroma == "444" {
+		chromaW, chromaH = w, h
+	}
+	for _, yuv := range frames {
+		b.WriteString("FRAME\n")
+		b.Write(bytes.Repeat([]byte{yuv[0]}, w*h))
+		b.Write(bytes.Repeat([]byte{yuv[1]}, chromaW*chromaH))
+		b.Write(bytes.Repeat([]byte{yuv[2]}, chromaW*chromaH))
+	}
+	return b.Bytes()
+}
+
+func writeSequenceFrame(t *testing.T, path string, c color.Color) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create frame: %v", err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatalf("failed to encode frame: %v", err)
+	}
+}
+
+func TestSplitY4MDecodesFramesAndTiming(t *testing.T) {
+	data := buildY4M(4, 2, "30000:1001", "420jpeg", [][3]byte{
+		{235, 128, 128},
+		{16, 128, 128},
+		{81, 90, 240},
+	})
+
+	frames, delays, err := SplitY4M(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("SplitY4M failed: %v", err)
+	}
+	if len(frames) != 3 || len(delays) != 3 {
+		t.Fatalf("expected 3 frames and delays, got %d/%d", len(frames), len(delays))
+	}
+	if frames[0].Bounds() != image.Rect(0, 0, 4, 2) {
+		t.Fatalf("expected 4x2 frame, got %v", frames[0].Bounds())
+	}
+
+	if got := color.RGBAModel.Convert(frames[0].At(1, 1)).(color.RGBA); got != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Fatalf("expected limited range white, got %v", got)
+	}
+	if got := color.RGBAModel.Convert(frames[1].At(1, 1)).(color.RGBA); got != (color.RGBA{A: 255}) {
+		t.Fatalf("expected limited range black, got %v", got)
+	}
+	if got := color.RGBAModel.Convert(frames[2].At(0, 0)).(color.RGBA); got.R < 240 || got.G > 15 || got.B > 15 {
+		t.Fatalf("expected BT.601 red, got %v", got)
+	}
+
+	var total time.Duration
+	for _, d := range delays {
+		if d < 33*time.Millisecond || d > 34*time.Millisecond {
+			t.Fatalf("expected ~33.4ms per frame, got %v", d)
+		}
+		total += d
+	}
+	if want := time.Duration(3 * 1001 * int64(time.Second) / 30000); total != want {
+		t.Fatalf("expected total duration %v, got %v", want, total)
+	}
+}
+
+func TestSplitY4MRejectsInvalidStreams(t *testing.T) {
+	if _, _, err := SplitY4M(bytes.NewReader(buildY4M(2, 2, "25:1", "411", [][3]byte{{0, 0, 0}}))); err == nil {
+		t.Fatalf("expected error for unsupported colorspace")
+	}
+	if _, _, err := SplitY4M(bytes.NewReader([]byte("YUV4MPEG2 W2 H2 F25:1\n"))); err == nil {
+		t.Fatalf("expected error for stream without frames")
+	}
+
+	truncated := buildY4M(2, 2, "25:1", "444", [][3]byte{{0, 0, 0}})
+	if _, _, err := SplitY4M(bytes.NewReader(truncated[:len(truncated)-1])); err == nil {
+		t.Fatalf("expected error for truncated frame")
+	}
+
+	if _, _, err := SplitY4M(bytes.NewReader([]byte("YUV4MPEG2 W3000000000 H3000000000 F25:1\nFRAME\n"))); err == nil || !strings.Contains(err.Error(), "frame size") {
+		t.Fatalf("expected error for oversized frames, got %v", err)
+	}
+	if _, _, err := SplitY4M(bytes.NewReader(buildY4M(2, 2, "1:9000000000000", "444", [][3]byte{{0, 0, 0}}))); err == nil || !strings.Contains(err.Error(), "frame rate") {
+		t.Fatalf("expected error for an overflowing frame rate, got %v", err)
+	}
+}
+
+func TestResolveImageSequenceOrdersByFrameNumber(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"frame_10.png", "frame_2.png", "frame_1.png"} {
+		writeSequenceFrame(t, filepath.Join(dir, name), color.White)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a frame"), 0o644); err != nil {
+		t.Fatalf("failed to write note: %v", err)
+	}
+
+	want := []string{"frame_1.png", "frame_2.png", "frame_10.png"}
+	for _, source := range []string{dir, filepath.Join(dir, "frame_*.png")} {
+		paths, err := ResolveImageSequence(source)
+		if err != nil {
+			t.Fatalf("ResolveImageSequence(%q) failed: %v", source, err)
+		}
+		if len(paths) != len(want) {
+			t.Fatalf("expected %d frames for %q, got %v", len(want), source, paths)
+		}
+		for i := range want {
+			if filepath.Base(paths[i]) != want[i] {
+				t.Fatalf("expected %v for %q, got %v", want, source, paths)
+			}
+		}
+	}
+}
+
+func TestResolveImageSequencePrintfPattern(t *testing.T) {
+	dir := t.TempDir()
+	for i := 1; i <= 3; i++ {
+		writeSequenceFrame(t, filepath.Join(dir, fmt.Sprintf("frame_%04d.png", i)), color.White)
+	}
+	writeSequenceFrame(t, filepath.Join(dir, "frame_0005.png"), color.White)
+
+	pattern := filepath.Join(dir, "frame_%04d.png")
+	if !IsImageSequence(pattern) {
+		t.Fatalf("expected printf pattern to be detected as a sequence")
+	}
+	paths, err := ResolveImageSequence(pattern)
+	if err != nil {
+		t.Fatalf("ResolveImageSequence failed: %v", err)
+	}
+	if len(paths) != 3 || filepath.Base(paths[0]) != "frame_0001.png" {
+		t.Fatalf("expected frames 1..3 up to the first gap, got %v", paths)
+	}
+
+	if _, err := ResolveImageSequence(filepath.Join(dir, "missing_%04d.png")); err == nil {
+		t.Fatalf("expected error when no frame matches")
+	}
+}
+
+func TestLoadImageSequenceUsesFPS(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 3; i++ {
+		writeSequenceFrame(t, filepath.Join(dir, fmt.Sprintf("f%d.png", i)), color.White)
+	}
+
+	frames, delays, err := LoadImageSequence(dir, 3)
+	if err != nil {
+		t.Fatalf("LoadImageSequence failed: %v", err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(frames))
+	}
+	var total time.Duration
+	for _, d := range delays {
+		total += d
+	}
+	if total != time.Second {
+		t.Fatalf("expected 3 frames at 3 fps to last 1s, got %v", total)
+	}
+
+	if _, _, err := LoadImageSequence(dir, 0); err == nil {
+		t.Fatalf("expected error for fps 0")
+	}
+}
+
+func TestLoadImageSequenceChecksFrameMemoryBeforeDecoding(t *testing.T) {
+	dir := t.TempDir()
+	// Only the header of these frames is valid, so decoding any of them would fail.
+	for i := 0; i < 9; i++ {
+		var buf bytes.Buffer
+		buf.WriteString(pngSignature)
+		ihdr := make([]byte, 13)
+		binary.BigEndian.PutUint32(ihdr[0:4], 8192)
+		binary.BigEndian.PutUint32(ihdr[4:8], 8192)
+		ihdr[8], ihdr[9] = 8, 6
+		writePNGChunk(&buf, "IHDR", ihdr)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.png", i)), buf.Bytes(), 0o644); err != nil {
+			t.Fatalf("failed to write frame: %v", err)
+		}
+	}
+
+	if _, _, err := LoadImageSequence(dir, 10); err == nil || !strings.Contains(err.Error(), "MiB") {
+		t.Fatalf("expected frame memory error, got %v", err)
+	}
+}
+
+func TestConfirmWithY4MInputRendersAnimation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clip.y4m")
+	if err := os.WriteFile(path, buildY4M(32, 32, "25:1", "420jpeg", [][3]byte{{235, 128, 128}, {16, 128, 128}}), 0o644); err != nil {
+		t.Fatalf("failed to write y4m: %v", err)
+	}
+
+	m := NewMezzotoneModelWithConfig(MezzotoneModelConfig{InputPath: path})
+	if m.currentActiveMenu != renderOptionsMenu {
+		t.Fatalf("expected InputPath to open the render options, got menu %d", m.currentActiveMenu)
+	}
+	m.renderSettings.Confirm = true
+
+	_, cmd := m.Update(keyPress(tea.KeyEnter))
+	if cmd == nil {
+		t.Fatalf("expected animation command")
+	}
+	if len(m.renderedGifOutput.renderedFrames) != 2 {
+		t.Fatalf("expected 2 rendered frames, got %d", len(m.renderedGifOutput.renderedFrames))
+	}
+	for i, d := range m.renderedGifOutput.delayTimes {
+		if d != 40*time.Millisecond {
+			t.Fatalf("expected 40ms for frame %d at 25 fps, got %v", i, d)
+		}
+	}
+	if m.renderedImgOutput.renderedCanvas != nil {
+		t.Fatalf("expected still image output to be cleared")
+	}
+}
+
+func TestFilePickerSKeySelectsSequenceDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeSequenceFrame(t, filepath.Join(dir, "frame_1.png"), color.White)
+
+	m := NewMezzotoneModel()
+	m.filePicker.CurrentDirectory = dir
+
+	_, _ = m.Update(keyChar("s"))
+	if m.selectedFile != dir || m.currentActiveMenu != renderOptionsMenu {
+		t.Fatalf("expected sequence directory to be selected, got %q (menu %d)", m.selectedFile, m.currentActiveMenu)
+	}
+
+	empty := NewMezzotoneModel()
+	empty.filePicker.CurrentDirectory = t.TempDir()
+	_, _ = empty.Update(keyChar("s"))
+	if empty.currentActiveMenu != filePickerMenu {
+		t.Fatalf("expected empty directory to stay in the file picker")
+	}
+}
+
+func TestExportedAPNGRoundTripsThroughSplitAPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anim.png")
+	frames := []export.ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes([][]rune{[]rune("#.")}, [][]color.NRGBA{{{R: 255, A: 255}, {R: 255, A: 255}}}), Duration: 30 * time.Millisecond},
+		{Canvas: canvas.FromRunes([][]rune{[]rune("#@")}, [][]color.NRGBA{{{R: 255, A: 255}, {B: 255, A: 255}}}), Duration: 70 * time.Millisecond},
+	}
+	if err := export.ASCIIFramesToAPNG(frames, path, export.ASCIIExportOptions{FontSize: 14, DPI: 72, RenderColor: true}); err != nil {
+		t.Fatalf("ASCIIFramesToAPNG failed: %v", err)
+	}
+	if !IsAPNG(path) {
+		t.Fatalf("expected exported file to be detected as apng")
+	}
+
+	decoded, delays, _, _, err := loadAnimationFrames(path, defaultSequenceFPS)
+	if err != nil {
+		t.Fatalf("loadAnimationFrames failed: %v", err)
+	}
+	if len(decoded) != 2 || delays[0] != 30*time.Millisecond || delays[1] != 70*time.Millisecond {
+		t.Fatalf("expected 2 frames of 30ms and 70ms, got %d frames %v", len(decoded), delays)
+	}
+
+	hasColor := func(img image.Image, match func(r, g, b uint32) bool) bool {
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, bl, _ := img.At(x, y).RGBA()
+				if match(r>>8, g>>8, bl>>8) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	blue := func(r, g, b uint32) bool { return b > 200 && r < 50 }
+	red := func(r, g, b uint32) bool { return r > 200 && b < 50 }
+	if hasColor(decoded[0], blue) || !hasColor(decoded[0], red) {
+		t.Fatalf("expected first frame to be red only")
+	}
+	// The second frame is a cropped patch, the red glyph must survive compositing.
+	if !hasColor(decoded[1], blue) || !hasColor(decoded[1], red) {
+		t.Fatalf("expected second frame to composite the blue patch over the red glyph")
+	}
+}
+
+func TestIsAPNGRejectsStillPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "still.png")
+	writeSequenceFrame(t, path, color.White)
+
+	if IsAPNG(path) {
+		t.Fatalf("expected still png not to be detected as apng")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open png: %v", err)
+	}
+	defer f.Close()
+	if _, _, err := SplitAPNG(f); err == nil {
+		t.Fatalf("expected error for still png")
+	}
+}
+
+func TestSplitAPNGRejectsOversizedAnimations(t *testing.T) {
+	apng := func(w, h uint32, frameCount int) *bytes.Buffer {
+		var buf bytes.Buffer
+		buf.WriteString(pngSignature)
+		ihdr := make([]byte, 13)
+		binary.BigEndian.PutUint32(ihdr[0:4], w)
+		binary.BigEndian.PutUint32(ihdr[4:8], h)
+		ihdr[8], ihdr[9] = 8, 6
+		writePNGChunk(&buf, "IHDR", ihdr)
+		writePNGChunk(&buf, "acTL", make([]byte, 8))
+		for i := 0; i < frameCount; i++ {
+			fctl := make([]byte, 26)
+			binary.BigEndian.PutUint32(fctl[4:8], 1)
+			binary.BigEndian.PutUint32(fctl[8:12], 1)
+			writePNGChunk(&buf, "fcTL", fctl)
+		}
+		writePNGChunk(&buf, "IEND", nil)
+		return &buf
+	}
+
+	if _, _, err := SplitAPNG(apng(1<<16, 1<<16, 1)); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("expected oversized canvas error, got %v", err)
+	}
+	if _, _, err := SplitAPNG(apng(1, 1, maxAPNGFrames+1)); err == nil || !strings.Contains(err.Error(), "frames") {
+		t.Fatalf("expected frame cap error, got %v", err)
+	}
+	// Each frame is within the size cap, all of them together are over the memory limit.
+	if _, _, err := SplitAPNG(apng(8192, 8192, 9)); err == nil || !strings.Contains(err.Error(), "MiB") {
+		t.Fatalf("expected frame memory error, got %v", err)
+	}
+}
+
+type webpTestFrame struct {
+	x, y, w, h int
+	color      color.NRGBA
+	duration   int
+	flags      byte
+}
+
+// solidVP8L encodes a w x h lossless bitstream of a single color, every prefix code has one symbol.
+func solidVP8L(w, h int, c color.NRGBA) []byte {
+	var out []byte
+	var acc uint64
+	var n uint
+	put := func(v uint64, bits uint) {
+		acc |= v << n
+		n += bits
+		for n >= 8 {
+			out = append(out, byte(acc))
+			acc >>= 8
+			n -= 8
+		}
+	}
+
+	put(0x2f, 8)
+	put(uint64(w-1), 14)
+	put(uint64(h-1), 14)
+	put(1, 1) // alpha is used
+	put(0, 3) // version
+	put(0, 1) // no transform
+	put(0, 1) // no color cache
+	put(0, 1) // no meta prefix codes
+	for _, symbol := range []uint8{c.G, c.R, c.B, c.A} {
+		put(1, 1) // simple code
+		put(0, 1) // one symbol
+		put(1, 1) // 8 bit symbol
+		put(uint64(symbol), 8)
+	}
+	put(1, 1) // distance: simple code
+	put(0, 1)
+	put(0, 1) // 1 bit symbol
+	put(0, 1)
+	if n > 0 {
+		out = append(out, byte(acc))
+	}
+	return out
+}
+
+func buildAnimatedWebP(w, h int, frames []webpTestFrame) []byte {
+	var body bytes.Buffer
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = webpAnimationFlag | webpAlphaFlag
+	putUint24(vp8x[4:7], uint32(w-1))
+	putUint24(vp8x[7:10], uint32(h-1))
+	writeWebPChunk(&body, "VP8X", vp8x)
+	writeWebPChunk(&body, "ANIM", []byte{255, 255, 255, 255, 0, 0})
+
+	for _, frame := range frames {
+		var anmf bytes.Buffer
+		header := make([]byte, 16)
+		putUint24(header[0:3], uint32(frame.x/2))
+		putUint24(header[3:6], uint32(frame.y/2))
+		putUint24(header[6:9], uint32(frame.w-1))
+		putUint24(header[9:12], uint32(frame.h-1))
+		putUint24(header[12:15], uint32(frame.duration))
+		header[15] = frame.flags
+		anmf.Write(header)
+		writeWebPChunk(&anmf, "VP8L", solidVP8L(frame.w, frame.h, frame.color))
+		writeWebPChunk(&body, "ANMF", anmf.Bytes())
+	}
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	_ = binary.Write(&file, binary.LittleEndian, uint32(4+body.Len()))
+	file.WriteString("WEBP")
+	file.Write(body.Bytes())
+	return file.Bytes()
+}
+
+func TestSplitAnimatedWebPCompositesFrames(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	green := color.NRGBA{G: 255, A: 255}
+	data := buildAnimatedWebP(4, 4, []webpTestFrame{
+		{w: 4, h: 4, color: red, duration: 50, flags: webpDisposeBackground},
+		{x: 2, y: 2, w: 2, h: 2, color: blue, duration: 70},
+		{w: 2, h: 2, color: green, duration: 90, flags: webpNoBlend},
+	})
+
+	frames, delays, err := SplitAnimatedWebP(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("SplitAnimatedWebP failed: %v", err)
+	}
+	if len(frames) != 3 {
+		t.Fatalf("expected 3 frames, got %d", len(frames))
+	}
+	want := []time.Duration{50 * time.Millisecond, 70 * time.Millisecond, 90 * time.Millisecond}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Fatalf("expected delays %v, got %v", want, delays)
+		}
+	}
+
+	at := func(frame, x, y int) color.RGBA {
+		return color.RGBAModel.Convert(frames[frame].At(x, y)).(color.RGBA)
+	}
+	if got := at(0, 0, 0); got != (color.RGBA{R: 255, A: 255}) {
+		t.Fatalf("expected red first frame, got %v", got)
+	}
+	// The first frame disposes to background, so only the blue patch is left.
+	if got := at(1, 0, 0); got.A != 0 {
+		t.Fatalf("expected disposed area to be transparent, got %v", got)
+	}
+	if got := at(1, 3, 3); got != (color.RGBA{B: 255, A: 255}) {
+		t.Fatalf("expected blue patch at offset, got %v", got)
+	}
+	if got, kept := at(2, 0, 0), at(2, 3, 3); got != (color.RGBA{G: 255, A: 255}) || kept != (color.RGBA{B: 255, A: 255}) {
+		t.Fatalf("expected green patch over the kept blue patch, got %v and %v", got, kept)
+	}
+}
+
+func TestSplitAnimatedWebPRejectsOversizedCanvas(t *testing.T) {
+	frame := webpTestFrame{w: 1, h: 1, color: color.NRGBA{A: 255}, duration: 50}
+
+	data := buildAnimatedWebP(100000, 100000, []webpTestFrame{frame})
+	if _, _, err := SplitAnimatedWebP(bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("expected oversized canvas error, got %v", err)
+	}
+
+	frames := make([]webpTestFrame, 9)
+	for i := range frames {
+		frames[i] = frame
+	}
+	data = buildAnimatedWebP(8192, 8192, frames)
+	if _, _, err := SplitAnimatedWebP(bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), "MiB") {
+		t.Fatalf("expected frame memory error, got %v", err)
+	}
+}
+
+func TestConfirmWithAnimatedWebPInputRendersAnimation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sticker.webp")
+	data := buildAnimatedWebP(32, 32, []webpTestFrame{
+		{w: 32, h: 32, color: color.NRGBA{R: 255, G: 255, B: 255, A: 255}, duration: 100},
+		{w: 32, h: 32, color: color.NRGBA{A: 255}, duration: 100},
+	})
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("failed to write webp: %v", err)
+	}
+	if !IsAnimatedWebP(path) {
+		t.Fatalf("expected animated webp to be detected")
+	}
+
+	m := NewMezzotoneModelWithConfig(MezzotoneModelConfig{InputPath: path})
+	m.renderSettings.Confirm = true
+
+	_, cmd := m.Update(keyPress(tea.KeyEnter))
+	if cmd == nil {
+		t.Fatalf("expected animation command")
+	}
+	if len(m.renderedGifOutput.renderedFrames) != 2 {
+		t.Fatalf("expected 2 rendered frames, got %d", len(m.renderedGifOutput.renderedFrames))
+	}
+	if m.renderedImgOutput.renderedCanvas != nil {
+		t.Fatalf("expected still image output to be cleared")
+	}
+}
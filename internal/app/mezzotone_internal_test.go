@@ -1,6 +1,8 @@
 package app
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -45,7 +47,79 @@ func TestNormalizeRenderOptionsForService_MapsRenderColor(t *testing.T) {
 		t.Fatalf("normalizeRenderOptionsForService returned error: %v", err)
 	}
 
-	if !opts.RenderColor {
+	if !opts.RenderColor() {
 		t.Fatalf("expected RenderColor to be true")
 	}
 }
+
+func TestNormalizeRenderOptionsForService_MapsColorStyle(t *testing.T) {
+	settings := []ui.SettingItem{
+		{Key: "textSize", Value: "8"},
+		{Key: "fontAspect", Value: "2.0"},
+		{Key: "renderColor", Value: "TRUE"},
+		{Key: "runeMode", Value: "ASCII"},
+		{Key: "colorMode", Value: "DUOTONE"},
+		{Key: "colorStops", Value: "#101010,#F0F0F0"},
+		{Key: "saturation", Value: "1.0"},
+		{Key: "vibrance", Value: "0.0"},
+	}
+
+	opts, err := normalizeRenderOptionsForService(settings)
+	if err != nil {
+		t.Fatalf("normalizeRenderOptionsForService returned error: %v", err)
+	}
+	if got := opts.ColorMode(); got != "DUOTONE" {
+		t.Fatalf("expected DUOTONE color mode, got %q", got)
+	}
+
+	settings[5].Value = "#101010"
+	if _, err := normalizeRenderOptionsForService(settings); err == nil {
+		t.Fatalf("expected error for duotone with a single color stop")
+	}
+}
+
+func TestResolveExportPath_IncrementAndOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	fields := map[string]string{"{name}": "photo", "{mode}": "ASCII", "{cols}": "80", "{rows}": "24"}
+
+	path, err := resolveExportPath(dir, "{name}_{mode}_{cols}x{rows}_{n}", ".png", conflictIncrement, fields)
+	if err != nil {
+		t.Fatalf("resolveExportPath failed: %v", err)
+	}
+	if filepath.Base(path) != "photo_ASCII_80x24_1.png" {
+		t.Fatalf("expected expanded template, got %q", path)
+	}
+
+	next, _ := resolveExportPath(dir, "{name}_{mode}_{cols}x{rows}_{n}", ".png", conflictIncrement, fields)
+	if filepath.Base(next) != "photo_ASCII_80x24_2.png" {
+		t.Fatalf("expected {n} to count past the reserved file, got %q", next)
+	}
+
+	releaseExportPath(next)
+	if _, err := os.Stat(next); !os.IsNotExist(err) {
+		t.Fatalf("expected the empty reservation to be released, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("frame"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	releaseExportPath(path)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected a written export to be kept, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "photo.png"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	suffixed, _ := resolveExportPath(dir, "{name}", ".png", conflictIncrement, fields)
+	if filepath.Base(suffixed) != "photo_2.png" {
+		t.Fatalf("expected a counter suffix without {n}, got %q", suffixed)
+	}
+	overwritten, _ := resolveExportPath(dir, "{name}", ".png", conflictOverwrite, fields)
+	if filepath.Base(overwritten) != "photo.png" {
+		t.Fatalf("expected overwrite to reuse the name, got %q", overwritten)
+	}
+
+	if _, err := resolveExportPath(dir, "sub/{name}", ".png", conflictIncrement, fields); err == nil {
+		t.Fatalf("expected an error for a template with a path separator")
+	}
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"os"
 	"testing"
 
 	tea "charm.land/bubbletea/v2"
@@ -49,6 +50,28 @@ func TestRenderViewShiftUpAndDownGoToBottomAndTop(t *testing.T) {
 
 }
 
+func TestRenderViewJScrollsWithoutExporting(t *testing.T) {
+	tmpHome := t.TempDir()
+	t.Setenv("HOME", tmpHome)
+
+	m := NewMezzotoneModel()
+	m.currentActiveMenu = renderView
+	m.renderView.SetWidth(6)
+	m.renderView.SetHeight(2)
+	m.renderView.SetContent("line0\nline1\nline2\nline3\nline4")
+
+	_, cmd := m.Update(textPress("j"))
+	if cmd != nil {
+		t.Fatalf("expected j to only scroll the render view")
+	}
+	if got := m.renderView.YOffset(); got != 1 {
+		t.Fatalf("expected j to scroll down one line, got offset %d", got)
+	}
+	if entries, _ := os.ReadDir(tmpHome); len(entries) != 0 {
+		t.Fatalf("expected j to write no export, got %d files", len(entries))
+	}
+}
+
 func TestRenderViewShiftLeftAndRightGoToEdges(t *testing.T) {
 	m := NewMezzotoneModel()
 	m.currentActiveMenu = renderView
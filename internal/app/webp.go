@@ -0,0 +1,204 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/image/webp"
+)
+
+const (
+	webpAnimationFlag = 1 << 1
+	webpAlphaFlag     = 1 << 4
+
+	webpDisposeBackground = 1 << 0
+	webpNoBlend           = 1 << 1
+
+	// maxWebPFrames caps the ANMF chunks decoded from an animation.
+	maxWebPFrames = 2000
+)
+
+type webpChunk struct {
+	fourCC string
+	data   []byte
+}
+
+// IsAnimatedWebP reports whether path is a WebP whose VP8X header has the animation flag set.
+func IsAnimatedWebP(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, 21)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return string(header[0:4]) == "RIFF" && string(header[8:12]) == "WEBP" &&
+		string(header[12:16]) == "VP8X" && header[20]&webpAnimationFlag != 0
+}
+
+// SplitAnimatedWebP decodes an animated WebP and returns composited frames plus per-frame durations.
+// Each ANMF frame is decoded with x/image/webp and drawn onto a full-size RGBA canvas like SplitAnimatedGIF,
+// alpha-blended unless the frame asks for no blending, and cleared afterwards when it disposes to background.
+// The ANIM background color is only a hint, the canvas starts and is disposed to transparent.
+func SplitAnimatedWebP(r io.Reader) (frames []image.Image, delays []time.Duration, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic while decoding webp: %v", rec)
+		}
+	}()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, nil, fmt.Errorf("not a webp file")
+	}
+	chunks, err := readWebPChunks(data[12:])
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(chunks) == 0 || chunks[0].fourCC != "VP8X" || len(chunks[0].data) < 10 || chunks[0].data[0]&webpAnimationFlag == 0 {
+		return nil, nil, fmt.Errorf("webp is not animated")
+	}
+
+	w := int(uint24(chunks[0].data[4:7])) + 1
+	h := int(uint24(chunks[0].data[7:10])) + 1
+	frameCount := 0
+	for _, chunk := range chunks[1:] {
+		if chunk.fourCC == "ANMF" {
+			frameCount++
+		}
+	}
+	if frameCount > maxWebPFrames {
+		return nil, nil, fmt.Errorf("webp: animation has more than %d frames", maxWebPFrames)
+	}
+	// Frames are kept as full canvas copies, VP8X allows canvases of 2^24 pixels per side.
+	var budget frameBudget
+	if err := budget.reserve(frameCount, w, h); err != nil {
+		return nil, nil, fmt.Errorf("webp: %w", err)
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for _, chunk := range chunks[1:] {
+		if chunk.fourCC != "ANMF" {
+			continue
+		}
+		if len(chunk.data) < 16 {
+			return nil, nil, fmt.Errorf("webp: invalid ANMF chunk")
+		}
+
+		x := int(uint24(chunk.data[0:3])) * 2
+		y := int(uint24(chunk.data[3:6])) * 2
+		frameW := int(uint24(chunk.data[6:9])) + 1
+		frameH := int(uint24(chunk.data[9:12])) + 1
+		duration := time.Duration(uint24(chunk.data[12:15])) * time.Millisecond
+		flags := chunk.data[15]
+
+		bounds := image.Rect(x, y, x+frameW, y+frameH)
+		if !bounds.In(canvas.Bounds()) {
+			return nil, nil, fmt.Errorf("webp: frame %d is outside the canvas", len(frames))
+		}
+
+		src, err := decodeWebPFrame(chunk.data[16:], frameW, frameH)
+		if err != nil {
+			return nil, nil, fmt.Errorf("webp: frame %d: %w", len(frames), err)
+		}
+
+		op := draw.Over
+		if flags&webpNoBlend != 0 {
+			op = draw.Src
+		}
+		draw.Draw(canvas, bounds, src, src.Bounds().Min, op)
+		frames = append(frames, cloneRGBA(canvas))
+		delays = append(delays, duration)
+
+		if flags&webpDisposeBackground != 0 {
+			draw.Draw(canvas, bounds, image.Transparent, image.Point{}, draw.Src)
+		}
+	}
+	if len(frames) == 0 {
+		return nil, nil, fmt.Errorf("webp has no frames")
+	}
+
+	return frames, delays, nil
+}
+
+func readWebPChunks(data []byte) ([]webpChunk, error) {
+	var chunks []webpChunk
+	for len(data) >= 8 {
+		size := int(binary.LittleEndian.Uint32(data[4:8]))
+		if size < 0 || size > len(data)-8 {
+			return nil, fmt.Errorf("webp: truncated %s chunk", data[0:4])
+		}
+		chunks = append(chunks, webpChunk{fourCC: string(data[0:4]), data: data[8 : 8+size]})
+		// Chunks are padded to an even size, the padding of the last chunk may be missing.
+		data = data[min(len(data), 8+size+size%2):]
+	}
+	return chunks, nil
+}
+
+// decodeWebPFrame wraps the ALPH/VP8/VP8L sub-chunks of one ANMF frame into a standalone still WebP,
+// so x/image/webp does the VP8 or VP8L decoding.
+func decodeWebPFrame(frameData []byte, w, h int) (image.Image, error) {
+	subChunks, err := readWebPChunks(frameData)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	var body bytes.Buffer
+	for _, chunk := range subChunks {
+		switch chunk.fourCC {
+		case "ALPH":
+			flags |= webpAlphaFlag
+		case "VP8 ", "VP8L":
+		default:
+			continue
+		}
+		writeWebPChunk(&body, chunk.fourCC, chunk.data)
+	}
+	if body.Len() == 0 {
+		return nil, fmt.Errorf("missing image data")
+	}
+
+	vp8x := make([]byte, 10)
+	vp8x[0] = flags
+	putUint24(vp8x[4:7], uint32(w-1))
+	putUint24(vp8x[7:10], uint32(h-1))
+
+	var file bytes.Buffer
+	file.WriteString("RIFF")
+	_ = binary.Write(&file, binary.LittleEndian, uint32(4+8+len(vp8x)+body.Len()))
+	file.WriteString("WEBP")
+	writeWebPChunk(&file, "VP8X", vp8x)
+	file.Write(body.Bytes())
+
+	return webp.Decode(&file)
+}
+
+func writeWebPChunk(w *bytes.Buffer, fourCC string, data []byte) {
+	w.WriteString(fourCC)
+	_ = binary.Write(w, binary.LittleEndian, uint32(len(data)))
+	w.Write(data)
+	if len(data)%2 == 1 {
+		w.WriteByte(0)
+	}
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
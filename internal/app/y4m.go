@@ -0,0 +1,214 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const y4mSignature = "YUV4MPEG2 "
+
+const (
+	// maxY4MFrames caps how many FRAME markers are read from a stream.
+	maxY4MFrames = 2000
+	// maxY4MRateTerm bounds both terms of the F frame rate, larger ones overflow the frame durations.
+	maxY4MRateTerm = 1_000_000
+)
+
+// IsY4M reports whether path is a YUV4MPEG2 stream.
+func IsY4M(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, len(y4mSignature))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return string(header) == y4mSignature
+}
+
+type y4mHeader struct {
+	width, height    int
+	fpsNum, fpsDen   int64
+	chroma           string
+	fullRange        bool
+	chromaW, chromaH int
+}
+
+// SplitY4M decodes an uncompressed YUV4MPEG2 stream into RGBA frames plus per-frame durations.
+// Durations are taken from the stream frame rate and rounded so they add up without drift.
+// Supported chroma layouts are 420 (all siting variants), 422, 444 and mono, 8 bits per sample.
+func SplitY4M(r io.Reader) (frames []image.Image, delays []time.Duration, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic while decoding y4m: %v", rec)
+		}
+	}()
+
+	br := bufio.NewReader(r)
+
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, nil, fmt.Errorf("y4m: reading header: %w", err)
+	}
+	header, err := parseY4MHeader(strings.TrimSuffix(line, "\n"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lumaSize := header.width * header.height
+	chromaSize := header.chromaW * header.chromaH
+	frameSize := lumaSize + 2*chromaSize
+	buf := make([]byte, frameSize)
+
+	// Streams carry no frame count, so the budget grows with every frame read.
+	var budget frameBudget
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("y4m: reading frame %d header: %w", len(frames), err)
+		}
+		if !strings.HasPrefix(line, "FRAME") {
+			return nil, nil, fmt.Errorf("y4m: expected FRAME marker, got %q", strings.TrimSpace(line))
+		}
+		if len(frames) >= maxY4MFrames {
+			return nil, nil, fmt.Errorf("y4m: stream has more than %d frames", maxY4MFrames)
+		}
+		if err := budget.reserve(1, header.width, header.height); err != nil {
+			return nil, nil, fmt.Errorf("y4m: %w", err)
+		}
+
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return nil, nil, fmt.Errorf("y4m: reading frame %d: %w", len(frames), err)
+		}
+		frames = append(frames, y4mFrameToRGBA(header, buf[:lumaSize], buf[lumaSize:lumaSize+chromaSize], buf[lumaSize+chromaSize:]))
+	}
+	if len(frames) == 0 {
+		return nil, nil, fmt.Errorf("y4m has no frames")
+	}
+
+	delays = make([]time.Duration, len(frames))
+	for i := range frames {
+		start := time.Duration(int64(i) * header.fpsDen * int64(time.Second) / header.fpsNum)
+		end := time.Duration(int64(i+1) * header.fpsDen * int64(time.Second) / header.fpsNum)
+		delays[i] = end - start
+	}
+
+	return frames, delays, nil
+}
+
+func parseY4MHeader(line string) (y4mHeader, error) {
+	if !strings.HasPrefix(line, y4mSignature) {
+		return y4mHeader{}, fmt.Errorf("y4m: missing YUV4MPEG2 signature")
+	}
+
+	header := y4mHeader{fpsNum: 25, fpsDen: 1, chroma: "420jpeg"}
+	for _, param := range strings.Fields(line[len(y4mSignature):]) {
+		value := param[1:]
+		switch param[0] {
+		case 'W':
+			header.width, _ = strconv.Atoi(value)
+		case 'H':
+			header.height, _ = strconv.Atoi(value)
+		case 'F':
+			num, den, ok := strings.Cut(value, ":")
+			if !ok {
+				return y4mHeader{}, fmt.Errorf("y4m: invalid frame rate %q", value)
+			}
+			header.fpsNum, _ = strconv.ParseInt(num, 10, 64)
+			header.fpsDen, _ = strconv.ParseInt(den, 10, 64)
+		case 'C':
+			header.chroma = value
+		case 'X':
+			if strings.EqualFold(value, "COLORRANGE=FULL") {
+				header.fullRange = true
+			}
+		}
+	}
+
+	if header.width <= 0 || header.height <= 0 || header.width > maxFramePixels/header.height {
+		return y4mHeader{}, fmt.Errorf("y4m: invalid frame size %dx%d", header.width, header.height)
+	}
+	if header.fpsNum <= 0 || header.fpsDen <= 0 || header.fpsNum > maxY4MRateTerm || header.fpsDen > maxY4MRateTerm {
+		return y4mHeader{}, fmt.Errorf("y4m: invalid frame rate %d:%d", header.fpsNum, header.fpsDen)
+	}
+
+	switch header.chroma {
+	case "420jpeg", "420paldv", "420mpeg2", "420":
+		header.chromaW, header.chromaH = (header.width+1)/2, (header.height+1)/2
+	case "422":
+		header.chromaW, header.chromaH = (header.width+1)/2, header.height
+	case "444":
+		header.chromaW, header.chromaH = header.width, header.height
+	case "mono":
+		header.chromaW, header.chromaH = 0, 0
+	default:
+		return y4mHeader{}, fmt.Errorf("y4m: unsupported colorspace %q", header.chroma)
+	}
+
+	return header, nil
+}
+
+// y4mFrameToRGBA converts one planar frame with BT.601 coefficients.
+// Y4M streams are limited range (16..235) unless tagged XCOLORRANGE=FULL.
+func y4mFrameToRGBA(header y4mHeader, yPlane, cbPlane, crPlane []byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, header.width, header.height))
+
+	xShift := 0
+	if header.chromaW > 0 && header.chromaW < header.width {
+		xShift = 1
+	}
+	yShift := 0
+	if header.chromaH > 0 && header.chromaH < header.height {
+		yShift = 1
+	}
+
+	for y := 0; y < header.height; y++ {
+		for x := 0; x < header.width; x++ {
+			luma := float64(yPlane[y*header.width+x])
+			cb, cr := 128.0, 128.0
+			if header.chromaW > 0 {
+				ci := (y>>yShift)*header.chromaW + (x >> xShift)
+				cb, cr = float64(cbPlane[ci]), float64(crPlane[ci])
+			}
+
+			var r, g, b float64
+			if header.fullRange {
+				r = luma + 1.402*(cr-128)
+				g = luma - 0.344136*(cb-128) - 0.714136*(cr-128)
+				b = luma + 1.772*(cb-128)
+			} else {
+				l := 1.164383 * (luma - 16)
+				r = l + 1.596027*(cr-128)
+				g = l - 0.391762*(cb-128) - 0.812968*(cr-128)
+				b = l + 2.017232*(cb-128)
+			}
+
+			img.SetRGBA(x, y, color.RGBA{R: clampToByte(r), G: clampToByte(g), B: clampToByte(b), A: 255})
+		}
+	}
+
+	return img
+}
+
+func clampToByte(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,324 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Attr is a bit set of text attributes applied to a cell.
+type Attr uint8
+
+const (
+	AttrBold Attr = 1 << iota
+	AttrUnderline
+)
+
+// Cell is one character position of a Canvas.
+type Cell struct {
+	Rune rune
+	// FG: glyph color, a zero alpha uses the default foreground.
+	FG color.NRGBA
+	// BG: cell background, a zero alpha leaves the background untouched.
+	BG    color.NRGBA
+	Attrs Attr
+}
+
+var blankCell = Cell{Rune: ' '}
+
+// Canvas is a fixed size grid of cells. Every row has exactly Width cells.
+type Canvas struct {
+	width  int
+	height int
+	cells  []Cell
+
+	// luminance is the optional sampled luminance per cell, nil until SetLuminance is called.
+	luminance []float32
+}
+
+// New returns a width x height canvas filled with spaces.
+func New(width, height int) *Canvas {
+	width = max(0, width)
+	height = max(0, height)
+
+	cells := make([]Cell, width*height)
+	for i := range cells {
+		cells[i] = blankCell
+	}
+	return &Canvas{width: width, height: height, cells: cells}
+}
+
+// FromRunes builds a canvas from rune rows and optional foreground colors indexed [row][col].
+// Rows shorter than the longest one are padded with spaces, missing colors keep the default foreground.
+func FromRunes(runes [][]rune, colors [][]color.NRGBA) *Canvas {
+	width := 0
+	for _, row := range runes {
+		width = max(width, len(row))
+	}
+
+	c := New(width, len(runes))
+	for y, row := range runes {
+		for x, r := range row {
+			cell := Cell{Rune: r}
+			if y < len(colors) && x < len(colors[y]) {
+				cell.FG = colors[y][x]
+			}
+			c.cells[y*width+x] = cell
+		}
+	}
+	return c
+}
+
+func (c *Canvas) Width() int  { return c.width }
+func (c *Canvas) Height() int { return c.height }
+
+func (c *Canvas) inBounds(x, y int) bool {
+	return x >= 0 && y >= 0 && x < c.width && y < c.height
+}
+
+// At returns the cell at column x, row y. Out of range positions read as a blank cell.
+func (c *Canvas) At(x, y int) Cell {
+	if !c.inBounds(x, y) {
+		return blankCell
+	}
+	return c.cells[y*c.width+x]
+}
+
+// Set replaces the cell at column x, row y and reports whether the position was in range.
+func (c *Canvas) Set(x, y int, cell Cell) bool {
+	if !c.inBounds(x, y) {
+		return false
+	}
+	c.cells[y*c.width+x] = cell
+	return true
+}
+
+// Row returns a copy of row y.
+func (c *Canvas) Row(y int) []Cell {
+	if y < 0 || y >= c.height {
+		return nil
+	}
+	row := make([]Cell, c.width)
+	copy(row, c.cells[y*c.width:(y+1)*c.width])
+	return row
+}
+
+// SetLuminance records the sampled luminance in [0..1] of the cell at x, y.
+// It is kept beside the cells for structured exports, drawing ignores it.
+func (c *Canvas) SetLuminance(x, y int, luminance float64) bool {
+	if !c.inBounds(x, y) {
+		return false
+	}
+	if c.luminance == nil {
+		c.luminance = make([]float32, len(c.cells))
+	}
+	c.luminance[y*c.width+x] = float32(luminance)
+	return true
+}
+
+// Luminance returns the recorded luminance of the cell at x, y. ok is false when none was recorded.
+func (c *Canvas) Luminance(x, y int) (luminance float64, ok bool) {
+	if c.luminance == nil || !c.inBounds(x, y) {
+		return 0, false
+	}
+	return float64(c.luminance[y*c.width+x]), true
+}
+
+// HasLuminance reports whether SetLuminance was used on the canvas.
+func (c *Canvas) HasLuminance() bool { return c.luminance != nil }
+
+// Clone returns a deep copy of the canvas.
+func (c *Canvas) Clone() *Canvas {
+	cells := make([]Cell, len(c.cells))
+	copy(cells, c.cells)
+	clone := &Canvas{width: c.width, height: c.height, cells: cells}
+	if c.luminance != nil {
+		clone.luminance = append([]float32(nil), c.luminance...)
+	}
+	return clone
+}
+
+// Equal reports whether both canvases have the same size and cells. Recorded luminance is not compared.
+func (c *Canvas) Equal(other *Canvas) bool {
+	if c.width != other.width || c.height != other.height {
+		return false
+	}
+	for i := range c.cells {
+		if c.cells[i] != other.cells[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Runes returns the glyphs as [row][col] slices.
+func (c *Canvas) Runes() [][]rune {
+	runes := make([][]rune, c.height)
+	for y := range runes {
+		runes[y] = make([]rune, c.width)
+		for x := range runes[y] {
+			runes[y][x] = c.cells[y*c.width+x].Rune
+		}
+	}
+	return runes
+}
+
+// Colors returns the foreground colors as [row][col] slices.
+func (c *Canvas) Colors() [][]color.NRGBA {
+	colors := make([][]color.NRGBA, c.height)
+	for y := range colors {
+		colors[y] = make([]color.NRGBA, c.width)
+		for x := range colors[y] {
+			colors[y][x] = c.cells[y*c.width+x].FG
+		}
+	}
+	return colors
+}
+
+// PlainText serializes the glyphs, one line per row, without any escape sequence.
+func (c *Canvas) PlainText() string {
+	var sb strings.Builder
+	sb.Grow((c.width + 1) * c.height)
+	for y := 0; y < c.height; y++ {
+		for x := 0; x < c.width; x++ {
+			sb.WriteRune(c.cells[y*c.width+x].Rune)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ANSI serializes the canvas with terminal styling for every cell that has colors or attributes.
+func (c *Canvas) ANSI() string {
+	var sb strings.Builder
+	for y := 0; y < c.height; y++ {
+		for x := 0; x < c.width; x++ {
+			cell := c.cells[y*c.width+x]
+			if cell.FG.A == 0 && cell.BG.A == 0 && cell.Attrs == 0 {
+				sb.WriteRune(cell.Rune)
+				continue
+			}
+
+			s := lipgloss.NewStyle()
+			if cell.FG.A > 0 {
+				s = s.Foreground(lipgloss.Color(ToHex(cell.FG)))
+			}
+			if cell.BG.A > 0 {
+				s = s.Background(lipgloss.Color(ToHex(cell.BG)))
+			}
+			if cell.Attrs&AttrBold != 0 {
+				s = s.Bold(true)
+			}
+			if cell.Attrs&AttrUnderline != 0 {
+				s = s.Underline(true)
+			}
+			sb.WriteString(s.Render(string(cell.Rune)))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ToHex formats c as #RRGGBB.
+func ToHex(c color.NRGBA) string {
+	const hex = "0123456789ABCDEF"
+	return string([]byte{
+		'#',
+		hex[c.R>>4], hex[c.R&0x0F],
+		hex[c.G>>4], hex[c.G&0x0F],
+		hex[c.B>>4], hex[c.B&0x0F],
+	})
+}
+
+// GlyphDrawer draws a single glyph with its baseline origin at dot.
+type GlyphDrawer interface {
+	DrawGlyph(dst draw.Image, dot image.Point, r rune, c color.Color)
+}
+
+// FaceDrawer draws glyphs straight from a font.Face.
+type FaceDrawer struct {
+	Face font.Face
+}
+
+func (f FaceDrawer) DrawGlyph(dst draw.Image, dot image.Point, r rune, c color.Color) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(c),
+		Face: f.Face,
+		Dot:  fixed.P(dot.X, dot.Y),
+	}
+	d.DrawString(string(r))
+}
+
+// ImageOptions describes the cell geometry and default colors used by Canvas.Image.
+type ImageOptions struct {
+	CellWidth  int
+	LineHeight int
+	Ascent     int
+	// Width, Height: output size in pixels, values smaller than the canvas are grown to fit it.
+	Width  int
+	Height int
+	FG     color.Color
+	BG     color.Color
+	// UseCellColors draws cells with their own FG/BG instead of the defaults.
+	UseCellColors bool
+}
+
+// Image rasterizes the canvas with g into a new RGBA image.
+func (c *Canvas) Image(g GlyphDrawer, opt ImageOptions) *image.RGBA {
+	if opt.CellWidth < 1 {
+		opt.CellWidth = 1
+	}
+	if opt.LineHeight < 1 {
+		opt.LineHeight = 1
+	}
+	if opt.FG == nil {
+		opt.FG = color.White
+	}
+	if opt.BG == nil {
+		opt.BG = color.Black
+	}
+
+	w := max(opt.Width, max(1, c.width)*opt.CellWidth)
+	h := max(opt.Height, max(1, c.height)*opt.LineHeight)
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: opt.BG}, image.Point{}, draw.Src)
+
+	for y := 0; y < c.height; y++ {
+		baselineY := y*opt.LineHeight + opt.Ascent
+		for x := 0; x < c.width; x++ {
+			cell := c.cells[y*c.width+x]
+			cellRect := image.Rect(x*opt.CellWidth, y*opt.LineHeight, (x+1)*opt.CellWidth, (y+1)*opt.LineHeight)
+
+			var fg color.Color = opt.FG
+			if opt.UseCellColors {
+				if cell.BG.A > 0 {
+					draw.Draw(img, cellRect, &image.Uniform{C: cell.BG}, image.Point{}, draw.Src)
+				}
+				if cell.FG.A > 0 {
+					fg = cell.FG
+				}
+			}
+
+			if cell.Rune != ' ' {
+				dot := image.Pt(x*opt.CellWidth, baselineY)
+				g.DrawGlyph(img, dot, cell.Rune, fg)
+				if cell.Attrs&AttrBold != 0 {
+					g.DrawGlyph(img, dot.Add(image.Pt(1, 0)), cell.Rune, fg)
+				}
+			}
+			if cell.Attrs&AttrUnderline != 0 {
+				underlineY := min(baselineY+1, cellRect.Max.Y-1)
+				draw.Draw(img, image.Rect(cellRect.Min.X, underlineY, cellRect.Max.X, underlineY+1), &image.Uniform{C: fg}, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	return img
+}
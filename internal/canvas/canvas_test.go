@@ -0,0 +1,167 @@
+package canvas_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/colorprofile"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+func TestNewFillsWithSpaces(t *testing.T) {
+	c := canvas.New(3, 2)
+	if c.Width() != 3 || c.Height() != 2 {
+		t.Fatalf("expected 3x2 canvas, got %dx%d", c.Width(), c.Height())
+	}
+	if got := c.PlainText(); got != "   \n   \n" {
+		t.Fatalf("expected blank rows, got %q", got)
+	}
+
+	empty := canvas.New(-1, -4)
+	if empty.Width() != 0 || empty.Height() != 0 {
+		t.Fatalf("expected negative sizes to clamp to 0, got %dx%d", empty.Width(), empty.Height())
+	}
+}
+
+func TestFromRunesPadsShortRows(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	c := canvas.FromRunes([][]rune{[]rune("abc"), []rune("d")}, [][]color.NRGBA{{red}})
+
+	if c.Width() != 3 || c.Height() != 2 {
+		t.Fatalf("expected 3x2 canvas, got %dx%d", c.Width(), c.Height())
+	}
+	if got := c.PlainText(); got != "abc\nd  \n" {
+		t.Fatalf("expected padded rows, got %q", got)
+	}
+	if got := c.At(0, 0).FG; got != red {
+		t.Fatalf("expected first cell color %v, got %v", red, got)
+	}
+	if got := c.At(1, 0).FG; got.A != 0 {
+		t.Fatalf("expected missing color to keep the default foreground, got %v", got)
+	}
+	for _, row := range c.Runes() {
+		if len(row) != c.Width() {
+			t.Fatalf("expected every row to have %d runes, got %d", c.Width(), len(row))
+		}
+	}
+}
+
+func TestSetAndAtStayInBounds(t *testing.T) {
+	c := canvas.New(2, 2)
+	if c.Set(2, 0, canvas.Cell{Rune: 'x'}) || c.Set(0, -1, canvas.Cell{Rune: 'x'}) {
+		t.Fatalf("expected out of range Set to report false")
+	}
+	if !c.Set(1, 1, canvas.Cell{Rune: 'x'}) {
+		t.Fatalf("expected in range Set to report true")
+	}
+	if got := c.At(1, 1).Rune; got != 'x' {
+		t.Fatalf("expected 'x', got %q", got)
+	}
+	if got := c.At(5, 5).Rune; got != ' ' {
+		t.Fatalf("expected out of range At to read a blank cell, got %q", got)
+	}
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	c := canvas.FromRunes([][]rune{[]rune("ab")}, nil)
+	clone := c.Clone()
+	if !c.Equal(clone) {
+		t.Fatalf("expected clone to equal source")
+	}
+
+	clone.Set(0, 0, canvas.Cell{Rune: 'z'})
+	if c.Equal(clone) {
+		t.Fatalf("expected modified clone to differ from source")
+	}
+	if got := c.At(0, 0).Rune; got != 'a' {
+		t.Fatalf("expected source to be untouched, got %q", got)
+	}
+}
+
+func TestLuminanceIsKeptBesideCells(t *testing.T) {
+	c := canvas.New(2, 1)
+	if _, ok := c.Luminance(0, 0); ok || c.HasLuminance() {
+		t.Fatalf("expected no luminance before SetLuminance")
+	}
+	if c.SetLuminance(5, 0, 1) {
+		t.Fatalf("expected out of bounds SetLuminance to fail")
+	}
+	c.SetLuminance(1, 0, 0.25)
+
+	clone := c.Clone()
+	if l, ok := clone.Luminance(1, 0); !ok || l != 0.25 {
+		t.Fatalf("expected cloned luminance 0.25, got %v %v", l, ok)
+	}
+	if !c.Equal(canvas.New(2, 1)) {
+		t.Fatalf("expected Equal to ignore luminance")
+	}
+}
+
+func TestANSIStylesColoredCells(t *testing.T) {
+	lipgloss.Writer.Profile = colorprofile.TrueColor
+
+	c := canvas.New(2, 1)
+	c.Set(0, 0, canvas.Cell{Rune: 'X', FG: color.NRGBA{R: 255, A: 255}, BG: color.NRGBA{B: 255, A: 255}, Attrs: canvas.AttrBold})
+	c.Set(1, 0, canvas.Cell{Rune: 'y'})
+
+	out := c.ANSI()
+	if !strings.Contains(out, "38;2;255;0;0") {
+		t.Fatalf("expected ANSI truecolor foreground in output, got %q", out)
+	}
+	if !strings.Contains(out, "48;2;0;0;255") {
+		t.Fatalf("expected ANSI truecolor background in output, got %q", out)
+	}
+	if !strings.HasSuffix(out, "y\n") {
+		t.Fatalf("expected unstyled cell to be written as is, got %q", out)
+	}
+	if plain := c.PlainText(); plain != "Xy\n" {
+		t.Fatalf("expected plain output %q, got %q", "Xy\n", plain)
+	}
+}
+
+func TestToHex(t *testing.T) {
+	if got := canvas.ToHex(color.NRGBA{R: 0x12, G: 0xAB, B: 0x0F, A: 255}); got != "#12AB0F" {
+		t.Fatalf("expected #12AB0F, got %s", got)
+	}
+}
+
+type boxDrawer struct{}
+
+func (boxDrawer) DrawGlyph(dst draw.Image, dot image.Point, r rune, c color.Color) {
+	draw.Draw(dst, image.Rect(dot.X, dot.Y-2, dot.X+2, dot.Y), &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
+
+func TestImageUsesCellGeometryAndColors(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+
+	c := canvas.New(2, 1)
+	c.Set(0, 0, canvas.Cell{Rune: 'a', FG: red})
+	c.Set(1, 0, canvas.Cell{Rune: ' ', BG: blue})
+
+	opt := canvas.ImageOptions{CellWidth: 4, LineHeight: 4, Ascent: 3, UseCellColors: true}
+	img := c.Image(boxDrawer{}, opt)
+	if img.Bounds().Dx() != 8 || img.Bounds().Dy() != 4 {
+		t.Fatalf("expected 8x4 image, got %v", img.Bounds())
+	}
+	if got := color.NRGBAModel.Convert(img.At(0, 1)); got != red {
+		t.Fatalf("expected glyph in cell color, got %v", got)
+	}
+	if got := color.NRGBAModel.Convert(img.At(5, 0)); got != blue {
+		t.Fatalf("expected cell background, got %v", got)
+	}
+
+	opt.UseCellColors = false
+	img = c.Image(boxDrawer{}, opt)
+	if got := color.NRGBAModel.Convert(img.At(0, 1)); got != (color.NRGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Fatalf("expected glyph in default foreground, got %v", got)
+	}
+	if got := color.NRGBAModel.Convert(img.At(5, 0)); got != (color.NRGBA{A: 255}) {
+		t.Fatalf("expected default background, got %v", got)
+	}
+}
@@ -0,0 +1,94 @@
+package export
+
+import "unicode"
+
+// cp437High holds the glyphs of code page 437 from 0x80 to 0xFF.
+const cp437High = "ÇüéâäàåçêëèïîìÄÅ" +
+	"ÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+	"áíóúñÑªº¿⌐¬½¼¡«»" +
+	"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+	"└┴┬├─┼╞╟╚╔╩╦╠═╬╧" +
+	"╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩" +
+	"≡±≥≤⌠⌡÷≈°∙·√ⁿ²■ "
+
+// cp437Bytes maps runes to their CP437 byte. The glyphs of 0x00-0x1F are left out on purpose,
+// ANSI viewers interpret those bytes as control codes (0x1A even ends the file).
+var cp437Bytes = func() map[rune]byte {
+	m := make(map[rune]byte, 224)
+	for b := 0x20; b < 0x7f; b++ {
+		m[rune(b)] = byte(b)
+	}
+	m['⌂'] = 0x7f
+	for i, r := range []rune(cp437High) {
+		m[r] = byte(0x80 + i)
+	}
+	return m
+}()
+
+// cp437Fallbacks maps common render glyphs outside CP437 to the closest glyph that is in it.
+var cp437Fallbacks = map[rune]rune{
+	// Shapes and dots used by the built-in ramps.
+	'●': '■', '•': '∙', '◦': '∙', '○': 'o', '□': '■', '▪': '■', '▫': '·', '◘': '█', '◙': '█',
+	'‧': '·', '⋅': '·', '∘': '°', '…': '.', '×': 'x', '✓': '√', '−': '-',
+
+	// Eighth, quadrant and edge blocks.
+	'▁': '_', '▂': '▄', '▃': '▄', '▅': '▄', '▆': '█', '▇': '█', '▔': '▀', '▕': '▐',
+	'▉': '█', '▊': '█', '▋': '▌', '▍': '▌', '▎': '│', '▏': '│',
+	'▖': '▄', '▗': '▄', '▘': '▀', '▝': '▀', '▚': '▒', '▞': '▒',
+	'▙': '█', '▛': '█', '▜': '█', '▟': '█',
+
+	// Box drawing, heavy, rounded and dashed lines fall back to light lines.
+	'╱': '/', '╲': '\\', '╳': 'X',
+	'━': '─', '┃': '│', '┄': '─', '┅': '─', '┆': '│', '┇': '│', '┈': '─', '┉': '─', '┊': '│', '┋': '│',
+	'╌': '─', '╍': '─', '╎': '│', '╏': '│', '╴': '─', '╵': '│', '╶': '─', '╷': '│',
+	'╸': '─', '╹': '│', '╺': '─', '╻': '│', '╼': '─', '╽': '│', '╾': '─', '╿': '│',
+	'┍': '┌', '┎': '┌', '┏': '┌', '┑': '┐', '┒': '┐', '┓': '┐', '╭': '┌', '╮': '┐',
+	'┕': '└', '┖': '└', '┗': '└', '┙': '┘', '┚': '┘', '┛': '┘', '╰': '└', '╯': '┘',
+	'┝': '├', '┞': '├', '┟': '├', '┠': '├', '┡': '├', '┢': '├', '┣': '├',
+	'┥': '┤', '┦': '┤', '┧': '┤', '┨': '┤', '┩': '┤', '┪': '┤', '┫': '┤',
+	'┭': '┬', '┮': '┬', '┯': '┬', '┰': '┬', '┱': '┬', '┲': '┬', '┳': '┬',
+	'┵': '┴', '┶': '┴', '┷': '┴', '┸': '┴', '┹': '┴', '┺': '┴', '┻': '┴',
+	'┽': '┼', '┾': '┼', '┿': '┼', '╀': '┼', '╁': '┼', '╂': '┼', '╃': '┼',
+	'╄': '┼', '╅': '┼', '╆': '┼', '╇': '┼', '╈': '┼', '╉': '┼', '╊': '┼', '╋': '┼',
+
+	// Arrows and typography.
+	'↑': '^', '↓': 'v', '→': '>', '←': '<', '▲': '^', '▼': 'v', '►': '>', '◄': '<', '▬': '■',
+	'‘': '\'', '’': '\'', '‚': ',', '‛': '\'', '“': '"', '”': '"', '„': '"',
+	'–': '-', '—': '-', '―': '-', '‐': '-', '‑': '-',
+}
+
+// cp437DensityRamp orders CP437 glyphs from empty to full, used for braille patterns.
+var cp437DensityRamp = []rune(" ·∙:░▒▒▓█")
+
+// toCP437 returns the CP437 byte for r, mapping runes outside the code page to their closest equivalent.
+func toCP437(r rune) byte {
+	if b, ok := cp437Bytes[r]; ok {
+		return b
+	}
+	if fallback, ok := cp437Fallbacks[r]; ok {
+		return cp437Bytes[fallback]
+	}
+
+	switch {
+	case r >= 0x2800 && r <= 0x28ff:
+		// Braille patterns map by the number of raised dots.
+		dots := 0
+		for bits := r - 0x2800; bits != 0; bits &= bits - 1 {
+			dots++
+		}
+		return cp437Bytes[cp437DensityRamp[dots]]
+	case unicode.IsSpace(r), unicode.IsControl(r):
+		return ' '
+	}
+	return '?'
+}
+
+// encodeCP437 converts s to CP437 with toCP437.
+func encodeCP437(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		out = append(out, toCP437(r))
+	}
+	return out
+}
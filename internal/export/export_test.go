@@ -1,15 +1,34 @@
 package export
 
 import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"image/gif"
+	"image/jpeg"
 	"image/png"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
 )
 
 func asciiToRunes(s string) [][]rune {
@@ -38,7 +57,7 @@ func TestASCIIToPNGCreatesValidPNG(t *testing.T) {
 	tmpDir := t.TempDir()
 	outPath := filepath.Join(tmpDir, "out.png")
 
-	err := ASCIIToPNG(asciiToRunes("hello\nworld"), nil, outPath, ASCIIExportOptions{
+	err := ASCIIToPNG(canvas.FromRunes(asciiToRunes("hello\nworld"), nil), outPath, ASCIIExportOptions{
 		FontSize:     14,
 		DPI:          300,
 		BG:           color.Black,
@@ -69,17 +88,17 @@ func TestASCIIFramesToGIFCreatesAnimatedGIF(t *testing.T) {
 	outPath := filepath.Join(tmpDir, "out.gif")
 
 	frames := []ASCIIGIFFrame{
-		{FrameRunes: asciiToRunes("frame one"), Duration: 40 * time.Millisecond},
-		{FrameRunes: asciiToRunes("frame two"), Duration: 90 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("frame one"), nil), Duration: 40 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("frame two"), nil), Duration: 90 * time.Millisecond},
 	}
 
-	err := ASCIIFramesToGIF(frames, outPath, ASCIIExportOptions{
+	_, err := ASCIIFramesToGIF(frames, outPath, ASCIIExportOptions{
 		FontSize:     14,
 		DPI:          300,
 		BG:           color.Black,
 		FG:           color.White,
 		TargetAspect: 1.0 / 2.3,
-	})
+	}, GIFExportOptions{})
 	if err != nil {
 		t.Fatalf("ASCIIFramesToGIF failed: %v", err)
 	}
@@ -110,12 +129,12 @@ func TestASCIIFramesToGIFNoFramesReturnsError(t *testing.T) {
 	tmpDir := t.TempDir()
 	outPath := filepath.Join(tmpDir, "out.gif")
 
-	err := ASCIIFramesToGIF(nil, outPath, ASCIIExportOptions{
+	_, err := ASCIIFramesToGIF(nil, outPath, ASCIIExportOptions{
 		FontSize: 14,
 		DPI:      300,
 		BG:       color.Black,
 		FG:       color.White,
-	})
+	}, GIFExportOptions{})
 	if err == nil {
 		t.Fatalf("expected error when exporting gif with no frames")
 	}
@@ -125,7 +144,7 @@ func TestASCIIToPNGInvalidFontPathReturnsError(t *testing.T) {
 	tmpDir := t.TempDir()
 	outPath := filepath.Join(tmpDir, "out.png")
 
-	err := ASCIIToPNG(asciiToRunes("test"), nil, outPath, ASCIIExportOptions{
+	err := ASCIIToPNG(canvas.FromRunes(asciiToRunes("test"), nil), outPath, ASCIIExportOptions{
 		FontSize:    14,
 		DPI:         300,
 		BG:          color.Black,
@@ -141,15 +160,15 @@ func TestASCIIFramesToGIFInvalidFontPathReturnsError(t *testing.T) {
 	tmpDir := t.TempDir()
 	outPath := filepath.Join(tmpDir, "out.gif")
 
-	err := ASCIIFramesToGIF([]ASCIIGIFFrame{
-		{FrameRunes: asciiToRunes("test"), Duration: 10 * time.Millisecond},
+	_, err := ASCIIFramesToGIF([]ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("test"), nil), Duration: 10 * time.Millisecond},
 	}, outPath, ASCIIExportOptions{
 		FontSize:    14,
 		DPI:         300,
 		BG:          color.Black,
 		FG:          color.White,
 		FontTTFPath: filepath.Join(tmpDir, "missing.ttf"),
-	})
+	}, GIFExportOptions{})
 	if err == nil {
 		t.Fatalf("expected error for missing custom font path")
 	}
@@ -174,22 +193,385 @@ func TestASCIIToTxtWritesContent(t *testing.T) {
 	}
 }
 
+func TestASCIIToPlainTextTrimsAndUsesLineEnding(t *testing.T) {
+	c := canvas.FromRunes([][]rune{[]rune("ab  "), []rune(" c  ")}, nil)
+
+	got := ASCIIToPlainText(c, TextExportOptions{LineEnding: LineEndingCRLF, TrimTrailingSpace: true})
+	if want := "ab\r\n c\r\n"; got != want {
+		t.Fatalf("plain text mismatch: want %q got %q", want, got)
+	}
+	if got := ASCIIToPlainText(c, TextExportOptions{}); got != "ab  \n c  \n" {
+		t.Fatalf("expected untrimmed LF text, got %q", got)
+	}
+}
+
+func TestASCIIToANSITextColorDepths(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	c := canvas.New(4, 1)
+	c.Set(0, 0, canvas.Cell{Rune: 'a', FG: red})
+	c.Set(1, 0, canvas.Cell{Rune: 'b', FG: red})
+	c.Set(2, 0, canvas.Cell{Rune: 'c', FG: red, BG: blue})
+	c.Set(3, 0, canvas.Cell{Rune: ' '})
+
+	tests := []struct {
+		depth ANSIColorDepth
+		want  string
+	}{
+		{ANSITrueColor, "\x1b[0;38;2;255;0;0mab\x1b[0;38;2;255;0;0;48;2;0;0;255mc\x1b[0m\n\x1b[0m"},
+		{ANSI256Color, "\x1b[0;38;5;196mab\x1b[0;38;5;196;48;5;21mc\x1b[0m\n\x1b[0m"},
+		{ANSI16Color, "\x1b[0;31mab\x1b[0;31;44mc\x1b[0m\n\x1b[0m"},
+	}
+	for _, tt := range tests {
+		got := ASCIIToANSIText(c, TextExportOptions{TrimTrailingSpace: true, FinalReset: true, ColorDepth: tt.depth})
+		if got != tt.want {
+			t.Fatalf("depth %d: want %q got %q", tt.depth, tt.want, got)
+		}
+	}
+}
+
+func TestASCIIToANSIArtWritesCP437AndSAUCE(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "art.ans")
+
+	c := canvas.New(3, 2)
+	c.Set(0, 0, canvas.Cell{Rune: '█', FG: color.NRGBA{R: 255, G: 255, B: 255, A: 255}})
+	c.Set(1, 0, canvas.Cell{Rune: '●', BG: color.NRGBA{R: 255, G: 85, B: 85, A: 255}})
+	c.Set(2, 0, canvas.Cell{Rune: '⣿'})
+	c.Set(0, 1, canvas.Cell{Rune: 'é'})
+	c.Set(1, 1, canvas.Cell{Rune: ' '})
+	c.Set(2, 1, canvas.Cell{Rune: ' '})
+
+	date := time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)
+	err := ASCIIToANSIArt(c, outPath, ANSIArtOptions{Title: "Café", Author: "mezzo", ICEColors: true, Date: date})
+	if err != nil {
+		t.Fatalf("ASCIIToANSIArt failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read ans output: %v", err)
+	}
+
+	eof := bytes.IndexByte(data, 0x1a)
+	if eof < 0 || len(data)-eof-1 != 128 {
+		t.Fatalf("expected EOF marker followed by a 128 byte SAUCE record, got %d bytes after EOF", len(data)-eof-1)
+	}
+	// Rows narrower than the 80 column viewer end with a line break, full or trimmed.
+	wantBody := "\x1b[0m\x1b[0;1;37;40m\xdb\x1b[0;5;37;41m\xfe\x1b[0;37;40m\xdb\r\n\x82\r\n\x1b[0m"
+	if got := string(data[:eof]); got != wantBody {
+		t.Fatalf("ans body mismatch:\nwant %q\ngot  %q", wantBody, got)
+	}
+
+	// A full row at the viewer width wraps on its own.
+	wide := canvas.FromRunes([][]rune{[]rune(strings.Repeat("#", 80)), []rune("#")}, nil)
+	wideData, err := encodeANSIArt(wide, ANSIArtOptions{})
+	if err != nil {
+		t.Fatalf("encodeANSIArt failed: %v", err)
+	}
+	if want := "\x1b[0m" + strings.Repeat("#", 80) + "#\r\n\x1b[0m\x1a"; !bytes.HasPrefix(wideData, []byte(want)) {
+		t.Fatalf("expected no line break after a full 80 column row, got %q", wideData[:bytes.IndexByte(wideData, 0x1a)])
+	}
+
+	sauce := data[eof+1:]
+	if string(sauce[:7]) != "SAUCE00" {
+		t.Fatalf("expected SAUCE00 id, got %q", sauce[:7])
+	}
+	if title := string(sauce[7:42]); title != "Caf\x82"+strings.Repeat(" ", 31) {
+		t.Fatalf("unexpected SAUCE title %q", title)
+	}
+	if author := strings.TrimRight(string(sauce[42:62]), " "); author != "mezzo" {
+		t.Fatalf("unexpected SAUCE author %q", author)
+	}
+	if got := string(sauce[82:90]); got != "20240309" {
+		t.Fatalf("unexpected SAUCE date %q", got)
+	}
+	if size := binary.LittleEndian.Uint32(sauce[90:94]); int(size) != eof {
+		t.Fatalf("SAUCE file size %d does not match body size %d", size, eof)
+	}
+	if sauce[94] != 1 || sauce[95] != 1 {
+		t.Fatalf("expected character/ANSi data type, got %d/%d", sauce[94], sauce[95])
+	}
+	if w, h := binary.LittleEndian.Uint16(sauce[96:98]), binary.LittleEndian.Uint16(sauce[98:100]); w != 3 || h != 2 {
+		t.Fatalf("expected SAUCE size 3x2, got %dx%d", w, h)
+	}
+	if sauce[105]&1 == 0 {
+		t.Fatalf("expected iCE colors flag")
+	}
+	if font := strings.TrimRight(string(sauce[106:]), "\x00"); font != "IBM VGA" {
+		t.Fatalf("unexpected SAUCE font %q", font)
+	}
+}
+
+func TestASCIIFramesToAsciicastWritesTimedEvents(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.cast")
+
+	red := color.NRGBA{R: 255, A: 255}
+	frames := []ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes([][]rune{[]rune("ab"), []rune("cd")}, nil), Duration: 250 * time.Millisecond},
+		{Canvas: canvas.FromRunes([][]rune{[]rune("xyz")}, [][]color.NRGBA{{red, red, red}}), Duration: 500 * time.Millisecond},
+	}
+	if err := ASCIIFramesToAsciicast(frames, outPath, TextExportOptions{}); err != nil {
+		t.Fatalf("ASCIIFramesToAsciicast failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read cast output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 events, got %d lines: %q", len(lines), data)
+	}
+
+	var header struct {
+		Version, Width, Height int
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("invalid header %q: %v", lines[0], err)
+	}
+	if header.Version != 2 || header.Width != 3 || header.Height != 2 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+
+	wantEvents := []struct {
+		at   float64
+		data string
+	}{
+		{0, "\x1b[?25l\x1b[H\x1b[2Jab\r\ncd\x1b[0m"},
+		{0.25, "\x1b[H\x1b[2J\x1b[0;38;2;255;0;0mxyz\x1b[0m"},
+		{0.75, "\x1b[?25h"},
+	}
+	for i, want := range wantEvents {
+		var event []any
+		if err := json.Unmarshal([]byte(lines[i+1]), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", lines[i+1], err)
+		}
+		if len(event) != 3 || event[0] != want.at || event[1] != "o" || event[2] != want.data {
+			t.Fatalf("event %d: want [%v o %q], got %q", i, want.at, want.data, lines[i+1])
+		}
+	}
+}
+
+func TestASCIIToPDFEmbedsSubsetAndWritesRows(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.pdf")
+
+	red := color.NRGBA{R: 255, A: 255}
+	c := canvas.FromRunes([][]rune{[]rune("ab"), []rune("cc")}, [][]color.NRGBA{{{}, red}, {{}, {}}})
+	opt := ASCIIExportOptions{FontSize: 10, RenderColor: true}
+	if err := ASCIIToPDF(c, outPath, opt, PDFExportOptions{PaperSize: "A4", Margin: 36}); err != nil {
+		t.Fatalf("ASCIIToPDF failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read pdf output: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("expected PDF header and trailer")
+	}
+	if !bytes.Contains(data, []byte("/MediaBox [0 0 595.28 841.89]")) {
+		t.Fatalf("expected A4 media box")
+	}
+
+	// Every xref entry must point at its object.
+	startXref := bytes.LastIndex(data, []byte("startxref\n"))
+	xrefOffset, err := strconv.Atoi(strings.Fields(string(data[startXref+len("startxref\n"):]))[0])
+	if err != nil {
+		t.Fatalf("invalid startxref: %v", err)
+	}
+	xrefLines := strings.Split(string(data[xrefOffset:]), "\n")
+	count, _ := strconv.Atoi(strings.Fields(xrefLines[1])[1])
+	for id := 1; id < count; id++ {
+		offset, _ := strconv.Atoi(strings.Fields(xrefLines[2+id])[0])
+		if want := fmt.Sprintf("%d 0 obj", id); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q", id, data[offset:offset+10])
+		}
+	}
+
+	streams := pdfStreams(t, data)
+	fontFile := streams[8]
+	f, err := sfnt.Parse(fontFile)
+	if err != nil {
+		t.Fatalf("embedded font does not parse: %v", err)
+	}
+	var buf sfnt.Buffer
+	gidA, _ := f.GlyphIndex(&buf, 'a')
+	gidB, _ := f.GlyphIndex(&buf, 'b')
+	gidC, _ := f.GlyphIndex(&buf, 'c')
+	if gidA == 0 || gidB == 0 || gidC == 0 {
+		t.Fatalf("expected a, b and c in the embedded subset")
+	}
+	if gid, _ := f.GlyphIndex(&buf, 'z'); gid != 0 {
+		t.Fatalf("expected unused glyphs to be dropped from the subset cmap")
+	}
+
+	content := string(streams[4])
+	wantRow0 := fmt.Sprintf("1 1 1 rg 1 1 1 RG <%04X> Tj\n1 0 0 rg 1 0 0 RG <%04X> Tj\n", gidA, gidB)
+	wantRow1 := fmt.Sprintf("Tm\n1 1 1 rg 1 1 1 RG <%04X%04X> Tj\n", gidC, gidC)
+	if !strings.Contains(content, wantRow0) || !strings.Contains(content, wantRow1) {
+		t.Fatalf("expected one text line per row with color changes, got:\n%s", content)
+	}
+	if strings.Count(content, " Tm\n") != 2 {
+		t.Fatalf("expected one text matrix per row, got:\n%s", content)
+	}
+
+	toUnicode := string(streams[9])
+	if !strings.Contains(toUnicode, fmt.Sprintf("<%04X> <0061>", gidA)) {
+		t.Fatalf("expected ToUnicode entry for a, got:\n%s", toUnicode)
+	}
+}
+
+// pdfStreams inflates every stream of a PDF written by buildPDF, keyed by object id.
+func pdfStreams(t *testing.T, data []byte) map[int][]byte {
+	t.Helper()
+
+	streams := make(map[int][]byte)
+	for _, part := range bytes.Split(data, []byte("endobj\n")) {
+		header, rest, ok := bytes.Cut(part, []byte(">>\nstream\n"))
+		if !ok {
+			continue
+		}
+		objEnd := bytes.LastIndex(header, []byte(" 0 obj"))
+		objStart := bytes.LastIndexByte(header[:max(objEnd, 0)], '\n') + 1
+		id, err := strconv.Atoi(string(header[objStart:max(objEnd, objStart)]))
+		if err != nil {
+			t.Fatalf("invalid object header %q", header)
+		}
+		rest = bytes.TrimSuffix(rest, []byte("\nendstream\n"))
+		zr, err := zlib.NewReader(bytes.NewReader(rest))
+		if err != nil {
+			t.Fatalf("stream %d is not Flate compressed: %v", id, err)
+		}
+		inflated, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("failed to inflate stream %d: %v", id, err)
+		}
+		streams[id] = inflated
+	}
+	return streams
+}
+
+func TestGridFormatsRoundTrip(t *testing.T) {
+	red := color.NRGBA{R: 255, A: 255}
+	first := canvas.New(2, 2)
+	first.Set(0, 0, canvas.Cell{Rune: '█', FG: red, Attrs: canvas.AttrBold})
+	first.Set(1, 0, canvas.Cell{Rune: '<', BG: color.NRGBA{G: 128, A: 200}})
+	first.Set(0, 1, canvas.Cell{Rune: '░'})
+	first.Set(1, 1, canvas.Cell{Rune: ' '})
+	for i, l := range []float64{1, 0.5, 0.25, 0} {
+		first.SetLuminance(i%2, i/2, l)
+	}
+	second := first.Clone()
+	second.Set(1, 1, canvas.Cell{Rune: '▒'})
+
+	doc := GridDocument{
+		Ramp:    "█▓▒░ ",
+		Options: map[string]string{"runeMode": "RECTANGLES", "textSize": "8"},
+		Frames: []ASCIIGIFFrame{
+			{Canvas: first, Duration: 100 * time.Millisecond},
+			{Canvas: second, Duration: 83333 * time.Microsecond},
+		},
+	}
+
+	tmpDir := t.TempDir()
+	for name, write := range map[string]func(GridDocument, string) error{"grid.json": GridToJSON, "grid.mzg": GridToBinary} {
+		outPath := filepath.Join(tmpDir, name)
+		if err := write(doc, outPath); err != nil {
+			t.Fatalf("%s: write failed: %v", name, err)
+		}
+		got, err := ReadGridFile(outPath)
+		if err != nil {
+			t.Fatalf("%s: read failed: %v", name, err)
+		}
+
+		if got.Ramp != doc.Ramp || got.Options["runeMode"] != "RECTANGLES" || got.Options["textSize"] != "8" {
+			t.Fatalf("%s: ramp or options lost: %q %v", name, got.Ramp, got.Options)
+		}
+		if len(got.Frames) != 2 {
+			t.Fatalf("%s: expected 2 frames, got %d", name, len(got.Frames))
+		}
+		for i, frame := range got.Frames {
+			if !frame.Canvas.Equal(doc.Frames[i].Canvas) {
+				t.Fatalf("%s: frame %d cells differ: %q", name, i, frame.Canvas.PlainText())
+			}
+			if frame.Duration != doc.Frames[i].Duration {
+				t.Fatalf("%s: frame %d duration %v, want %v", name, i, frame.Duration, doc.Frames[i].Duration)
+			}
+			if l, ok := frame.Canvas.Luminance(0, 1); !ok || math.Abs(l-0.25) > 1e-4 {
+				t.Fatalf("%s: frame %d luminance %v %v, want 0.25", name, i, l, ok)
+			}
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(tmpDir, "grid.mzg"))
+	if err != nil {
+		t.Fatalf("failed to read binary grid: %v", err)
+	}
+	if string(data[:6]) != "MZGRID" || binary.LittleEndian.Uint16(data[8:]) != 2 || binary.LittleEndian.Uint32(data[12:]) != 2 {
+		t.Fatalf("unexpected binary grid header % x", data[:20])
+	}
+}
+
+func TestGridToBinaryRejectsMixedFrameSizes(t *testing.T) {
+	doc := GridDocument{Frames: []ASCIIGIFFrame{
+		{Canvas: canvas.New(2, 1)},
+		{Canvas: canvas.New(3, 1)},
+	}}
+	if err := GridToBinary(doc, filepath.Join(t.TempDir(), "grid.mzg")); err == nil {
+		t.Fatalf("expected error for frames of different sizes")
+	}
+}
+
+func TestASCIIToPNGAppliesPaddingAndScale(t *testing.T) {
+	tmpDir := t.TempDir()
+	c := canvas.FromRunes(asciiToRunes("ab"), nil)
+	opt := ASCIIExportOptions{FontSize: 14, DPI: 72, BG: color.White, FG: color.Black}
+
+	decode := func(name string, opt ASCIIExportOptions) image.Image {
+		outPath := filepath.Join(tmpDir, name)
+		if err := ASCIIToPNG(c, outPath, opt); err != nil {
+			t.Fatalf("ASCIIToPNG failed: %v", err)
+		}
+		f, err := os.Open(outPath)
+		if err != nil {
+			t.Fatalf("failed to open png output: %v", err)
+		}
+		defer f.Close()
+		img, err := png.Decode(f)
+		if err != nil {
+			t.Fatalf("failed to decode png: %v", err)
+		}
+		return img
+	}
+
+	plain := decode("plain.png", opt)
+	opt.Padding = 5
+	opt.Scale = 2
+	padded := decode("padded.png", opt)
+
+	wantW, wantH := 2*(plain.Bounds().Dx()+10), 2*(plain.Bounds().Dy()+10)
+	if padded.Bounds().Dx() != wantW || padded.Bounds().Dy() != wantH {
+		t.Fatalf("expected %dx%d, got %v", wantW, wantH, padded.Bounds())
+	}
+	if r, g, b, _ := padded.At(1, 1).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 {
+		t.Fatalf("expected padding in the background color, got %v", padded.At(1, 1))
+	}
+}
+
 func TestASCIIFramesToGIFClampsDelay(t *testing.T) {
 	tmpDir := t.TempDir()
 	outPath := filepath.Join(tmpDir, "clamped-delay.gif")
 
 	frames := []ASCIIGIFFrame{
-		{FrameRunes: asciiToRunes("short"), Duration: 0},
-		{FrameRunes: asciiToRunes("this frame is wider"), Duration: time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("short"), nil), Duration: 0},
+		{Canvas: canvas.FromRunes(asciiToRunes("this frame is wider"), nil), Duration: time.Millisecond},
 	}
 
-	err := ASCIIFramesToGIF(frames, outPath, ASCIIExportOptions{
+	_, err := ASCIIFramesToGIF(frames, outPath, ASCIIExportOptions{
 		FontSize:     14,
 		DPI:          300,
 		BG:           color.Black,
 		FG:           color.White,
 		TargetAspect: 1.0 / 2.3,
-	})
+	}, GIFExportOptions{})
 	if err != nil {
 		t.Fatalf("ASCIIFramesToGIF failed: %v", err)
 	}
@@ -222,7 +604,7 @@ func TestASCIIToPNGEmptyInputStillCreatesImage(t *testing.T) {
 	tmpDir := t.TempDir()
 	outPath := filepath.Join(tmpDir, "empty.png")
 
-	if err := ASCIIToPNG(asciiToRunes(""), nil, outPath, ASCIIExportOptions{
+	if err := ASCIIToPNG(canvas.FromRunes(asciiToRunes(""), nil), outPath, ASCIIExportOptions{
 		FontSize: 14,
 		DPI:      300,
 		BG:       color.Black,
@@ -261,7 +643,7 @@ func TestASCIIToPNGEmptyInputStillCreatesImage(t *testing.T) {
 	}
 
 	// Smoke-check that decoding a newline-normalized payload remains valid.
-	if err := ASCIIToPNG(asciiToRunes(strings.ReplaceAll("a\r\nb", "\r\n", "\n")), nil, filepath.Join(tmpDir, "normalized.png"), ASCIIExportOptions{
+	if err := ASCIIToPNG(canvas.FromRunes(asciiToRunes(strings.ReplaceAll("a\r\nb", "\r\n", "\n")), nil), filepath.Join(tmpDir, "normalized.png"), ASCIIExportOptions{
 		FontSize: 14,
 		DPI:      300,
 		BG:       color.Black,
@@ -283,7 +665,7 @@ func TestASCIIToPNGRenderColorUsesPerCellColor(t *testing.T) {
 		},
 	}
 
-	if err := ASCIIToPNG(runes, colors, outPath, ASCIIExportOptions{
+	if err := ASCIIToPNG(canvas.FromRunes(runes, colors), outPath, ASCIIExportOptions{
 		FontSize:    20,
 		DPI:         300,
 		BG:          color.Black,
@@ -321,28 +703,26 @@ func TestASCIIFramesToGIFRenderColorUsesPerFrameColors(t *testing.T) {
 
 	frames := []ASCIIGIFFrame{
 		{
-			FrameRunes: asciiToRunes("X"),
-			FrameColors: [][]color.NRGBA{
+			Canvas: canvas.FromRunes(asciiToRunes("X"), [][]color.NRGBA{
 				{{R: 255, G: 0, B: 0, A: 255}},
-			},
+			}),
 			Duration: 20 * time.Millisecond,
 		},
 		{
-			FrameRunes: asciiToRunes("X"),
-			FrameColors: [][]color.NRGBA{
+			Canvas: canvas.FromRunes(asciiToRunes("X"), [][]color.NRGBA{
 				{{R: 0, G: 255, B: 0, A: 255}},
-			},
+			}),
 			Duration: 20 * time.Millisecond,
 		},
 	}
 
-	if err := ASCIIFramesToGIF(frames, outPath, ASCIIExportOptions{
+	if _, err := ASCIIFramesToGIF(frames, outPath, ASCIIExportOptions{
 		FontSize:    20,
 		DPI:         300,
 		BG:          color.Black,
 		FG:          color.White,
 		RenderColor: true,
-	}); err != nil {
+	}, GIFExportOptions{}); err != nil {
 		t.Fatalf("ASCIIFramesToGIF failed: %v", err)
 	}
 
@@ -370,3 +750,940 @@ func TestASCIIFramesToGIFRenderColorUsesPerFrameColors(t *testing.T) {
 		t.Fatalf("expected frame 0 to contain red-dominant pixels and frame 1 to contain green-dominant pixels (got red=%v green=%v)", frame0HasRed, frame1HasGreen)
 	}
 }
+
+func TestASCIIFramesToGIFAdaptivePaletteKeepsExactColors(t *testing.T) {
+	orange := color.NRGBA{R: 255, G: 128, B: 0, A: 255}
+	bg := color.RGBA{R: 250, G: 248, B: 240, A: 255}
+	frames := []ASCIIGIFFrame{{
+		Canvas:   canvas.FromRunes(asciiToRunes("##"), [][]color.NRGBA{{orange, orange}}),
+		Duration: 20 * time.Millisecond,
+	}}
+
+	for _, gifOpt := range []GIFExportOptions{
+		{Palette: GIFPaletteMedianCut},
+		{Palette: GIFPaletteOctree},
+		{Palette: GIFPaletteMedianCut, Dither: true},
+	} {
+		outPath := filepath.Join(t.TempDir(), "palette.gif")
+		if _, err := ASCIIFramesToGIF(frames, outPath, ASCIIExportOptions{
+			FontSize:    20,
+			DPI:         72,
+			BG:          bg,
+			FG:          color.Black,
+			RenderColor: true,
+		}, gifOpt); err != nil {
+			t.Fatalf("ASCIIFramesToGIF(%+v) failed: %v", gifOpt, err)
+		}
+
+		data, err := os.ReadFile(outPath)
+		if err != nil {
+			t.Fatalf("failed to read gif output: %v", err)
+		}
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to decode gif output: %v", err)
+		}
+
+		global, ok := g.Config.ColorModel.(color.Palette)
+		if !ok || g.BackgroundIndex != 0 {
+			t.Fatalf("expected a global palette with the background at index 0")
+		}
+		if r, gr, b := rgb8(global[0]); r != bg.R || gr != bg.G || b != bg.B {
+			t.Fatalf("expected background %v at index 0, got %v", bg, global[0])
+		}
+		hasOrange := imageHasPixelMatching(g.Image[0], func(r, g, b, a uint32) bool {
+			return r>>8 == 255 && g>>8 == 128 && b>>8 == 0
+		})
+		if !hasOrange {
+			t.Fatalf("expected the exact glyph color with %+v", gifOpt)
+		}
+	}
+}
+
+func TestASCIIFramesToGIFUsesLocalPaletteOnlyWhenCloser(t *testing.T) {
+	shades := func(base func(i int) color.NRGBA) ASCIIGIFFrame {
+		runes := make([]rune, 200)
+		colors := make([]color.NRGBA, 200)
+		for i := range runes {
+			runes[i] = '#'
+			colors[i] = base(i)
+		}
+		return ASCIIGIFFrame{Canvas: canvas.FromRunes([][]rune{runes}, [][]color.NRGBA{colors}), Duration: 20 * time.Millisecond}
+	}
+	reds := shades(func(i int) color.NRGBA { return color.NRGBA{R: uint8(55 + i), A: 255} })
+	blues := shades(func(i int) color.NRGBA { return color.NRGBA{B: uint8(55 + i), G: uint8(i / 2), A: 255} })
+	opt := ASCIIExportOptions{FontSize: 10, DPI: 72, BG: color.Black, RenderColor: true}
+
+	sources, err := renderGIFSources([]ASCIIGIFFrame{reds, blues}, opt, gifKeepBytes)
+	if err != nil {
+		t.Fatalf("renderGIFSources failed: %v", err)
+	}
+	palettes, global := gifFramePalettes(sources.histograms, 2, opt.BG, GIFExportOptions{})
+	if samePalette(palettes[0], global) || samePalette(palettes[1], global) {
+		t.Fatalf("expected frames with disjoint colors to get local palettes")
+	}
+
+	sources, err = renderGIFSources([]ASCIIGIFFrame{reds, reds}, opt, gifKeepBytes)
+	if err != nil {
+		t.Fatalf("renderGIFSources failed: %v", err)
+	}
+	palettes, global = gifFramePalettes(sources.histograms, 2, opt.BG, GIFExportOptions{})
+	if !samePalette(palettes[0], global) || !samePalette(palettes[1], global) {
+		t.Fatalf("expected identical frames to share the global palette")
+	}
+}
+
+func TestASCIIFramesToGIFComparesSizeOnlyWhenAsked(t *testing.T) {
+	frames := []ASCIIGIFFrame{{Canvas: canvas.FromRunes(asciiToRunes("#."), nil), Duration: 100 * time.Millisecond}}
+	outPath := filepath.Join(t.TempDir(), "plain.gif")
+
+	report, err := ASCIIFramesToGIF(frames, outPath, ASCIIExportOptions{FontSize: 12, DPI: 72}, GIFExportOptions{})
+	if err != nil {
+		t.Fatalf("ASCIIFramesToGIF failed: %v", err)
+	}
+	if report.BytesBefore != 0 || report.BytesAfter == 0 {
+		t.Fatalf("expected only the written size without CompareSize, got %+v", report)
+	}
+	if strings.Contains(report.String(), "smaller") {
+		t.Fatalf("expected no comparison in %q", report.String())
+	}
+}
+
+func TestQuantizeGIFFramesRendersFramesOverTheKeepBudgetAgain(t *testing.T) {
+	frames := []ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes([][]rune{[]rune("#.")}, [][]color.NRGBA{{{R: 255, A: 255}, {G: 255, A: 255}}})},
+		{Canvas: canvas.FromRunes([][]rune{[]rune("@")}, [][]color.NRGBA{{{B: 255, A: 255}}})},
+		{Canvas: canvas.FromRunes([][]rune{[]rune("%%")}, [][]color.NRGBA{{{R: 255, G: 128, A: 255}, {A: 255}}})},
+	}
+	opt := ASCIIExportOptions{FontSize: 12, DPI: 72, BG: color.Black, RenderColor: true, TargetAspect: 0.5}
+
+	kept, err := renderGIFSources(frames, opt, gifKeepBytes)
+	if err != nil {
+		t.Fatalf("renderGIFSources failed: %v", err)
+	}
+	frameBytes := len(kept.kept[0].Pix)
+	// Only the first frame fits, the others are rendered again when they are mapped.
+	partial, err := renderGIFSources(frames, opt, frameBytes)
+	if err != nil {
+		t.Fatalf("renderGIFSources failed: %v", err)
+	}
+	if partial.kept[0] == nil || partial.kept[1] != nil || partial.kept[2] != nil {
+		t.Fatalf("expected only the first frame to be kept")
+	}
+
+	palettes, _ := gifFramePalettes(kept.histograms, len(frames), opt.BG, GIFExportOptions{})
+	want, err := quantizeGIFFrames(frames, opt, kept, palettes, true)
+	if err != nil {
+		t.Fatalf("quantizeGIFFrames failed: %v", err)
+	}
+	got, err := quantizeGIFFrames(frames, opt, partial, palettes, true)
+	if err != nil {
+		t.Fatalf("quantizeGIFFrames failed: %v", err)
+	}
+	for i := range frames {
+		if got[i].Bounds() != want[i].Bounds() || !bytes.Equal(got[i].Pix, want[i].Pix) {
+			t.Fatalf("expected frame %d rendered again to match the kept frame", i)
+		}
+	}
+}
+
+func TestGIFQuantizersFindColorClusters(t *testing.T) {
+	var colors []weightedColor
+	centers := [][3]float64{{20, 20, 20}, {200, 40, 40}, {40, 40, 220}}
+	for _, c := range centers {
+		for d := -4.0; d <= 4; d++ {
+			colors = append(colors, weightedColor{r: c[0] + d, g: c[1] - d, b: c[2] + d/2, count: 10})
+		}
+	}
+
+	for name, quantize := range map[string]func([]weightedColor, int) []weightedColor{
+		"median cut": medianCut,
+		"octree":     octreeQuantize,
+	} {
+		picked := quantize(colors, 3)
+		if len(picked) != 3 {
+			t.Fatalf("%s: expected 3 colors, got %d", name, len(picked))
+		}
+		for _, c := range centers {
+			found := false
+			for _, p := range picked {
+				if math.Abs(p.r-c[0]) < 4 && math.Abs(p.g-c[1]) < 4 && math.Abs(p.b-c[2]) < 4 {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("%s: expected a color near %v, got %+v", name, c, picked)
+			}
+		}
+	}
+}
+
+func TestASCIIFramesToGIFMergesIdenticalFramesAndStoresDeltas(t *testing.T) {
+	frames := []ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("#.#.#\n.#.#."), nil), Duration: 100 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("#.#.#\n.#.#."), nil), Duration: 200 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("#.#.#\n.#@#."), nil), Duration: 300 * time.Millisecond},
+	}
+	outPath := filepath.Join(t.TempDir(), "optimized.gif")
+	opt := ASCIIExportOptions{FontSize: 20, DPI: 72, BG: color.Black, FG: color.White}
+
+	report, err := ASCIIFramesToGIF(frames, outPath, opt, GIFExportOptions{CompareSize: true})
+	if err != nil {
+		t.Fatalf("ASCIIFramesToGIF failed: %v", err)
+	}
+	if report.FramesBefore != 3 || report.FramesAfter != 2 {
+		t.Fatalf("expected 3 -> 2 frames, got %+v", report)
+	}
+	if report.BytesAfter >= report.BytesBefore {
+		t.Fatalf("expected the optimized gif to be smaller, got %+v", report)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read gif output: %v", err)
+	}
+	if int64(len(data)) != report.BytesAfter {
+		t.Fatalf("expected the report to match the file size %d, got %d", len(data), report.BytesAfter)
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode gif output: %v", err)
+	}
+	if !slices.Equal(g.Delay, []int{30, 30}) {
+		t.Fatalf("expected merged delays [30 30], got %v", g.Delay)
+	}
+	if !slices.Equal(g.Disposal, []byte{gif.DisposalNone, gif.DisposalNone}) {
+		t.Fatalf("expected explicit disposal none, got %v", g.Disposal)
+	}
+
+	full, delta := g.Image[0], g.Image[1]
+	if delta.Rect.Dx() >= full.Rect.Dx() || delta.Rect.Dy() >= full.Rect.Dy() {
+		t.Fatalf("expected the second frame cropped to the changed cell, got %v of %v", delta.Rect, full.Rect)
+	}
+	transparent := 0
+	for _, index := range delta.Pix {
+		if _, _, _, a := delta.Palette[index].RGBA(); a == 0 {
+			transparent++
+		}
+	}
+	if transparent == 0 {
+		t.Fatalf("expected unchanged pixels inside the delta to be transparent")
+	}
+
+	// Drawing the delta over the first frame must give the last source frame.
+	composed := image.NewRGBA(full.Rect)
+	draw.Draw(composed, full.Rect, full, image.Point{}, draw.Src)
+	draw.Draw(composed, delta.Rect, delta, delta.Rect.Min, draw.Over)
+	r, err := newASCIIRenderer(opt)
+	if err != nil {
+		t.Fatalf("newASCIIRenderer failed: %v", err)
+	}
+	defer r.Close()
+	want := r.RenderFrame(frames[2].Canvas, r.fontVariables(5, 2))
+	for y := full.Rect.Min.Y; y < full.Rect.Max.Y; y++ {
+		for x := full.Rect.Min.X; x < full.Rect.Max.X; x++ {
+			if composed.RGBAAt(x, y) != want.RGBAAt(x, y) {
+				t.Fatalf("composed pixel %d,%d is %v, want %v", x, y, composed.RGBAAt(x, y), want.RGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestGIFDelaysFollowPolicyAndSpeed(t *testing.T) {
+	frames := make([]ASCIIGIFFrame, 3)
+	for i, d := range []time.Duration{0, 10 * time.Millisecond, 250 * time.Millisecond} {
+		frames[i].Duration = d
+	}
+
+	for _, tc := range []struct {
+		gifOpt GIFExportOptions
+		want   []int
+	}{
+		{GIFExportOptions{}, []int{1, 1, 25}},
+		{GIFExportOptions{DelayPolicy: GIFDelayBrowser}, []int{10, 10, 25}},
+		{GIFExportOptions{DelayPolicy: GIFDelayKeep, Speed: 0.5}, []int{1, 2, 50}},
+		{GIFExportOptions{DelayPolicy: GIFDelayFixedFPS, FPS: 30}, []int{3, 4, 3}},
+	} {
+		got, err := gifDelays(frames, tc.gifOpt)
+		if err != nil {
+			t.Fatalf("gifDelays(%+v) failed: %v", tc.gifOpt, err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("gifDelays(%+v) = %v, want %v", tc.gifOpt, got, tc.want)
+		}
+	}
+
+	if _, err := gifDelays(frames, GIFExportOptions{DelayPolicy: GIFDelayFixedFPS}); err == nil {
+		t.Fatalf("expected fixed fps without a rate to fail")
+	}
+}
+
+func TestGlyphAtlasMatchesFaceDrawer(t *testing.T) {
+	colors := [][]color.NRGBA{
+		{{R: 255, A: 255}, {G: 200, A: 255}, {B: 255, A: 128}, {R: 10, G: 20, B: 30, A: 255}},
+		{{R: 255, G: 255, A: 255}, {}, {R: 128, B: 128, A: 255}, {G: 255, B: 255, A: 255}},
+	}
+	c := canvas.FromRunes(asciiToRunes("@#gj\nW%y@"), colors)
+	opt := ASCIIExportOptions{FontSize: 18, DPI: 96, BG: color.RGBA{R: 40, G: 40, B: 60, A: 255}, RenderColor: true}
+
+	r, err := newASCIIRenderer(opt)
+	if err != nil {
+		t.Fatalf("newASCIIRenderer failed: %v", err)
+	}
+	defer r.Close()
+
+	fontVars := r.fontVariables(c.Width(), c.Height())
+	got := r.RenderFrame(c, fontVars)
+	want := c.Image(canvas.FaceDrawer{Face: r.face}, canvas.ImageOptions{
+		CellWidth:     fontVars.cellW,
+		LineHeight:    fontVars.lineH,
+		Ascent:        fontVars.ascent,
+		Width:         fontVars.width,
+		Height:        fontVars.height,
+		FG:            r.opt.FG,
+		BG:            r.opt.BG,
+		UseCellColors: true,
+	})
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Fatalf("expected the atlas to draw the same pixels as the face")
+	}
+	if len(r.atlas.glyphs) != 7 {
+		t.Fatalf("expected 7 distinct runes in the atlas, got %d", len(r.atlas.glyphs))
+	}
+}
+
+// fontCollection packs sfnt fonts into a .ttc, moving the table offsets of each font to its place in the file.
+func fontCollection(t *testing.T, fonts ...[]byte) []byte {
+	t.Helper()
+	header := 12 + 4*len(fonts)
+	out := make([]byte, header)
+	copy(out, "ttcf")
+	binary.BigEndian.PutUint32(out[4:], 0x00010000)
+	binary.BigEndian.PutUint32(out[8:], uint32(len(fonts)))
+	for i, f := range fonts {
+		base := len(out)
+		binary.BigEndian.PutUint32(out[12+4*i:], uint32(base))
+		f = slices.Clone(f)
+		numTables := int(binary.BigEndian.Uint16(f[4:]))
+		for table := 0; table < numTables; table++ {
+			offset := f[12+16*table+8:]
+			binary.BigEndian.PutUint32(offset, binary.BigEndian.Uint32(offset)+uint32(base))
+		}
+		out = append(out, f...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+func TestFontChainResolvesRunesToFirstCoveringFont(t *testing.T) {
+	dir := t.TempDir()
+	subset := func(runes string) []byte {
+		s, err := subsetTrueType(Font, []rune(runes))
+		if err != nil {
+			t.Fatalf("subsetTrueType failed: %v", err)
+		}
+		return s.data
+	}
+	primary := filepath.Join(dir, "ab.ttf")
+	collection := filepath.Join(dir, "cd.ttc")
+	if err := os.WriteFile(primary, subset("AB"), 0o644); err != nil {
+		t.Fatalf("failed to write font: %v", err)
+	}
+	if err := os.WriteFile(collection, fontCollection(t, subset("C"), subset("D")), 0o644); err != nil {
+		t.Fatalf("failed to write collection: %v", err)
+	}
+
+	opt := ASCIIExportOptions{FontSize: 20, DPI: 72, FontTTFPath: primary, FallbackFontPaths: []string{collection}}
+	chain, err := loadFontChain(opt)
+	if err != nil {
+		t.Fatalf("loadFontChain failed: %v", err)
+	}
+	if len(chain) != 4 {
+		t.Fatalf("expected export font, 2 collection fonts and the embedded font, got %d fonts", len(chain))
+	}
+	var buf sfnt.Buffer
+	for r, want := range map[rune]int{'A': 0, 'C': 1, 'D': 2, 'Z': 3, '\u0378': -1} {
+		if got := fontForRune(chain, &buf, r); got != want {
+			t.Fatalf("expected %q to resolve to font %d, got %d", r, want, got)
+		}
+	}
+
+	uncovered, err := UncoveredRunes([]*canvas.Canvas{canvas.FromRunes([][]rune{[]rune("AC \u0378D\u0378")}, nil)}, opt)
+	if err != nil {
+		t.Fatalf("UncoveredRunes failed: %v", err)
+	}
+	if string(uncovered) != "\u0378" {
+		t.Fatalf("expected only U+0378 to be uncovered, got %q", string(uncovered))
+	}
+
+	// C comes from the collection, a subset of the embedded font, so it matches a render with the embedded font.
+	render := func(opt ASCIIExportOptions) *image.RGBA {
+		r, err := newASCIIRenderer(opt)
+		if err != nil {
+			t.Fatalf("newASCIIRenderer failed: %v", err)
+		}
+		defer r.Close()
+		c := canvas.FromRunes(asciiToRunes("C"), nil)
+		return r.RenderFrame(c, r.fontVariables(1, 1))
+	}
+	if !bytes.Equal(render(opt).Pix, render(ASCIIExportOptions{FontSize: 20, DPI: 72}).Pix) {
+		t.Fatalf("expected C to be drawn from the fallback font")
+	}
+}
+
+func TestFontInstanceFollowsAxes(t *testing.T) {
+	measure := func(data []byte) (fixed.Int26_6, fixed.Rectangle26_6) {
+		t.Helper()
+		f, err := opentype.Parse(data)
+		if err != nil {
+			t.Fatalf("parse instance: %v", err)
+		}
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: 1000, DPI: 72})
+		if err != nil {
+			t.Fatalf("new face: %v", err)
+		}
+		defer func() { _ = face.Close() }()
+		bounds, advance, ok := face.GlyphBounds('M')
+		if !ok {
+			t.Fatalf("instance has no 'M'")
+		}
+		return advance, bounds
+	}
+	defaultAdvance, defaultBounds := measure(Font)
+
+	same, err := instanceFontData(Font, map[string]float64{FontAxisWeight: 400, FontAxisWidth: 100})
+	if err != nil {
+		t.Fatalf("instance at defaults: %v", err)
+	}
+	if &same[0] != &Font[0] {
+		t.Fatalf("expected the default instance to keep the font as it is")
+	}
+
+	narrow, err := instanceFontData(Font, map[string]float64{FontAxisWidth: 62.5})
+	if err != nil {
+		t.Fatalf("instance at wdth 62.5: %v", err)
+	}
+	if advance, _ := measure(narrow); advance != fixed.I(500) {
+		t.Fatalf("expected a condensed advance of 500 units, got %v (default %v)", advance, defaultAdvance)
+	}
+
+	bold, err := instanceFontData(Font, map[string]float64{FontAxisWeight: 700})
+	if err != nil {
+		t.Fatalf("instance at wght 700: %v", err)
+	}
+	advance, bounds := measure(bold)
+	if advance != defaultAdvance {
+		t.Fatalf("expected the bold advance to stay %v, got %v", defaultAdvance, advance)
+	}
+	if bounds.Min.X >= defaultBounds.Min.X {
+		t.Fatalf("expected bolder stems to reach further out, got %v default %v", bounds, defaultBounds)
+	}
+}
+
+func TestASCIIToPNGUsesExactCellGeometry(t *testing.T) {
+	c := canvas.FromRunes(asciiToRunes("@#M\nW%y"), nil)
+	dir := t.TempDir()
+
+	readSize := func(opt ASCIIExportOptions) image.Point {
+		t.Helper()
+		outPath := filepath.Join(dir, "cells.png")
+		if err := ASCIIToPNG(c, outPath, opt); err != nil {
+			t.Fatalf("ASCIIToPNG failed: %v", err)
+		}
+		f, err := os.Open(outPath)
+		if err != nil {
+			t.Fatalf("open png: %v", err)
+		}
+		defer func() { _ = f.Close() }()
+		cfg, err := png.DecodeConfig(f)
+		if err != nil {
+			t.Fatalf("decode png: %v", err)
+		}
+		return image.Pt(cfg.Width, cfg.Height)
+	}
+
+	size := readSize(ASCIIExportOptions{FontSize: 20, DPI: 72, TargetAspect: 0.3, CellWidth: 9, CellHeight: 21})
+	if size != image.Pt(27, 42) {
+		t.Fatalf("expected explicit cells to give 27x42 without aspect stretching, got %v", size)
+	}
+
+	opt := ASCIIExportOptions{FontSize: 20, DPI: 72, TargetAspect: 1 / 3.2, FitWidthToAspect: true, LineSpacing: 1.25}
+	exportFont, err := loadASCIIFont(opt)
+	if err != nil {
+		t.Fatalf("loadASCIIFont failed: %v", err)
+	}
+	if exportFont.opt.FontWidth <= 62.5 || exportFont.opt.FontWidth >= 100 {
+		t.Fatalf("expected a condensed width between the axis limits, got %v", exportFont.opt.FontWidth)
+	}
+	r, err := exportFont.newRenderer()
+	if err != nil {
+		t.Fatalf("newRenderer failed: %v", err)
+	}
+	defer r.Close()
+
+	fontVars := r.fontVariables(c.Width(), c.Height())
+	advance := font.MeasureString(r.face, "M").Ceil()
+	if !fontVars.exact || fontVars.cellW != int(math.Round(float64(fontVars.lineH)/3.2)) || fontVars.cellW-advance > 1 {
+		t.Fatalf("expected the fitted advance %d to fill cells of %dx%d", advance, fontVars.cellW, fontVars.lineH)
+	}
+	if size := readSize(opt); size != image.Pt(3*fontVars.cellW, 2*fontVars.lineH) {
+		t.Fatalf("expected the fitted export to keep its %dx%d cells, got %v", fontVars.cellW, fontVars.lineH, size)
+	}
+}
+
+func TestASCIIFramesToGIFSizesToTallestFrame(t *testing.T) {
+	tmpDir := t.TempDir()
+	opt := ASCIIExportOptions{
+		FontSize: 14,
+		DPI:      72,
+		BG:       color.Black,
+		FG:       color.White,
+	}
+
+	decodeConfig := func(path string) gif.GIF {
+		t.Helper()
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("failed to open gif output: %v", err)
+		}
+		defer f.Close()
+
+		g, err := gif.DecodeAll(f)
+		if err != nil {
+			t.Fatalf("failed to decode gif output: %v", err)
+		}
+		return *g
+	}
+
+	singlePath := filepath.Join(tmpDir, "single.gif")
+	if _, err := ASCIIFramesToGIF([]ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("a"), nil), Duration: 10 * time.Millisecond},
+	}, singlePath, opt, GIFExportOptions{}); err != nil {
+		t.Fatalf("ASCIIFramesToGIF failed: %v", err)
+	}
+
+	mixedPath := filepath.Join(tmpDir, "mixed.gif")
+	if _, err := ASCIIFramesToGIF([]ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("a"), nil), Duration: 10 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("a\nb\nc"), nil), Duration: 10 * time.Millisecond},
+	}, mixedPath, opt, GIFExportOptions{}); err != nil {
+		t.Fatalf("ASCIIFramesToGIF failed: %v", err)
+	}
+
+	single := decodeConfig(singlePath)
+	mixed := decodeConfig(mixedPath)
+	if mixed.Image[0].Bounds().Dy() != 3*single.Image[0].Bounds().Dy() {
+		t.Fatalf("expected gif height of 3 rows (%d), got %d", 3*single.Image[0].Bounds().Dy(), mixed.Image[0].Bounds().Dy())
+	}
+}
+
+func TestASCIIFramesToGIFNilCanvasReturnsError(t *testing.T) {
+	_, err := ASCIIFramesToGIF([]ASCIIGIFFrame{{Duration: 10 * time.Millisecond}}, filepath.Join(t.TempDir(), "nil.gif"), ASCIIExportOptions{}, GIFExportOptions{})
+	if err == nil {
+		t.Fatalf("expected error for frame without canvas")
+	}
+}
+
+func TestNewVideoTimingKeepsExactDurations(t *testing.T) {
+	timing := newVideoTiming([]ASCIIGIFFrame{
+		{Duration: 40 * time.Millisecond},
+		{Duration: 120 * time.Millisecond},
+		{Duration: 0},
+	})
+	if timing.tick != 10*time.Millisecond {
+		t.Fatalf("expected 10ms tick, got %v", timing.tick)
+	}
+	if timing.rate != 100 || timing.scale != 1 {
+		t.Fatalf("expected 100/1 rate, got %d/%d", timing.rate, timing.scale)
+	}
+	if want := []int{4, 12, 1}; !slices.Equal(timing.ticks, want) {
+		t.Fatalf("expected ticks %v, got %v", want, timing.ticks)
+	}
+
+	// NTSC style durations have no usable common divisor, frames land on rounded timestamps.
+	ntsc := make([]ASCIIGIFFrame, 30)
+	var total time.Duration
+	for i := range ntsc {
+		start := time.Duration(int64(i) * 1001 * int64(time.Second) / 30000)
+		end := time.Duration(int64(i+1) * 1001 * int64(time.Second) / 30000)
+		ntsc[i].Duration = end - start
+		total += end - start
+	}
+	timing = newVideoTiming(ntsc)
+	if got := time.Duration(timing.totalTicks()) * timing.tick; got-total > timing.tick/2 || total-got > timing.tick/2 {
+		t.Fatalf("expected total %v within half a tick, got %v", total, got)
+	}
+}
+
+func TestASCIIFramesToAVIWritesMJPEGWithTiming(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.avi")
+	frames := []ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("one"), nil), Duration: 50 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("two"), nil), Duration: 150 * time.Millisecond},
+	}
+	if err := ASCIIFramesToAVI(frames, outPath, ASCIIExportOptions{FontSize: 14, DPI: 72}); err != nil {
+		t.Fatalf("ASCIIFramesToAVI failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read avi output: %v", err)
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "AVI " {
+		t.Fatalf("expected RIFF AVI header, got %q", data[:12])
+	}
+	if got := int(binary.LittleEndian.Uint32(data[4:8])); got != len(data)-8 {
+		t.Fatalf("expected RIFF size %d, got %d", len(data)-8, got)
+	}
+
+	strh := bytes.Index(data, []byte("strh"))
+	if strh < 0 || string(data[strh+8:strh+12]) != "vids" || string(data[strh+12:strh+16]) != "MJPG" {
+		t.Fatalf("expected MJPG video stream header")
+	}
+	scale := binary.LittleEndian.Uint32(data[strh+28:])
+	rate := binary.LittleEndian.Uint32(data[strh+32:])
+	length := binary.LittleEndian.Uint32(data[strh+40:])
+	if rate != 20 || scale != 1 || length != 4 {
+		t.Fatalf("expected 20 fps with 4 chunks (1 + 3 repeats), got %d/%d length %d", rate, scale, length)
+	}
+
+	idx := bytes.LastIndex(data, []byte("idx1"))
+	if idx < 0 || binary.LittleEndian.Uint32(data[idx+4:]) != 4*16 {
+		t.Fatalf("expected idx1 with 4 entries")
+	}
+
+	movi := bytes.Index(data, []byte("movi"))
+	firstSize := binary.LittleEndian.Uint32(data[movi+8:])
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data[movi+12 : movi+12+int(firstSize)]))
+	if err != nil {
+		t.Fatalf("expected first chunk to be a jpeg: %v", err)
+	}
+	if cfg.Width%2 != 0 || cfg.Height%2 != 0 {
+		t.Fatalf("expected even frame size, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestASCIIFramesToY4MWritesRepeatedFrames(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.y4m")
+	frames := []ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("a"), nil), Duration: 100 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("b"), nil), Duration: 200 * time.Millisecond},
+	}
+	if err := ASCIIFramesToY4M(frames, outPath, ASCIIExportOptions{FontSize: 14, DPI: 72, BG: color.Black, FG: color.White}); err != nil {
+		t.Fatalf("ASCIIFramesToY4M failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read y4m output: %v", err)
+	}
+	header, body, _ := bytes.Cut(data, []byte("\n"))
+	var w, h, rate, scale int
+	if _, err := fmt.Sscanf(string(header), "YUV4MPEG2 W%d H%d F%d:%d", &w, &h, &rate, &scale); err != nil {
+		t.Fatalf("failed to parse y4m header %q: %v", header, err)
+	}
+	if rate != 10 || scale != 1 {
+		t.Fatalf("expected 10 fps, got %d:%d", rate, scale)
+	}
+	if !strings.Contains(string(header), "C444") {
+		t.Fatalf("expected 4:4:4 stream, got %q", header)
+	}
+
+	frameSize := len("FRAME\n") + 3*w*h
+	if len(body) != 3*frameSize {
+		t.Fatalf("expected 3 frames (1 + 2 repeats) of %d bytes, got %d bytes", frameSize, len(body))
+	}
+	if !bytes.Equal(body[frameSize:2*frameSize], body[2*frameSize:]) {
+		t.Fatalf("expected repeated frames to be identical")
+	}
+	if body[len("FRAME\n")] != 0 {
+		t.Fatalf("expected black background luma 0, got %d", body[len("FRAME\n")])
+	}
+}
+
+func TestASCIIFramesToAPNGWritesAnimationChunks(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.png")
+	frames := []ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("ab"), nil), Duration: 40 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("ac"), nil), Duration: 250 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("ac"), nil), Duration: 0},
+	}
+	if err := ASCIIFramesToAPNG(frames, outPath, ASCIIExportOptions{FontSize: 14, DPI: 72, BG: color.Black, FG: color.White}); err != nil {
+		t.Fatalf("ASCIIFramesToAPNG failed: %v", err)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read apng output: %v", err)
+	}
+	// The default image is the first frame, so plain PNG decoders still read it.
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("expected a valid png, got %v", err)
+	}
+
+	var types []string
+	var controls [][]byte
+	for rest := data[8:]; len(rest) >= 12; {
+		length := binary.BigEndian.Uint32(rest[:4])
+		typ := string(rest[4:8])
+		types = append(types, typ)
+		if typ == "fcTL" {
+			controls = append(controls, rest[8:8+length])
+		}
+		rest = rest[12+length:]
+	}
+	want := []string{"IHDR", "acTL", "fcTL", "IDAT", "fcTL", "fdAT", "fcTL", "fdAT", "IEND"}
+	if !slices.Equal(types, want) {
+		t.Fatalf("expected chunks %v, got %v", want, types)
+	}
+
+	delays := [][2]uint16{{1, 25}, {1, 4}, {1, 100}}
+	for i, fctl := range controls {
+		num, den := binary.BigEndian.Uint16(fctl[20:22]), binary.BigEndian.Uint16(fctl[22:24])
+		if [2]uint16{num, den} != delays[i] {
+			t.Fatalf("frame %d: expected delay %d/%d, got %d/%d", i, delays[i][0], delays[i][1], num, den)
+		}
+	}
+
+	// Only the changed glyph is stored for the second frame, the identical third frame is a 1x1 patch.
+	second := controls[1]
+	if w := binary.BigEndian.Uint32(second[4:8]); int(w) >= img.Bounds().Dx() {
+		t.Fatalf("expected second frame to be cropped, got width %d of %d", w, img.Bounds().Dx())
+	}
+	if x := binary.BigEndian.Uint32(second[12:16]); x == 0 {
+		t.Fatalf("expected second frame to start after the unchanged glyph")
+	}
+	if w, h := binary.BigEndian.Uint32(controls[2][4:8]), binary.BigEndian.Uint32(controls[2][8:12]); w != 1 || h != 1 {
+		t.Fatalf("expected 1x1 patch for identical frame, got %dx%d", w, h)
+	}
+}
+
+func TestSubsetTrueTypeKeepsOnlyRequestedGlyphs(t *testing.T) {
+	subset, err := subsetTrueType(Font, []rune("Ab"))
+	if err != nil {
+		t.Fatalf("subsetTrueType failed: %v", err)
+	}
+	if len(subset.data) >= len(Font)/10 {
+		t.Fatalf("expected subset to be much smaller than %d bytes, got %d", len(Font), len(subset.data))
+	}
+
+	f, err := sfnt.Parse(subset.data)
+	if err != nil {
+		t.Fatalf("expected subset to parse: %v", err)
+	}
+	var buf sfnt.Buffer
+	for _, r := range "Ab" {
+		gid, err := f.GlyphIndex(&buf, r)
+		if err != nil || gid == 0 || gid != sfnt.GlyphIndex(subset.glyphs[r]) {
+			t.Fatalf("expected %q to map to glyph %d, got %d (%v)", r, subset.glyphs[r], gid, err)
+		}
+		segments, err := f.LoadGlyph(&buf, gid, fixed.I(14), nil)
+		if err != nil || len(segments) == 0 {
+			t.Fatalf("expected outline for %q, got %d segments (%v)", r, len(segments), err)
+		}
+	}
+	if gid, _ := f.GlyphIndex(&buf, 'Z'); gid != 0 {
+		t.Fatalf("expected unused rune to be unmapped, got glyph %d", gid)
+	}
+
+	woff, err := sfntToWOFF(subset.data)
+	if err != nil {
+		t.Fatalf("sfntToWOFF failed: %v", err)
+	}
+	if string(woff[0:4]) != "wOFF" || binary.BigEndian.Uint32(woff[8:12]) != uint32(len(woff)) {
+		t.Fatalf("expected woff header with total length, got %q", woff[:12])
+	}
+}
+
+func TestASCIIToSVGMergesColorRuns(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.svg")
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	c := canvas.FromRunes(asciiToRunes("ab <c\nxy"), [][]color.NRGBA{
+		{red, red, {}, red, blue},
+		{{}, {}},
+	})
+	c.Set(0, 1, canvas.Cell{Rune: 'x', BG: color.NRGBA{G: 128, A: 255}})
+
+	err := ASCIIToSVG(c, outPath, ASCIIExportOptions{
+		FontSize:     14,
+		DPI:          72,
+		FG:           color.White,
+		BG:           color.Black,
+		TargetAspect: 0.5,
+		RenderColor:  true,
+		FontFamily:   "Courier",
+	})
+	if err != nil {
+		t.Fatalf("ASCIIToSVG failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read svg: %v", err)
+	}
+	svg := string(data)
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := decoder.Token(); err != nil {
+			if err != io.EOF {
+				t.Fatalf("expected well-formed svg: %v", err)
+			}
+			break
+		}
+	}
+
+	// "ab <" share red (the space joins the run), "c" is blue.
+	if !strings.Contains(svg, `<tspan fill="#ff0000">ab &lt;</tspan><tspan fill="#0000ff">c</tspan>`) {
+		t.Fatalf("expected merged color runs, got:\n%s", svg)
+	}
+	if !strings.Contains(svg, `fill="#008000"`) {
+		t.Fatalf("expected cell background rect")
+	}
+	if !strings.Contains(svg, `<g transform="scale(`) || strings.Contains(svg, `scale(1 1)`) {
+		t.Fatalf("expected target aspect as a scale transform")
+	}
+	if !strings.Contains(svg, "font-family:Courier") || strings.Contains(svg, "@font-face") {
+		t.Fatalf("expected referenced font family without embedding")
+	}
+}
+
+func TestASCIIToSVGEmbedsWOFFSubset(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.svg")
+	if err := ASCIIToSVG(canvas.FromRunes(asciiToRunes("#@"), nil), outPath, ASCIIExportOptions{FontSize: 14, DPI: 72, EmbedFont: true}); err != nil {
+		t.Fatalf("ASCIIToSVG failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read svg: %v", err)
+	}
+
+	_, encoded, ok := strings.Cut(string(data), "data:font/woff;base64,")
+	if !ok {
+		t.Fatalf("expected embedded woff font")
+	}
+	encoded, _, _ = strings.Cut(encoded, ")")
+	woff, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("failed to decode embedded font: %v", err)
+	}
+	if string(woff[0:4]) != "wOFF" {
+		t.Fatalf("expected woff signature, got %q", woff[0:4])
+	}
+	if len(data) > 64*1024 {
+		t.Fatalf("expected a subset font, svg is %d bytes", len(data))
+	}
+}
+
+func TestASCIIFramesToAnimatedSVGDeduplicatesFrames(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.svg")
+	frames := []ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("one"), nil), Duration: 100 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("two"), nil), Duration: 200 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("two"), nil), Duration: 100 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("one"), nil), Duration: 400 * time.Millisecond},
+	}
+	if err := ASCIIFramesToAnimatedSVG(frames, outPath, ASCIIExportOptions{FontSize: 14, DPI: 72}); err != nil {
+		t.Fatalf("ASCIIFramesToAnimatedSVG failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read svg: %v", err)
+	}
+	svg := string(data)
+
+	if n := strings.Count(svg, `class="frame"`); n != 2 {
+		t.Fatalf("expected 2 distinct frame groups, got %d", n)
+	}
+	if !strings.Contains(svg, "animation:800ms step-end infinite") {
+		t.Fatalf("expected 800ms loop, got:\n%s", svg)
+	}
+	// "one" shows for 0-100ms and 400-800ms, "two" for the merged 100-400ms window.
+	if !strings.Contains(svg, "@keyframes frame0{0%{visibility:visible}12.5%{visibility:hidden}50%{visibility:visible}}") {
+		t.Fatalf("expected keyframes for the repeated frame, got:\n%s", svg)
+	}
+	if !strings.Contains(svg, "@keyframes frame1{0%{visibility:hidden}12.5%{visibility:visible}50%{visibility:hidden}}") {
+		t.Fatalf("expected merged window for consecutive identical frames, got:\n%s", svg)
+	}
+}
+
+func TestASCIIFramesToAnimatedHTMLEmbedsSVG(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.html")
+	frames := []ASCIIGIFFrame{
+		{Canvas: canvas.FromRunes(asciiToRunes("a"), nil), Duration: 50 * time.Millisecond},
+		{Canvas: canvas.FromRunes(asciiToRunes("b"), nil), Duration: 50 * time.Millisecond},
+	}
+	if err := ASCIIFramesToAnimatedHTML(frames, outPath, ASCIIExportOptions{FontSize: 14, DPI: 72}); err != nil {
+		t.Fatalf("ASCIIFramesToAnimatedHTML failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read html: %v", err)
+	}
+	html := string(data)
+	if !strings.HasPrefix(html, "<!DOCTYPE html>") || !strings.Contains(html, "<svg ") || strings.Contains(html, "<?xml") {
+		t.Fatalf("expected standalone html with inline svg, got:\n%s", html)
+	}
+}
+
+func TestASCIIToHTMLWritesPageWithMergedSpans(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.html")
+	red := color.NRGBA{R: 255, A: 255}
+	c := canvas.FromRunes(asciiToRunes("a b<c"), [][]color.NRGBA{{red, red, red, {}, {}}})
+
+	err := ASCIIToHTML(c, outPath, ASCIIExportOptions{FontSize: 14, DPI: 72, RenderColor: true}, HTMLExportOptions{
+		FontAspect: 2,
+		Theme:      HTMLThemeAuto,
+	})
+	if err != nil {
+		t.Fatalf("ASCIIToHTML failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read html: %v", err)
+	}
+	html := string(data)
+
+	if !strings.Contains(html, `<pre class="mezzotone"><span style="color:#ff0000">a b</span>&lt;c</pre>`) {
+		t.Fatalf("expected one merged span and escaped text, got:\n%s", html)
+	}
+	if !strings.Contains(html, "@media (prefers-color-scheme: light){:root{--bg:#ffffff;--fg:#000000}}") {
+		t.Fatalf("expected light scheme override, got:\n%s", html)
+	}
+
+	// Noto Sans Mono advances 0.6em, a 2:1 cell needs a 1.2 line height.
+	_, rest, _ := strings.Cut(html, "line-height:")
+	lineHeight, _, _ := strings.Cut(rest, ";")
+	if v, err := strconv.ParseFloat(lineHeight, 64); err != nil || v < 1.1 || v > 1.3 {
+		t.Fatalf("expected line height near 1.2 from the font aspect, got %q", lineHeight)
+	}
+}
+
+func TestASCIIToHTMLFragmentUsesInlineStyles(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "out.html")
+	err := ASCIIToHTML(canvas.FromRunes(asciiToRunes("ab\ncd"), nil), outPath, ASCIIExportOptions{BG: color.White, FG: color.Black}, HTMLExportOptions{
+		Fragment: true,
+		Theme:    HTMLThemeLight,
+	})
+	if err != nil {
+		t.Fatalf("ASCIIToHTML failed: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("failed to read html: %v", err)
+	}
+	html := string(data)
+
+	if !strings.HasPrefix(html, "<pre style=") || strings.Contains(html, "<html") || strings.Contains(html, "<span") {
+		t.Fatalf("expected a bare pre fragment without spans, got:\n%s", html)
+	}
+	if !strings.Contains(html, "background:#000000;color:#ffffff") {
+		t.Fatalf("expected swapped light theme colors, got:\n%s", html)
+	}
+	if !strings.Contains(html, ">ab\ncd</pre>") {
+		t.Fatalf("expected rows separated by newlines, got:\n%s", html)
+	}
+}
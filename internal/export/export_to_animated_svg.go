@@ -0,0 +1,141 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+// animationTrack is one distinct frame and the time windows it is visible in.
+type animationTrack struct {
+	canvas  *canvas.Canvas
+	windows [][2]time.Duration
+}
+
+// dedupeAnimationFrames groups identical consecutive or repeated frames, so each distinct canvas is emitted once.
+// Frame durations are floored like in the GIF and video exporters.
+func dedupeAnimationFrames(frames []ASCIIGIFFrame) ([]animationTrack, time.Duration, error) {
+	var tracks []animationTrack
+	byText := make(map[string][]int)
+
+	var elapsed time.Duration
+	previous := -1
+	for i, frame := range frames {
+		if frame.Canvas == nil {
+			return nil, 0, fmt.Errorf("frame %d has no canvas", i)
+		}
+		duration := max(frame.Duration, minVideoFrameDuration)
+
+		track := -1
+		key := frame.Canvas.PlainText()
+		for _, candidate := range byText[key] {
+			if tracks[candidate].canvas.Equal(frame.Canvas) {
+				track = candidate
+				break
+			}
+		}
+		if track == -1 {
+			track = len(tracks)
+			tracks = append(tracks, animationTrack{canvas: frame.Canvas})
+			byText[key] = append(byText[key], track)
+		}
+
+		windows := tracks[track].windows
+		if track == previous {
+			windows[len(windows)-1][1] += duration
+		} else {
+			tracks[track].windows = append(windows, [2]time.Duration{elapsed, elapsed + duration})
+		}
+		elapsed += duration
+		previous = track
+	}
+	return tracks, elapsed, nil
+}
+
+// animatedSVG renders frames as one SVG with a group per distinct frame.
+// Groups are hidden by default and a step-end CSS animation shows each one during its windows,
+// so playback loops without script and the text stays selectable.
+func animatedSVG(frames []ASCIIGIFFrame, opt ASCIIExportOptions) (string, error) {
+	if len(frames) == 0 {
+		return "", fmt.Errorf("no frames to export")
+	}
+
+	tracks, total, err := dedupeAnimationFrames(frames)
+	if err != nil {
+		return "", err
+	}
+	canvases := make([]*canvas.Canvas, len(tracks))
+	for i := range tracks {
+		canvases[i] = tracks[i].canvas
+	}
+	layout, err := newSVGLayout(canvases, opt)
+	if err != nil {
+		return "", err
+	}
+
+	var css strings.Builder
+	if len(tracks) > 1 {
+		fmt.Fprintf(&css, ".frame{visibility:hidden;animation:%sms step-end infinite}", formatSVGNumber(float64(total)/float64(time.Millisecond)))
+		for i, track := range tracks {
+			fmt.Fprintf(&css, "#frame%d{animation-name:frame%d}@keyframes frame%d{", i, i, i)
+			if track.windows[0][0] > 0 {
+				css.WriteString("0%{visibility:hidden}")
+			}
+			for _, window := range track.windows {
+				fmt.Fprintf(&css, "%s%%{visibility:visible}", animationPercent(window[0], total))
+				if window[1] < total {
+					fmt.Fprintf(&css, "%s%%{visibility:hidden}", animationPercent(window[1], total))
+				}
+			}
+			css.WriteString("}")
+		}
+	}
+
+	var sb strings.Builder
+	writeSVGHeader(&sb, layout, opt, css.String())
+	sb.WriteString(`<g transform="scale(` + formatSVGNumber(layout.scaleX) + ` 1)">` + "\n")
+	for i, track := range tracks {
+		if len(tracks) > 1 {
+			fmt.Fprintf(&sb, `<g class="frame" id="frame%d">`+"\n", i)
+		} else {
+			sb.WriteString("<g>\n")
+		}
+		writeSVGCanvas(&sb, track.canvas, layout, opt)
+		sb.WriteString("</g>\n")
+	}
+	sb.WriteString("</g>\n</svg>\n")
+	return sb.String(), nil
+}
+
+func animationPercent(at, total time.Duration) string {
+	return formatSVGNumber(float64(at) / float64(total) * 100)
+}
+
+// ASCIIFramesToAnimatedSVG writes frames as a single looping SVG driven by CSS keyframes.
+func ASCIIFramesToAnimatedSVG(frames []ASCIIGIFFrame, outPath string, opt ASCIIExportOptions) error {
+	svg, err := animatedSVG(frames, opt)
+	if err != nil {
+		return err
+	}
+	return writeStringFile(outPath, svg)
+}
+
+// ASCIIFramesToAnimatedHTML writes the animated SVG inline in a standalone HTML page.
+func ASCIIFramesToAnimatedHTML(frames []ASCIIGIFFrame, outPath string, opt ASCIIExportOptions) error {
+	svg, err := animatedSVG(frames, opt)
+	if err != nil {
+		return err
+	}
+	// The XML declaration is not allowed inside HTML.
+	_, svg, _ = strings.Cut(svg, "?>\n")
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Mezzotone</title>\n")
+	fmt.Fprintf(&sb, "<style>body{margin:0;background:%s}svg{display:block;max-width:100%%;height:auto}</style>\n", cssColor(exportBG(opt)))
+	sb.WriteString("</head>\n<body>\n")
+	sb.WriteString(svg)
+	sb.WriteString("</body>\n</html>\n")
+	return writeStringFile(outPath, sb.String())
+}
@@ -0,0 +1,224 @@
+package export
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+// ANSIColorDepth selects the SGR color codes used by ANSI text exports.
+type ANSIColorDepth int
+
+const (
+	ANSITrueColor ANSIColorDepth = iota
+	ANSI256Color
+	ANSI16Color
+)
+
+const (
+	LineEndingLF   = "\n"
+	LineEndingCRLF = "\r\n"
+
+	ansiReset = "\x1b[0m"
+)
+
+type TextExportOptions struct {
+	// LineEnding is written after every row, empty means LineEndingLF.
+	LineEnding string
+	// TrimTrailingSpace drops spaces at the end of rows. In ANSI output spaces with a background are kept.
+	TrimTrailingSpace bool
+	// FinalReset ends ANSI output with a reset, so the terminal is left unstyled after cat.
+	FinalReset bool
+	ColorDepth ANSIColorDepth
+}
+
+// ansi16Palette holds the standard VGA colors in SGR order, 8-15 are the bright variants.
+var ansi16Palette = []color.NRGBA{
+	{0x00, 0x00, 0x00, 0xff}, {0xaa, 0x00, 0x00, 0xff}, {0x00, 0xaa, 0x00, 0xff}, {0xaa, 0x55, 0x00, 0xff},
+	{0x00, 0x00, 0xaa, 0xff}, {0xaa, 0x00, 0xaa, 0xff}, {0x00, 0xaa, 0xaa, 0xff}, {0xaa, 0xaa, 0xaa, 0xff},
+	{0x55, 0x55, 0x55, 0xff}, {0xff, 0x55, 0x55, 0xff}, {0x55, 0xff, 0x55, 0xff}, {0xff, 0xff, 0x55, 0xff},
+	{0x55, 0x55, 0xff, 0xff}, {0xff, 0x55, 0xff, 0xff}, {0x55, 0xff, 0xff, 0xff}, {0xff, 0xff, 0xff, 0xff},
+}
+
+// ASCIIToPlainText serializes the glyphs of c as UTF-8 text without any escape sequence.
+func ASCIIToPlainText(c *canvas.Canvas, opt TextExportOptions) string {
+	if c == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for y := 0; y < c.Height(); y++ {
+		var line strings.Builder
+		for _, cell := range c.Row(y) {
+			line.WriteRune(cell.Rune)
+		}
+		row := line.String()
+		if opt.TrimTrailingSpace {
+			row = strings.TrimRightFunc(row, unicode.IsSpace)
+		}
+		sb.WriteString(row)
+		sb.WriteString(lineEnding(opt))
+	}
+	return sb.String()
+}
+
+// ansiStyle is the SGR state of one cell, zero alpha means the terminal default.
+type ansiStyle struct {
+	fg, bg color.NRGBA
+	attrs  canvas.Attr
+}
+
+// ASCIIToANSIText serializes c with SGR sequences for cell colors and attributes.
+// Codes are only written when the style changes, and backgrounds are reset before line breaks so they do not bleed.
+func ASCIIToANSIText(c *canvas.Canvas, opt TextExportOptions) string {
+	if c == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	var current ansiStyle
+	for y := 0; y < c.Height(); y++ {
+		row := c.Row(y)
+		if opt.TrimTrailingSpace {
+			for len(row) > 0 {
+				last := row[len(row)-1]
+				if !unicode.IsSpace(last.Rune) || last.BG.A > 0 || last.Attrs&canvas.AttrUnderline != 0 {
+					break
+				}
+				row = row[:len(row)-1]
+			}
+		}
+
+		for _, cell := range row {
+			style := ansiStyle{fg: cell.FG, bg: cell.BG, attrs: cell.Attrs}
+			if style != current {
+				sb.WriteString(ansiSGR(style, opt.ColorDepth))
+				current = style
+			}
+			sb.WriteRune(cell.Rune)
+		}
+
+		if current.bg.A > 0 || current.attrs&canvas.AttrUnderline != 0 {
+			sb.WriteString(ansiReset)
+			current = ansiStyle{}
+		}
+		sb.WriteString(lineEnding(opt))
+	}
+
+	if opt.FinalReset {
+		sb.WriteString(ansiReset)
+	}
+	return sb.String()
+}
+
+// ansiSGR returns the sequence that switches the terminal from any state to style.
+func ansiSGR(style ansiStyle, depth ANSIColorDepth) string {
+	params := []string{"0"}
+	if style.attrs&canvas.AttrBold != 0 {
+		params = append(params, "1")
+	}
+	if style.attrs&canvas.AttrUnderline != 0 {
+		params = append(params, "4")
+	}
+	if style.fg.A > 0 {
+		params = append(params, ansiColorParams(style.fg, depth, false))
+	}
+	if style.bg.A > 0 {
+		params = append(params, ansiColorParams(style.bg, depth, true))
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+func ansiColorParams(c color.NRGBA, depth ANSIColorDepth, background bool) string {
+	switch depth {
+	case ANSI256Color:
+		prefix := "38;5;"
+		if background {
+			prefix = "48;5;"
+		}
+		return prefix + strconv.Itoa(nearestXterm256(c))
+	case ANSI16Color:
+		index := nearestPaletteIndex(c, ansi16Palette)
+		base := 30
+		if background {
+			base = 40
+		}
+		if index >= 8 {
+			base += 60
+			index -= 8
+		}
+		return strconv.Itoa(base + index)
+	default:
+		prefix := "38;2;"
+		if background {
+			prefix = "48;2;"
+		}
+		return prefix + strconv.Itoa(int(c.R)) + ";" + strconv.Itoa(int(c.G)) + ";" + strconv.Itoa(int(c.B))
+	}
+}
+
+// nearestXterm256 maps c to the closest entry of the xterm 6x6x6 color cube or gray ramp.
+// The first 16 entries are skipped, terminals redefine them freely.
+func nearestXterm256(c color.NRGBA) int {
+	levels := [6]int{0, 95, 135, 175, 215, 255}
+	nearestLevel := func(v uint8) int {
+		best := 0
+		for i, level := range levels {
+			if abs(int(v)-level) < abs(int(v)-levels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+
+	r, g, b := nearestLevel(c.R), nearestLevel(c.G), nearestLevel(c.B)
+	cube := color.NRGBA{R: uint8(levels[r]), G: uint8(levels[g]), B: uint8(levels[b]), A: 0xff}
+
+	gray := (int(c.R) + int(c.G) + int(c.B)) / 3
+	grayIndex := min(max((gray-3)/10, 0), 23)
+	grayLevel := uint8(8 + grayIndex*10)
+
+	if colorDistance(c, color.NRGBA{R: grayLevel, G: grayLevel, B: grayLevel, A: 0xff}) < colorDistance(c, cube) {
+		return 232 + grayIndex
+	}
+	return 16 + r*36 + g*6 + b
+}
+
+// nearestPaletteIndex returns the palette entry closest to c.
+func nearestPaletteIndex(c color.NRGBA, palette []color.NRGBA) int {
+	best, bestDistance := 0, -1
+	for i, p := range palette {
+		if d := colorDistance(c, p); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+// colorDistance is a squared RGB distance weighted for perceived brightness.
+func colorDistance(a, b color.NRGBA) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return 3*dr*dr + 4*dg*dg + 2*db*db
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func lineEnding(opt TextExportOptions) string {
+	if opt.LineEnding == "" {
+		return LineEndingLF
+	}
+	return opt.LineEnding
+}
+
+// ASCIIToANSI writes c as an .ans file with SGR color codes.
+func ASCIIToANSI(c *canvas.Canvas, outPath string, opt TextExportOptions) error {
+	return writeStringFile(outPath, ASCIIToANSIText(c, opt))
+}
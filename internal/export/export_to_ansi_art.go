@@ -0,0 +1,172 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+const (
+	// DefaultANSIArtFont is the SAUCE font name used when ANSIArtOptions.Font is empty.
+	DefaultANSIArtFont = "IBM VGA"
+
+	// Default ANSI art colors, light gray on black.
+	ansiArtDefaultFG = 7
+	ansiArtDefaultBG = 0
+
+	// ansiArtViewerWidth is the column count ANSI art viewers wrap at.
+	ansiArtViewerWidth = 80
+
+	sauceRecordSize = 128
+	sauceEOF        = 0x1a
+)
+
+// ANSIArtOptions holds the SAUCE metadata and color mode of an ANSI art export.
+type ANSIArtOptions struct {
+	Title  string
+	Author string
+	Group  string
+	// Font is the SAUCE font name viewers render with, empty means DefaultANSIArtFont.
+	Font string
+	// ICEColors uses blink as bright background, giving 16 background colors.
+	// Without it backgrounds are limited to the 8 dark colors.
+	ICEColors bool
+	// Date is stored in the SAUCE record, zero means now.
+	Date time.Time
+}
+
+// ASCIIToANSIArt writes c as a CP437 .ans file with 16 color SGR codes, followed by a SAUCE record.
+func ASCIIToANSIArt(c *canvas.Canvas, outPath string, opt ANSIArtOptions) error {
+	data, err := encodeANSIArt(c, opt)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, data, 0o644)
+}
+
+func encodeANSIArt(c *canvas.Canvas, opt ANSIArtOptions) ([]byte, error) {
+	if c == nil {
+		return nil, fmt.Errorf("no canvas to export")
+	}
+	if c.Width() > 0xffff || c.Height() > 0xffff {
+		return nil, fmt.Errorf("canvas %dx%d is too large for a SAUCE record", c.Width(), c.Height())
+	}
+
+	var buf bytes.Buffer
+	fg, bg := ansiArtDefaultFG, ansiArtDefaultBG
+	buf.WriteString("\x1b[0m")
+	for y := 0; y < c.Height(); y++ {
+		row := c.Row(y)
+		// Trailing blanks on the default background are left to the viewer.
+		for len(row) > 0 {
+			last := row[len(row)-1]
+			if toCP437(last.Rune) != ' ' || ansiArtBG(last, opt.ICEColors) != ansiArtDefaultBG {
+				break
+			}
+			row = row[:len(row)-1]
+		}
+
+		for _, cell := range row {
+			cellFG, cellBG := ansiArtFG(cell), ansiArtBG(cell, opt.ICEColors)
+			if cellFG != fg || cellBG != bg {
+				buf.WriteString(ansiArtSGR(cellFG, cellBG))
+				fg, bg = cellFG, cellBG
+			}
+			buf.WriteByte(toCP437(cell.Rune))
+		}
+
+		// Viewers wrap after a full 80 column row on their own, a line break there would leave an empty line.
+		if len(row) < ansiArtViewerWidth || c.Width() != ansiArtViewerWidth {
+			buf.WriteString("\r\n")
+		}
+	}
+	buf.WriteString("\x1b[0m")
+
+	fileSize := buf.Len()
+	buf.WriteByte(sauceEOF)
+	buf.Write(sauceRecord(opt, fileSize, c.Width(), c.Height()))
+	return buf.Bytes(), nil
+}
+
+func ansiArtFG(cell canvas.Cell) int {
+	if cell.FG.A == 0 {
+		return ansiArtDefaultFG
+	}
+	return nearestPaletteIndex(cell.FG, ansi16Palette)
+}
+
+func ansiArtBG(cell canvas.Cell, iceColors bool) int {
+	if cell.BG.A == 0 {
+		return ansiArtDefaultBG
+	}
+	if iceColors {
+		return nearestPaletteIndex(cell.BG, ansi16Palette)
+	}
+	return nearestPaletteIndex(cell.BG, ansi16Palette[:8])
+}
+
+// ansiArtSGR resets and sets both colors, bright foregrounds use bold and bright backgrounds use blink,
+// which is how DOS era viewers address the upper 8 colors.
+func ansiArtSGR(fg, bg int) string {
+	params := []string{"0"}
+	if fg >= 8 {
+		params = append(params, "1")
+	}
+	if bg >= 8 {
+		params = append(params, "5")
+	}
+	params = append(params, strconv.Itoa(30+fg%8), strconv.Itoa(40+bg%8))
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// sauceRecord builds the 128 byte SAUCE 00 record for a character/ANSi file.
+func sauceRecord(opt ANSIArtOptions, fileSize, width, height int) []byte {
+	date := opt.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	font := opt.Font
+	if font == "" {
+		font = DefaultANSIArtFont
+	}
+
+	record := make([]byte, 0, sauceRecordSize)
+	record = append(record, "SAUCE00"...)
+	record = append(record, sauceField(opt.Title, 35, ' ')...)
+	record = append(record, sauceField(opt.Author, 20, ' ')...)
+	record = append(record, sauceField(opt.Group, 20, ' ')...)
+	record = append(record, date.Format("20060102")...)
+	record = binary.LittleEndian.AppendUint32(record, uint32(fileSize))
+	record = append(record, 1, 1) // DataType Character, FileType ANSi.
+	record = binary.LittleEndian.AppendUint16(record, uint16(width))
+	record = binary.LittleEndian.AppendUint16(record, uint16(height))
+	record = binary.LittleEndian.AppendUint16(record, 0)
+	record = binary.LittleEndian.AppendUint16(record, 0)
+	record = append(record, 0) // No comment block.
+
+	var flags byte
+	if opt.ICEColors {
+		flags |= 1
+	}
+	record = append(record, flags)
+	record = append(record, sauceField(font, 22, 0)...)
+	return record
+}
+
+// sauceField encodes s as CP437, cut or padded to size.
+func sauceField(s string, size int, pad byte) []byte {
+	field := encodeCP437(s)
+	if len(field) > size {
+		field = field[:size]
+	}
+	for len(field) < size {
+		field = append(field, pad)
+	}
+	return field
+}
@@ -0,0 +1,214 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	apngSignature = "\x89PNG\r\n\x1a\n"
+
+	apngColorTypeRGBA = 6
+	apngFilterSub     = 1
+)
+
+// ASCIIFramesToAPNG writes frames as a looping animated PNG in full 8-bit RGBA, without the palette
+// reduction of ASCIIFramesToGIF. After the first frame only the region that changed is stored.
+func ASCIIFramesToAPNG(frames []ASCIIGIFFrame, outPath string, opt ASCIIExportOptions) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to export")
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriter(f)
+	pw := &pngChunkWriter{w: w}
+	sequence := uint32(0)
+	var previous *image.RGBA
+
+	err = renderASCIIFrames(frames, opt,
+		func(_ int, img *image.RGBA, fontVars fontVariables) (*image.RGBA, error) {
+			return finishASCIIImage(img, fontVars, opt), nil
+		},
+		func(frameIdx int, img *image.RGBA) error {
+			region := img.Bounds()
+			if previous == nil {
+				var ihdr [13]byte
+				binary.BigEndian.PutUint32(ihdr[0:4], uint32(region.Dx()))
+				binary.BigEndian.PutUint32(ihdr[4:8], uint32(region.Dy()))
+				ihdr[8] = 8
+				ihdr[9] = apngColorTypeRGBA
+
+				var actl [8]byte
+				binary.BigEndian.PutUint32(actl[0:4], uint32(len(frames)))
+
+				pw.bytes([]byte(apngSignature))
+				pw.chunk("IHDR", ihdr[:])
+				pw.chunk("acTL", actl[:])
+			} else {
+				region = getRGBADiffBounds(previous, img)
+				if region.Empty() {
+					region = image.Rect(0, 0, 1, 1)
+				}
+			}
+			previous = img
+
+			num, den := apngDelayFraction(frames[frameIdx].Duration)
+			var fctl [26]byte
+			binary.BigEndian.PutUint32(fctl[0:4], sequence)
+			binary.BigEndian.PutUint32(fctl[4:8], uint32(region.Dx()))
+			binary.BigEndian.PutUint32(fctl[8:12], uint32(region.Dy()))
+			binary.BigEndian.PutUint32(fctl[12:16], uint32(region.Min.X))
+			binary.BigEndian.PutUint32(fctl[16:20], uint32(region.Min.Y))
+			binary.BigEndian.PutUint16(fctl[20:22], num)
+			binary.BigEndian.PutUint16(fctl[22:24], den)
+			// dispose_op NONE and blend_op SOURCE: the region replaces what was there.
+			pw.chunk("fcTL", fctl[:])
+			sequence++
+
+			data, err := encodeAPNGFrameData(img, region)
+			if err != nil {
+				return err
+			}
+			if frameIdx == 0 {
+				pw.chunk("IDAT", data)
+			} else {
+				fdat := make([]byte, 4+len(data))
+				binary.BigEndian.PutUint32(fdat[0:4], sequence)
+				copy(fdat[4:], data)
+				pw.chunk("fdAT", fdat)
+				sequence++
+			}
+			return pw.err
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	pw.chunk("IEND", nil)
+	if pw.err != nil {
+		return pw.err
+	}
+	return w.Flush()
+}
+
+// apngDelayFraction expresses d as delay_num/delay_den seconds, exact to the millisecond when it fits.
+func apngDelayFraction(d time.Duration) (num, den uint16) {
+	d = max(d, minVideoFrameDuration)
+
+	ms := int64(math.Round(float64(d) / float64(time.Millisecond)))
+	g := int64(gcdDuration(time.Duration(ms), 1000))
+	if ms/g <= math.MaxUint16 {
+		return uint16(ms / g), uint16(1000 / g)
+	}
+
+	seconds := int64(math.Round(d.Seconds()))
+	return uint16(min(seconds, math.MaxUint16)), 1
+}
+
+// encodeAPNGFrameData returns the zlib stream of the region scanlines as non-premultiplied RGBA,
+// each row using the Sub filter.
+func encodeAPNGFrameData(img *image.RGBA, region image.Rectangle) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+
+	rowLen := region.Dx() * 4
+	row := make([]byte, 1+rowLen)
+	pixels := make([]byte, rowLen)
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(region.Min.X, y):]
+		for i := 0; i < rowLen; i += 4 {
+			r, g, b, a := src[i], src[i+1], src[i+2], src[i+3]
+			if a != 0xff && a != 0 {
+				r = uint8(uint32(r) * 0xff / uint32(a))
+				g = uint8(uint32(g) * 0xff / uint32(a))
+				b = uint8(uint32(b) * 0xff / uint32(a))
+			}
+			pixels[i], pixels[i+1], pixels[i+2], pixels[i+3] = r, g, b, a
+		}
+
+		row[0] = apngFilterSub
+		for i := 0; i < rowLen; i++ {
+			left := byte(0)
+			if i >= 4 {
+				left = pixels[i-4]
+			}
+			row[1+i] = pixels[i] - left
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func getRGBADiffBounds(previous, current *image.RGBA) image.Rectangle {
+	rec := current.Rect
+	minX, maxX, minY, maxY := rec.Max.X, rec.Min.X-1, rec.Max.Y, rec.Min.Y-1
+
+	for y := rec.Min.Y; y < rec.Max.Y; y++ {
+		previousRow := previous.Pix[previous.PixOffset(rec.Min.X, y):][:rec.Dx()*4]
+		currentRow := current.Pix[current.PixOffset(rec.Min.X, y):][:rec.Dx()*4]
+		if bytes.Equal(previousRow, currentRow) {
+			continue
+		}
+		minY = min(minY, y)
+		maxY = max(maxY, y)
+		for x := 0; x < rec.Dx(); x++ {
+			if !bytes.Equal(previousRow[x*4:x*4+4], currentRow[x*4:x*4+4]) {
+				minX = min(minX, rec.Min.X+x)
+				maxX = max(maxX, rec.Min.X+x)
+			}
+		}
+	}
+	if maxY < minY {
+		return image.Rectangle{}
+	}
+	return image.Rect(minX, minY, maxX+1, maxY+1)
+}
+
+// pngChunkWriter writes length, type, data and CRC of PNG chunks and keeps the first error.
+type pngChunkWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *pngChunkWriter) bytes(b []byte) {
+	if p.err == nil && len(b) > 0 {
+		_, p.err = p.w.Write(b)
+	}
+}
+
+func (p *pngChunkWriter) chunk(typ string, data []byte) {
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(data)))
+	copy(header[4:], typ)
+
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write(header[4:])
+	_, _ = crc.Write(data)
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+
+	p.bytes(header[:])
+	p.bytes(data)
+	p.bytes(sum[:])
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	asciicastClear      = "\x1b[H\x1b[2J"
+	asciicastHideCursor = "\x1b[?25l"
+	asciicastShowCursor = "\x1b[?25h"
+)
+
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// ASCIIFramesToAsciicast writes frames as an asciinema v2 recording. Every frame is one output event
+// that clears the screen and draws the grid with its ANSI colors, at the sum of the previous frame durations.
+// Only ColorDepth is used from opt, rows always end in CRLF since players emulate a raw terminal.
+func ASCIIFramesToAsciicast(frames []ASCIIGIFFrame, outPath string, opt TextExportOptions) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to export")
+	}
+
+	header := asciicastHeader{
+		Version:   2,
+		Timestamp: time.Now().Unix(),
+		Env:       map[string]string{"TERM": "xterm-256color"},
+	}
+	for i, frame := range frames {
+		if frame.Canvas == nil {
+			return fmt.Errorf("frame %d has no canvas", i)
+		}
+		header.Width = max(header.Width, frame.Canvas.Width())
+		header.Height = max(header.Height, frame.Canvas.Height())
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+
+	textOpt := TextExportOptions{LineEnding: LineEndingCRLF, ColorDepth: opt.ColorDepth}
+	var elapsed time.Duration
+	for i, frame := range frames {
+		// The last row ends without a line break so a grid as tall as the terminal does not scroll.
+		text := strings.TrimSuffix(ASCIIToANSIText(frame.Canvas, textOpt), LineEndingCRLF) + ansiReset
+		out := asciicastClear + text
+		if i == 0 {
+			out = asciicastHideCursor + out
+		}
+		if err := enc.Encode([]any{asciicastSeconds(elapsed), "o", out}); err != nil {
+			return err
+		}
+		elapsed += max(frame.Duration, minVideoFrameDuration)
+	}
+
+	// Holds the last frame for its duration, players end the recording at the last event.
+	if err := enc.Encode([]any{asciicastSeconds(elapsed), "o", asciicastShowCursor}); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// asciicastSeconds returns d in seconds rounded to microseconds, the precision asciinema records with.
+func asciicastSeconds(d time.Duration) json.Number {
+	return json.Number(fmt.Sprintf("%.6f", d.Seconds()))
+}
@@ -1,205 +1,576 @@
 package export
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/color"
 	"image/color/palette"
 	"image/draw"
 	"image/gif"
+	"io"
+	"math"
 	"os"
 	"runtime"
 	"sync"
 	"time"
 
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
-	"golang.org/x/image/math/fixed"
 )
 
 type ASCIIGIFFrame struct {
-	FrameRunes  [][]rune
-	Duration    time.Duration
-	FrameColors [][]color.NRGBA
+	Canvas   *canvas.Canvas
+	Duration time.Duration
 }
 
-func ASCIIFramesToGIF(frames []ASCIIGIFFrame, outPath string, opt ASCIIExportOptions) error {
+// GIFExportOptions controls the color reduction of a gif export.
+type GIFExportOptions struct {
+	// Palette is GIFPaletteMedianCut (default), GIFPaletteOctree or GIFPalettePlan9, the fixed palette
+	// of older exports. Adaptive palettes are built from the rendered colors with BG at index 0.
+	Palette string
+	// Dither spreads the quantization error to neighbor pixels with Floyd-Steinberg.
+	Dither bool
+	// LoopCount follows image/gif: 0 loops forever, -1 plays once and n repeats n more times.
+	LoopCount int
+	// DelayPolicy is GIFDelayKeep (default), GIFDelayBrowser or GIFDelayFixedFPS.
+	DelayPolicy string
+	// FPS is the frame rate of GIFDelayFixedFPS.
+	FPS float64
+	// Speed divides every frame duration, 2 plays twice as fast. 0 keeps the durations.
+	Speed float64
+	// CompareSize also encodes the frames stored whole, to report the bytes the optimization saved.
+	// It doubles the encoding time, without it GIFSizeReport.BytesBefore stays 0.
+	CompareSize bool
+}
+
+const (
+	// GIFDelayKeep stores the frame durations in 100ths of a second, at least 1.
+	GIFDelayKeep = "KEEP"
+	// GIFDelayBrowser raises delays below gifBrowserMinDelay to the 100 ms browsers show them for anyway.
+	GIFDelayBrowser = "BROWSER"
+	// GIFDelayFixedFPS gives every frame the same duration, 1/FPS.
+	GIFDelayFixedFPS = "FIXED_FPS"
+)
+
+// GIFDelayPolicies lists the delay policies of the gif export.
+func GIFDelayPolicies() []string {
+	return []string{GIFDelayKeep, GIFDelayBrowser, GIFDelayFixedFPS}
+}
+
+// gifBrowserMinDelay is the smallest delay browsers honor, shorter ones play at gifBrowserDelay.
+const (
+	gifBrowserMinDelay = 2
+	gifBrowserDelay    = 10
+)
+
+// maxGIFDelay is the longest delay a gif frame can store, in 100ths of a second.
+const maxGIFDelay = 65535
+
+// GIFSizeReport compares a gif export with the same frames stored whole, one gif frame per source frame.
+// BytesBefore is only measured with GIFExportOptions.CompareSize.
+type GIFSizeReport struct {
+	FramesBefore int
+	FramesAfter  int
+	BytesBefore  int64
+	BytesAfter   int64
+}
+
+func (r GIFSizeReport) String() string {
+	if r.BytesBefore == 0 {
+		return fmt.Sprintf("%s, %d -> %d frames", formatByteSize(r.BytesAfter), r.FramesBefore, r.FramesAfter)
+	}
+	saved := 100 * float64(r.BytesBefore-r.BytesAfter) / float64(r.BytesBefore)
+	return fmt.Sprintf("%s -> %s (%.0f%% smaller), %d -> %d frames",
+		formatByteSize(r.BytesBefore), formatByteSize(r.BytesAfter), saved, r.FramesBefore, r.FramesAfter)
+}
+
+// ASCIIFramesToGIF writes frames as an animated GIF. Adaptive palettes collect the colors of every frame
+// for a global palette first, frames get a local palette only when it is clearly closer to their colors,
+// and the frames are then mapped to their palettes, see quantizeGIFFrames. The frames are stored as
+// deltas, see optimizeGIFFrames, and the report can compare the file with an unoptimized encoding.
+func ASCIIFramesToGIF(frames []ASCIIGIFFrame, outPath string, opt ASCIIExportOptions, gifOpt GIFExportOptions) (GIFSizeReport, error) {
 	if len(frames) == 0 {
-		return fmt.Errorf("no frames to export")
+		return GIFSizeReport{}, fmt.Errorf("no frames to export")
+	}
+	if opt.BG == nil {
+		opt.BG = color.Black
+	}
+	delays, err := gifDelays(frames, gifOpt)
+	if err != nil {
+		return GIFSizeReport{}, err
 	}
 
-	gifFrames := make([]*image.Paletted, len(frames))
-	delays := make([]int, len(frames))
-	renderer, err := newASCIIRenderer(opt)
+	var sources gifSources
+	if gifOpt.Palette != GIFPalettePlan9 {
+		sources, err = renderGIFSources(frames, opt, gifKeepBytes)
+		if err != nil {
+			return GIFSizeReport{}, err
+		}
+	}
+	framePalettes, globalPalette := gifFramePalettes(sources.histograms, len(frames), opt.BG, gifOpt)
+	gifFrames, err := quantizeGIFFrames(frames, opt, sources, framePalettes, gifOpt.Dither)
 	if err != nil {
-		return err
+		return GIFSizeReport{}, err
 	}
-	defer renderer.Close()
 
-	maxRows := 1
-	maxCols := 1
-	for _, frame := range frames {
-		if len(frame.FrameRunes) > maxRows {
-			maxRows = len(frame.FrameColors)
+	bounds := gifFrames[0].Bounds()
+	config := image.Config{
+		ColorModel: globalPalette,
+		Width:      bounds.Dx(),
+		Height:     bounds.Dy(),
+	}
+
+	report := GIFSizeReport{FramesBefore: len(gifFrames)}
+	if gifOpt.CompareSize {
+		unoptimized := &byteCounter{}
+		err = gif.EncodeAll(unoptimized, &gif.GIF{
+			Image:           gifFrames,
+			Delay:           delays,
+			LoopCount:       gifOpt.LoopCount,
+			Config:          config,
+			BackgroundIndex: gifBackgroundIndex,
+		})
+		if err != nil {
+			return GIFSizeReport{}, err
 		}
-		for _, frameColor := range frame.FrameRunes {
-			if len(frameColor) > maxCols {
-				maxCols = len(frameColor)
+		report.BytesBefore = unoptimized.n
+	}
+
+	gifFrames, delays, disposals := optimizeGIFFrames(gifFrames, delays)
+	report.FramesAfter = len(gifFrames)
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return GIFSizeReport{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	written := &byteCounter{w: f}
+	err = gif.EncodeAll(written, &gif.GIF{
+		Image:           gifFrames,
+		Delay:           delays,
+		Disposal:        disposals,
+		LoopCount:       gifOpt.LoopCount,
+		Config:          config,
+		BackgroundIndex: gifBackgroundIndex,
+	})
+	if err != nil {
+		return GIFSizeReport{}, err
+	}
+	report.BytesAfter = written.n
+	return report, nil
+}
+
+// gifDelays converts the frame durations to gif delays under the delay policy of gifOpt. The durations
+// are rounded on the running total, so a fixed 30 FPS alternates 3 and 4 instead of drifting.
+func gifDelays(frames []ASCIIGIFFrame, gifOpt GIFExportOptions) ([]int, error) {
+	speed := gifOpt.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	if speed < 0 {
+		return nil, fmt.Errorf("gif speed must be positive")
+	}
+	if gifOpt.DelayPolicy == GIFDelayFixedFPS && gifOpt.FPS <= 0 {
+		return nil, fmt.Errorf("gif fps must be positive")
+	}
+
+	delays := make([]int, len(frames))
+	var elapsed time.Duration
+	end := 0
+	for i, frame := range frames {
+		duration := frame.Duration
+		if gifOpt.DelayPolicy == GIFDelayFixedFPS {
+			duration = time.Duration(float64(time.Second) / gifOpt.FPS)
+		}
+		elapsed += time.Duration(float64(duration) / speed)
+
+		start := end
+		end = int(math.Round(float64(elapsed) / float64(10*time.Millisecond)))
+		delay := end - start
+		if gifOpt.DelayPolicy == GIFDelayBrowser && delay < gifBrowserMinDelay {
+			delay = gifBrowserDelay
+		}
+		delays[i] = min(max(delay, 1), maxGIFDelay)
+	}
+	return delays, nil
+}
+
+// optimizeGIFFrames merges runs of identical frames into one frame with their summed delay. Every later
+// frame is cropped to the rectangle that changed, with the unchanged pixels inside it set to the
+// transparent index when its palette has one. Frames are never disposed, so each one draws over the last.
+func optimizeGIFFrames(frames []*image.Paletted, delays []int) ([]*image.Paletted, []int, []byte) {
+	optimized := []*image.Paletted{frames[0]}
+	optimizedDelays := []int{delays[0]}
+	previous := frames[0]
+
+	for i, frame := range frames[1:] {
+		delay := delays[i+1]
+		diffBox := getDiffBounds(previous, frame)
+		if diffBox.Empty() {
+			last := len(optimizedDelays) - 1
+			// Browsers play delays below gifBrowserMinDelay at 100 ms each, merging them would change the timing.
+			merged := optimizedDelays[last] + delay
+			if optimizedDelays[last] >= gifBrowserMinDelay && delay >= gifBrowserMinDelay && merged <= maxGIFDelay {
+				optimizedDelays[last] = merged
+				continue
+			}
+			// The frame can not be merged, keep a 1x1 frame that redraws the same pixel.
+			diffBox = image.Rect(frame.Rect.Min.X, frame.Rect.Min.Y, frame.Rect.Min.X+1, frame.Rect.Min.Y+1)
+		}
+
+		delta := image.NewPaletted(diffBox, frame.Palette)
+		transparent := hasTransparentIndex(frame.Palette)
+		for y := diffBox.Min.Y; y < diffBox.Max.Y; y++ {
+			for x := diffBox.Min.X; x < diffBox.Max.X; x++ {
+				index := frame.ColorIndexAt(x, y)
+				if transparent && sameGIFPixel(previous, frame, x, y) {
+					index = gifTransparentIndex
+				}
+				delta.SetColorIndex(x, y, index)
 			}
 		}
+
+		optimized = append(optimized, delta)
+		optimizedDelays = append(optimizedDelays, delay)
+		previous = frame
+	}
+
+	disposals := make([]byte, len(optimized))
+	for i := range disposals {
+		disposals[i] = gif.DisposalNone
+	}
+	return optimized, optimizedDelays, disposals
+}
+
+// sameGIFPixel reports whether previous and current show the same color at x, y. Indexes are compared
+// directly when both use the same palette.
+func sameGIFPixel(previous, current *image.Paletted, x, y int) bool {
+	a, b := previous.ColorIndexAt(x, y), current.ColorIndexAt(x, y)
+	if samePalette(previous.Palette, current.Palette) {
+		return a == b
+	}
+	return previous.Palette[a] == current.Palette[b]
+}
+
+// byteCounter counts the bytes written through it, w may be nil to only measure.
+type byteCounter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	n := len(p)
+	var err error
+	if c.w != nil {
+		n, err = c.w.Write(p)
+	}
+	c.n += int64(n)
+	return n, err
+}
+
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
 	}
+}
 
-	d := &font.Drawer{Face: renderer.face}
+// gifKeepBytes bounds the finished RGBA frames the palette pass keeps for mapping, later frames are
+// rendered again instead of holding 4 bytes per pixel of the whole animation.
+const gifKeepBytes = 256 << 20
 
-	metrics := renderer.face.Metrics()
-	lineH := metrics.Height.Round()
-	cellW := d.MeasureString("M").Ceil()
+// gifSources is the result of the palette pass: the color histogram of every frame, and the finished
+// frames kept for mapping. A nil frame is rendered again.
+type gifSources struct {
+	histograms []colorHistogram
+	kept       []*image.RGBA
+}
+
+// renderGIFSources rasterizes and finishes every frame for its color histogram, keeping finished frames
+// in frame order while they fit in keepBytes.
+func renderGIFSources(frames []ASCIIGIFFrame, opt ASCIIExportOptions, keepBytes int) (gifSources, error) {
+	type gifSource struct {
+		img  *image.RGBA
+		hist colorHistogram
+	}
 
-	if cellW < 1 {
-		cellW = 1
+	sources := gifSources{
+		histograms: make([]colorHistogram, len(frames)),
+		kept:       make([]*image.RGBA, len(frames)),
 	}
-	if lineH < 1 {
-		lineH = 1
+	keeping := true
+	err := renderASCIIFrames(frames, opt,
+		func(_ int, img *image.RGBA, fontVars fontVariables) (gifSource, error) {
+			img = finishASCIIImage(img, fontVars, opt)
+			return gifSource{img: img, hist: histogramRGBA(img, opt.BG)}, nil
+		},
+		func(frameIdx int, source gifSource) error {
+			sources.histograms[frameIdx] = source.hist
+			if keeping = keeping && len(source.img.Pix) <= keepBytes; keeping {
+				sources.kept[frameIdx] = source.img
+				keepBytes -= len(source.img.Pix)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return gifSources{}, err
 	}
+	return sources, nil
+}
 
-	fontVars := fontVariables{
-		width:  maxCols * cellW,
-		height: maxRows * lineH,
-		ascent: metrics.Ascent.Ceil(),
-		lineH:  lineH,
-		cellW:  cellW,
+// gifFramePalettes picks the palette of each of the frameCount frames from the frame histograms. Frames
+// on the global palette share its slice, which the encoder writes once as the global color table.
+func gifFramePalettes(histograms []colorHistogram, frameCount int, bg color.Color, gifOpt GIFExportOptions) ([]color.Palette, color.Palette) {
+	framePalettes := make([]color.Palette, frameCount)
+	if gifOpt.Palette == GIFPalettePlan9 {
+		for i := range framePalettes {
+			framePalettes[i] = palette.Plan9
+		}
+		return framePalettes, palette.Plan9
 	}
 
-	workers := min(4, runtime.GOMAXPROCS(0), len(frames))
-	if workers < 1 {
-		workers = 1
+	all := make(colorHistogram)
+	for _, hist := range histograms {
+		all.merge(hist)
+		all = all.capped()
 	}
-	if workers > len(frames) {
-		workers = len(frames)
+	globalPalette := buildGIFPalette(bg, all.colors(), gifOpt.Palette)
+
+	for i, hist := range histograms {
+		framePalettes[i] = globalPalette
+		if len(histograms) == 1 {
+			continue
+		}
+		colors := hist.colors()
+		localPalette := buildGIFPalette(bg, colors, gifOpt.Palette)
+		if paletteError(colors, localPalette) < localPaletteGain*paletteError(colors, globalPalette) {
+			framePalettes[i] = localPalette
+		}
 	}
+	return framePalettes, globalPalette
+}
 
-	jobs := make(chan int, len(frames))
-	var wg sync.WaitGroup
-	var errOnce sync.Once
-	var firstErr error
+// quantizeGIFFrames maps every frame to its palette. Frames kept by the palette pass are mapped on up to
+// 4 workers and released, the others are rendered again and mapped as they are finished.
+func quantizeGIFFrames(frames []ASCIIGIFFrame, opt ASCIIExportOptions, sources gifSources, framePalettes []color.Palette, dither bool) ([]*image.Paletted, error) {
+	var drawer draw.Drawer = draw.Src
+	if dither {
+		drawer = draw.FloydSteinberg
+	}
 
-	setErr := func(err error) {
-		errOnce.Do(func() {
-			firstErr = err
-		})
+	gifFrames := make([]*image.Paletted, len(frames))
+	var kept, missing []int
+	for i := range frames {
+		if i < len(sources.kept) && sources.kept[i] != nil {
+			kept = append(kept, i)
+		} else {
+			missing = append(missing, i)
+		}
 	}
 
+	workers := min(4, runtime.GOMAXPROCS(0), len(kept))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
 	for i := 0; i < workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-
-			r, err := newASCIIRenderer(opt)
-			if err != nil {
-				setErr(err)
-				return
-			}
-			defer r.Close()
-
 			for frameIdx := range jobs {
-				img, err := r.RenderFrame(frames[frameIdx], opt.RenderColor, fontVars)
-				if err != nil {
-					setErr(err)
-					continue
-				}
-
-				canvas := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
-				draw.Draw(canvas, canvas.Bounds(), &image.Uniform{C: opt.BG}, image.Point{}, draw.Src)
-				draw.Draw(canvas, image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()), img, image.Point{}, draw.Over)
-
-				paletted := image.NewPaletted(canvas.Bounds(), palette.Plan9)
-				draw.Draw(paletted, image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()), canvas, image.Point{}, draw.Over)
-				gifFrames[frameIdx] = paletted
-
-				delay := int(frames[frameIdx].Duration / (10 * time.Millisecond))
-				if delay < 1 {
-					delay = 1
-				}
-				delays[frameIdx] = delay
+				gifFrames[frameIdx] = mapGIFFrame(sources.kept[frameIdx], framePalettes[frameIdx], drawer)
+				sources.kept[frameIdx] = nil
 			}
 		}()
 	}
-
-	for i := range frames {
-		jobs <- i
+	for _, frameIdx := range kept {
+		jobs <- frameIdx
 	}
 	close(jobs)
 	wg.Wait()
 
-	if firstErr != nil {
-		return firstErr
+	if len(missing) == 0 {
+		return gifFrames, nil
 	}
+	err := renderASCIIFrameSubset(frames, missing, opt,
+		func(frameIdx int, img *image.RGBA, fontVars fontVariables) (*image.Paletted, error) {
+			return mapGIFFrame(finishASCIIImage(img, fontVars, opt), framePalettes[frameIdx], drawer), nil
+		},
+		func(frameIdx int, paletted *image.Paletted) error {
+			gifFrames[frameIdx] = paletted
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return gifFrames, nil
+}
 
-	//reduce gif size
-	previousFrame := gifFrames[0]
-	for i, frame := range gifFrames {
-		if i == 0 {
-			continue
+// mapGIFFrame maps img to framePalette. Dithering runs on the opaque palette, so the transparent index
+// is never picked for a pixel.
+func mapGIFFrame(img *image.RGBA, framePalette color.Palette, drawer draw.Drawer) *image.Paletted {
+	paletted := image.NewPaletted(img.Bounds(), opaqueGIFPalette(framePalette))
+	drawer.Draw(paletted, img.Bounds(), img, image.Point{})
+	paletted.Palette = framePalette
+	return paletted
+}
+
+func samePalette(a, b color.Palette) bool {
+	return len(a) == len(b) && len(a) > 0 && &a[0] == &b[0]
+}
+
+type preparedFrame[T any] struct {
+	value T
+	err   error
+}
+
+// renderASCIIFrames rasterizes every frame on a shared grid sized to the largest frame.
+// Rasterizing and prepare run on up to 4 workers, each with its own face on the shared font and
+// glyph atlas. handle is called
+// from the calling goroutine in frame order, with a bounded number of frames prepared ahead.
+func renderASCIIFrames[T any](
+	frames []ASCIIGIFFrame,
+	opt ASCIIExportOptions,
+	prepare func(frameIdx int, img *image.RGBA, fontVars fontVariables) (T, error),
+	handle func(frameIdx int, value T) error,
+) error {
+	indexes := make([]int, len(frames))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return renderASCIIFrameSubset(frames, indexes, opt, prepare, handle)
+}
+
+// renderASCIIFrameSubset is renderASCIIFrames for the frames at indexes, handled in the order of indexes.
+// The grid is still sized to the largest of all frames, so the images match the other frames.
+func renderASCIIFrameSubset[T any](
+	frames []ASCIIGIFFrame,
+	indexes []int,
+	opt ASCIIExportOptions,
+	prepare func(frameIdx int, img *image.RGBA, fontVars fontVariables) (T, error),
+	handle func(frameIdx int, value T) error,
+) error {
+	maxRows := 1
+	maxCols := 1
+	for i, frame := range frames {
+		if frame.Canvas == nil {
+			return fmt.Errorf("frame %d has no canvas", i)
 		}
-		diffBox := getDiffBounds(previousFrame, frame)
-		if diffBox.Empty() {
-			gifFrames[i] = image.NewPaletted(image.Rect(0, 0, 1, 1), frame.Palette)
-			continue
+		maxRows = max(maxRows, frame.Canvas.Height())
+		maxCols = max(maxCols, frame.Canvas.Width())
+	}
+
+	workers := min(4, runtime.GOMAXPROCS(0), len(indexes))
+	if workers < 1 {
+		workers = 1
+	}
+
+	exportFont, err := loadASCIIFont(opt)
+	if err != nil {
+		return err
+	}
+	renderers := make([]*asciiRenderer, 0, workers)
+	defer func() {
+		for _, r := range renderers {
+			r.Close()
 		}
-		if diffBox.Dx()*diffBox.Dy() > frame.Rect.Dx()*frame.Rect.Dy() {
-			continue
+	}()
+	for i := 0; i < workers; i++ {
+		r, err := exportFont.newRenderer()
+		if err != nil {
+			return err
 		}
+		renderers = append(renderers, r)
+	}
+
+	fontVars := renderers[0].fontVariables(maxCols, maxRows)
 
-		croppedPallete := image.NewPaletted(diffBox, frame.Palette)
-		r := croppedPallete.Rect
-		for y := r.Min.Y; y < r.Max.Y; y++ {
-			srcOff := (y-frame.Rect.Min.Y)*frame.Stride + (r.Min.X - frame.Rect.Min.X)
-			dstOff := (y-croppedPallete.Rect.Min.Y)*croppedPallete.Stride + (r.Min.X - croppedPallete.Rect.Min.X)
-			copy(croppedPallete.Pix[dstOff:dstOff+r.Dx()], frame.Pix[srcOff:srcOff+r.Dx()])
+	results := make([]chan preparedFrame[T], len(indexes))
+	for i := range results {
+		results[i] = make(chan preparedFrame[T], 1)
+	}
+	jobs := make(chan int)
+	done := make(chan struct{})
+	inFlight := make(chan struct{}, 2*workers)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer close(done)
+
+	go func() {
+		defer close(jobs)
+		for i := range indexes {
+			select {
+			case inFlight <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
 		}
-		gifFrames[i] = croppedPallete
+	}()
+
+	for _, r := range renderers {
+		wg.Add(1)
+		go func(r *asciiRenderer) {
+			defer wg.Done()
+			for i := range jobs {
+				frameIdx := indexes[i]
+				value, err := prepare(frameIdx, r.RenderFrame(frames[frameIdx].Canvas, fontVars), fontVars)
+				results[i] <- preparedFrame[T]{value: value, err: err}
+			}
+		}(r)
 	}
 
-	f, err := os.Create(outPath)
-	if err != nil {
-		return err
+	for i, frameIdx := range indexes {
+		result := <-results[i]
+		<-inFlight
+		if result.err != nil {
+			return result.err
+		}
+		if err := handle(frameIdx, result.value); err != nil {
+			return err
+		}
 	}
-	defer func() { _ = f.Close() }()
 
-	return gif.EncodeAll(f, &gif.GIF{
-		Image: gifFrames,
-		Delay: delays,
-	})
+	return nil
 }
 
+// getDiffBounds returns the smallest rectangle holding every pixel whose color differs between previous
+// and current, empty when the frames look the same.
 func getDiffBounds(previous, current *image.Paletted) image.Rectangle {
 	rec := previous.Rect
 	minX, maxX, minY, maxY := -1, -1, -1, -1
 
+	indexesComparable := samePalette(previous.Palette, current.Palette)
 	for y := rec.Min.Y; y < rec.Max.Y; y++ {
-		previousRow := (y - rec.Min.Y) * previous.Stride
-		currentRow := (y - rec.Min.Y) * previous.Stride
+		if indexesComparable {
+			row := (y - rec.Min.Y) * previous.Stride
+			if bytes.Equal(previous.Pix[row:row+rec.Dx()], current.Pix[row:row+rec.Dx()]) {
+				continue
+			}
+		}
 		for x := rec.Min.X; x < rec.Max.X; x++ {
-			i := x - rec.Min.X
-			if previous.Pix[previousRow+i] != current.Pix[currentRow+i] {
-				if minX == -1 {
-					minX, maxX = x, x
-					minY, maxY = y, y
-				} else {
-					if x < minX {
-						minX = x
-					}
-					if x > maxX {
-						maxX = x
-					}
-					if y < minY {
-						minY = y
-					}
-					if y > maxY {
-						maxY = y
-					}
-				}
+			if sameGIFPixel(previous, current, x, y) {
+				continue
 			}
+			if minX == -1 {
+				minX, maxX = x, x
+				minY, maxY = y, y
+				continue
+			}
+			minX, maxX = min(minX, x), max(maxX, x)
+			minY, maxY = min(minY, y), max(maxY, y)
 		}
 	}
 	if maxX == -1 {
@@ -208,9 +579,20 @@ func getDiffBounds(previous, current *image.Paletted) image.Rectangle {
 	return image.Rect(minX, minY, maxX+1, maxY+1)
 }
 
+// asciiFont is the parsed font chain of an export and the glyph atlas of its size, shared by the renderers
+// of an export. The first font of the chain sets the cell metrics.
+type asciiFont struct {
+	opt   ASCIIExportOptions
+	chain []*opentype.Font
+	atlas *glyphAtlas
+}
+
 type asciiRenderer struct {
-	opt  ASCIIExportOptions
-	face font.Face
+	opt ASCIIExportOptions
+	// face is the export font, faces holds a face for every font of the chain with face first.
+	face  font.Face
+	faces []font.Face
+	atlas *glyphAtlas
 }
 
 type fontVariables struct {
@@ -219,9 +601,13 @@ type fontVariables struct {
 	lineH  int
 	ascent int
 	cellW  int
+	// offsetX centers glyphs in cells wider or narrower than their advance.
+	offsetX int
+	// exact is set when the cells already have their target shape and need no aspect correction.
+	exact bool
 }
 
-func newASCIIRenderer(opt ASCIIExportOptions) (*asciiRenderer, error) {
+func loadASCIIFont(opt ASCIIExportOptions) (*asciiFont, error) {
 	if opt.DPI <= 0 {
 		opt.DPI = 72
 	}
@@ -235,63 +621,115 @@ func newASCIIRenderer(opt ASCIIExportOptions) (*asciiRenderer, error) {
 		opt.FG = color.White
 	}
 
-	fontBytes, err := loadExportFontBytes(opt.FontTTFPath)
-	if err != nil {
-		return nil, err
+	if opt.FitWidthToAspect {
+		width, err := fitFontWidth(opt)
+		if err != nil {
+			return nil, err
+		}
+		if width > 0 {
+			opt.FontWidth = width
+		}
 	}
 
-	tt, err := opentype.Parse(fontBytes)
+	chain, err := loadFontChain(opt)
 	if err != nil {
 		return nil, err
 	}
 
-	face, err := opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    float64(opt.FontSize),
-		DPI:     float64(opt.DPI),
-		Hinting: font.HintingFull,
-	})
+	return &asciiFont{
+		opt:   opt,
+		chain: chain,
+		atlas: newGlyphAtlas(chain),
+	}, nil
+}
+
+// newRenderer opens faces of the font chain. Faces are not safe for concurrent use, so every worker gets its own.
+func (f *asciiFont) newRenderer() (*asciiRenderer, error) {
+	r := &asciiRenderer{opt: f.opt, atlas: f.atlas}
+	for _, tt := range f.chain {
+		face, err := opentype.NewFace(tt, &opentype.FaceOptions{
+			Size:    float64(f.opt.FontSize),
+			DPI:     float64(f.opt.DPI),
+			Hinting: font.HintingFull,
+		})
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.faces = append(r.faces, face)
+	}
+	r.face = r.faces[0]
+	return r, nil
+}
+
+func newASCIIRenderer(opt ASCIIExportOptions) (*asciiRenderer, error) {
+	exportFont, err := loadASCIIFont(opt)
 	if err != nil {
 		return nil, err
 	}
-
-	return &asciiRenderer{
-		opt:  opt,
-		face: face,
-	}, nil
+	return exportFont.newRenderer()
 }
 
 func (r *asciiRenderer) Close() {
-	if r.face != nil {
-		_ = r.face.Close()
+	for _, face := range r.faces {
+		_ = face.Close()
 	}
 }
 
-func (r *asciiRenderer) RenderFrame(frame ASCIIGIFFrame, renderColor bool, fontVars fontVariables) (*image.RGBA, error) {
-	img := image.NewRGBA(image.Rect(0, 0, fontVars.width, fontVars.height))
-	draw.Draw(img, img.Bounds(), &image.Uniform{C: r.opt.BG}, image.Point{}, draw.Src)
+// fontVariables measures the cell geometry of the face for a cols x rows grid, with the cell size and
+// line spacing of the options.
+func (r *asciiRenderer) fontVariables(cols, rows int) fontVariables {
+	d := &font.Drawer{Face: r.face}
+
+	lineH, ascent := cellHeight(r.face.Metrics(), r.opt)
+	advance := max(1, d.MeasureString("M").Ceil())
+
+	cellW, exact := advance, false
+	switch {
+	case r.opt.CellWidth > 0:
+		cellW, exact = r.opt.CellWidth, true
+	case r.opt.FitWidthToAspect && r.opt.TargetAspect > 0:
+		// The fitted width leaves the advance at most a pixel short of the target, unless the axis ran out.
+		if target := int(math.Round(r.opt.TargetAspect * float64(lineH))); target >= advance {
+			cellW, exact = target, true
+		}
+	}
 
-	d := &font.Drawer{
-		Dst:  img,
-		Src:  image.NewUniform(r.opt.FG),
-		Face: r.face,
+	return fontVariables{
+		width:   max(1, cols) * cellW,
+		height:  max(1, rows) * lineH,
+		ascent:  ascent,
+		lineH:   lineH,
+		cellW:   cellW,
+		offsetX: (cellW - advance) / 2,
+		exact:   exact,
 	}
+}
 
-	if !renderColor {
-		for y, row := range frame.FrameRunes {
-			baselineY := y*fontVars.lineH + fontVars.ascent
-			d.Dot = fixed.P(0, baselineY)
-			d.DrawString(string(row))
-		}
-	} else {
-		for y, row := range frame.FrameRunes {
-			baselineY := y*fontVars.lineH + fontVars.ascent
-			for x, r := range row {
-				d.Src = image.NewUniform(frame.FrameColors[y][x])
-				d.Dot = fixed.P(x*fontVars.cellW, baselineY)
-				d.DrawString(string(r))
-			}
-		}
+// cellHeight returns the line height and baseline of a cell for the font metrics. CellHeight or
+// LineSpacing of opt grow or shrink the line evenly above and below the glyphs.
+func cellHeight(metrics font.Metrics, opt ASCIIExportOptions) (lineH, ascent int) {
+	fontH := max(1, metrics.Height.Round())
+	lineH = fontH
+	switch {
+	case opt.CellHeight > 0:
+		lineH = opt.CellHeight
+	case opt.LineSpacing > 0:
+		lineH = max(1, int(math.Round(float64(fontH)*opt.LineSpacing)))
 	}
+	return lineH, metrics.Ascent.Ceil() + (lineH-fontH)/2
+}
 
-	return img, nil
+func (r *asciiRenderer) RenderFrame(c *canvas.Canvas, fontVars fontVariables) *image.RGBA {
+	drawer := atlasDrawer{faces: r.faces, atlas: r.atlas, offset: image.Pt(fontVars.offsetX, 0)}
+	return c.Image(drawer, canvas.ImageOptions{
+		CellWidth:     fontVars.cellW,
+		LineHeight:    fontVars.lineH,
+		Ascent:        fontVars.ascent,
+		Width:         fontVars.width,
+		Height:        fontVars.height,
+		FG:            r.opt.FG,
+		BG:            r.opt.BG,
+		UseCellColors: r.opt.RenderColor,
+	})
 }
@@ -0,0 +1,149 @@
+package export
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+const (
+	HTMLThemeDark  = "dark"
+	HTMLThemeLight = "light"
+	HTMLThemeAuto  = "auto"
+
+	defaultHTMLLineHeight = 1.2
+)
+
+type HTMLExportOptions struct {
+	// Fragment writes only a <pre> element with inline styles, for pasting into wikis and existing pages.
+	Fragment bool
+	// FontAspect is the cell height/width ratio the render was made for. Line height is tuned so cells keep
+	// that shape with the export font, 0 uses a plain 1.2 line height.
+	FontAspect float64
+	// Theme is HTMLThemeDark (BG and FG as given), HTMLThemeLight (swapped) or HTMLThemeAuto, which follows
+	// prefers-color-scheme. Fragments cannot follow the scheme and use dark for auto.
+	Theme string
+}
+
+// ASCIIToHTML writes c as preformatted text with a span per merged color run, so the render stays selectable
+// and copyable. Cell colors are only used with RenderColor, the page colors come from BG, FG and the theme.
+func ASCIIToHTML(c *canvas.Canvas, outPath string, opt ASCIIExportOptions, htmlOpt HTMLExportOptions) error {
+	if c == nil {
+		return fmt.Errorf("no canvas to export")
+	}
+
+	lineHeight, err := htmlLineHeight(opt, htmlOpt.FontAspect)
+	if err != nil {
+		return err
+	}
+
+	fontFamily := opt.FontFamily
+	if fontFamily == "" {
+		fontFamily = defaultExportFontFamily
+	}
+	fontSize := opt.FontSize
+	if fontSize <= 0 {
+		fontSize = 14
+	}
+
+	dark := [2]string{cssColor(exportBG(opt)), cssColor(exportFG(opt))}
+	light := [2]string{dark[1], dark[0]}
+	colors := dark
+	if htmlOpt.Theme == HTMLThemeLight {
+		colors = light
+	}
+	preStyle := fmt.Sprintf("margin:0;padding:1em;font-family:%s;font-size:%dpx;line-height:%s;white-space:pre",
+		fontFamily, fontSize, formatSVGNumber(lineHeight))
+
+	var sb strings.Builder
+	if htmlOpt.Fragment {
+		fmt.Fprintf(&sb, `<pre style="%s;background:%s;color:%s">`, strings.ReplaceAll(preStyle, `"`, "&quot;"), colors[0], colors[1])
+		writeHTMLRows(&sb, c, opt)
+		sb.WriteString("</pre>\n")
+		return writeStringFile(outPath, sb.String())
+	}
+
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	switch htmlOpt.Theme {
+	case HTMLThemeAuto:
+		sb.WriteString("<meta name=\"color-scheme\" content=\"dark light\">\n")
+	case HTMLThemeLight:
+		sb.WriteString("<meta name=\"color-scheme\" content=\"light\">\n")
+	default:
+		sb.WriteString("<meta name=\"color-scheme\" content=\"dark\">\n")
+	}
+	sb.WriteString("<title>Mezzotone</title>\n<style>\n")
+	if opt.EmbedFont {
+		fontFace, err := embeddedFontFace([]*canvas.Canvas{c}, opt.FontTTFPath)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(fontFace + "\n")
+		preStyle = strings.Replace(preStyle, "font-family:", "font-family:'"+embeddedFontFamily+"', ", 1)
+	}
+
+	fmt.Fprintf(&sb, ":root{--bg:%s;--fg:%s}\n", colors[0], colors[1])
+	if htmlOpt.Theme == HTMLThemeAuto {
+		fmt.Fprintf(&sb, "@media (prefers-color-scheme: light){:root{--bg:%s;--fg:%s}}\n", light[0], light[1])
+	}
+	sb.WriteString("body{margin:0;background:var(--bg);color:var(--fg)}\n")
+	fmt.Fprintf(&sb, ".mezzotone{%s}\n", preStyle)
+	sb.WriteString("</style>\n</head>\n<body>\n<pre class=\"mezzotone\">")
+	writeHTMLRows(&sb, c, opt)
+	sb.WriteString("</pre>\n</body>\n</html>\n")
+
+	return writeStringFile(outPath, sb.String())
+}
+
+// htmlLineHeight returns the CSS line height that gives cells a height/width ratio of fontAspect
+// with the advance of the export font.
+func htmlLineHeight(opt ASCIIExportOptions, fontAspect float64) (float64, error) {
+	if fontAspect <= 0 {
+		return defaultHTMLLineHeight, nil
+	}
+
+	renderer, err := newASCIIRenderer(opt.vectorOptions())
+	if err != nil {
+		return 0, err
+	}
+	defer renderer.Close()
+
+	fontVars := renderer.fontVariables(1, 1)
+	fontSizePx := float64(renderer.opt.FontSize) * float64(renderer.opt.DPI) / 72
+	return fontAspect * float64(fontVars.cellW) / fontSizePx, nil
+}
+
+// writeHTMLRows writes the rows of c as escaped text, runs that differ from the page colors get a span.
+func writeHTMLRows(sb *strings.Builder, c *canvas.Canvas, opt ASCIIExportOptions) {
+	fg := exportFG(opt)
+	defaultFG := color.NRGBAModel.Convert(fg).(color.NRGBA)
+	for y := 0; y < c.Height(); y++ {
+		for _, run := range rowRuns(c, y, fg, opt.RenderColor) {
+			var style []string
+			if run.fg != defaultFG {
+				style = append(style, "color:"+cssColor(run.fg))
+			}
+			if run.bg.A > 0 {
+				style = append(style, "background:"+cssColor(run.bg))
+			}
+			if run.attrs&canvas.AttrBold != 0 {
+				style = append(style, "font-weight:bold")
+			}
+			if run.attrs&canvas.AttrUnderline != 0 {
+				style = append(style, "text-decoration:underline")
+			}
+
+			text := escapeMarkup(run.text)
+			if len(style) == 0 {
+				sb.WriteString(text)
+				continue
+			}
+			sb.WriteString(`<span style="` + strings.Join(style, ";") + `">` + text + "</span>")
+		}
+		if y < c.Height()-1 {
+			sb.WriteByte('\n')
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// pdfPaperSizes are portrait page sizes in points.
+var pdfPaperSizes = map[string][2]float64{
+	"A3":      {841.89, 1190.55},
+	"A4":      {595.28, 841.89},
+	"A5":      {419.53, 595.28},
+	"Letter":  {612, 792},
+	"Legal":   {612, 1008},
+	"Tabloid": {792, 1224},
+}
+
+// PDFPaperSizes lists the paper sizes of the pdf export.
+func PDFPaperSizes() []string {
+	return []string{"A3", "A4", "A5", "Letter", "Legal", "Tabloid"}
+}
+
+type PDFExportOptions struct {
+	// PaperSize is one of A3, A4, A5, Letter, Legal or Tabloid. The grid is scaled to fit the page
+	// inside the margins and centered. Empty sizes the page to the grid.
+	PaperSize string
+	Landscape bool
+	// Margin is the space around the grid in points.
+	Margin float64
+}
+
+// pdfFont is the subset export font and the metrics the page layout is built from, in 1/1000 em.
+type pdfFont struct {
+	subset     *fontSubset
+	postScript string
+	advance    int
+	ascent     float64
+	descent    float64
+	lineHeight float64
+	bbox       [4]int
+}
+
+// ASCIIToPDF writes c as a single page vector PDF. The used glyphs of the export font are embedded as a
+// TrueType subset, every row is one line of text with color changes between runs, so the output stays sharp
+// at any print size and the text can be selected and searched.
+// FontSize is in points, DPI is not used.
+func ASCIIToPDF(c *canvas.Canvas, outPath string, opt ASCIIExportOptions, pdfOpt PDFExportOptions) error {
+	if c == nil {
+		return fmt.Errorf("no canvas to export")
+	}
+	if pdfOpt.Margin < 0 {
+		return fmt.Errorf("pdf margin must not be negative")
+	}
+
+	fontBytes, err := loadExportFontBytes(opt.FontTTFPath)
+	if err != nil {
+		return err
+	}
+	pf, err := newPDFFont(fontBytes, canvasRunes(c))
+	if err != nil {
+		return err
+	}
+
+	fontSize := float64(opt.FontSize)
+	if fontSize <= 0 {
+		fontSize = 14
+	}
+	cellW := float64(pf.advance) * fontSize / 1000
+	lineH := pf.lineHeight * fontSize / 1000
+	ascent := pf.ascent * fontSize / 1000
+
+	scaleX := 1.0
+	if opt.TargetAspect > 0 {
+		if s := opt.TargetAspect / (cellW / lineH); s > 0.01 && s < 100 {
+			scaleX = s
+		}
+	}
+	gridW, gridH := float64(max(1, c.Width()))*cellW*scaleX, float64(max(1, c.Height()))*lineH
+
+	pageW, pageH := gridW+2*pdfOpt.Margin, gridH+2*pdfOpt.Margin
+	scale, offsetX, offsetY := 1.0, pdfOpt.Margin, pdfOpt.Margin
+	if pdfOpt.PaperSize != "" {
+		paper, ok := pdfPaperSizes[pdfOpt.PaperSize]
+		if !ok {
+			return fmt.Errorf("unknown paper size: %s", pdfOpt.PaperSize)
+		}
+		pageW, pageH = paper[0], paper[1]
+		if pdfOpt.Landscape {
+			pageW, pageH = pageH, pageW
+		}
+		innerW, innerH := pageW-2*pdfOpt.Margin, pageH-2*pdfOpt.Margin
+		if innerW <= 0 || innerH <= 0 {
+			return fmt.Errorf("pdf margin leaves no room on %s paper", pdfOpt.PaperSize)
+		}
+		scale = math.Min(innerW/gridW, innerH/gridH)
+		offsetX = (pageW - gridW*scale) / 2
+		offsetY = (pageH - gridH*scale) / 2
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "%s rg 0 0 %s %s re f\n", pdfColor(exportBG(opt)), pdfNumber(pageW), pdfNumber(pageH))
+	// Cell coordinates from here on, y grows down from the top of the grid.
+	fmt.Fprintf(&content, "%s 0 0 %s %s %s cm\n",
+		pdfNumber(scale*scaleX), pdfNumber(-scale), pdfNumber(offsetX), pdfNumber(offsetY+gridH*scale))
+
+	fg := exportFG(opt)
+	rows := make([][]styledRun, c.Height())
+	for y := range rows {
+		rows[y] = rowRuns(c, y, fg, opt.RenderColor)
+	}
+
+	for y, runs := range rows {
+		for i := 0; i < len(runs); i++ {
+			if runs[i].bg.A == 0 {
+				continue
+			}
+			start, end := runs[i].start, runs[i].start+len(runs[i].text)
+			for i+1 < len(runs) && runs[i+1].bg == runs[i].bg {
+				i++
+				end = runs[i].start + len(runs[i].text)
+			}
+			fmt.Fprintf(&content, "%s rg %s %s %s %s re f\n", pdfColor(runs[i].bg),
+				pdfNumber(float64(start)*cellW), pdfNumber(float64(y)*lineH), pdfNumber(float64(end-start)*cellW), pdfNumber(lineH))
+		}
+	}
+
+	// Text space is flipped back so glyphs stand upright inside the flipped cell space.
+	fmt.Fprintf(&content, "BT\n/F1 %s Tf\n%s w\n", pdfNumber(fontSize), pdfNumber(fontSize/30))
+	bold, currentFG := false, ""
+	for y, runs := range rows {
+		fmt.Fprintf(&content, "1 0 0 -1 0 %s Tm\n", pdfNumber(float64(y)*lineH+ascent))
+		for _, run := range runs {
+			if isBold := run.attrs&canvas.AttrBold != 0; isBold != bold {
+				bold = isBold
+				if bold {
+					content.WriteString("2 Tr\n")
+				} else {
+					content.WriteString("0 Tr\n")
+				}
+			}
+			if runFG := pdfColor(run.fg); runFG != currentFG {
+				currentFG = runFG
+				fmt.Fprintf(&content, "%s rg %s RG ", runFG, runFG)
+			}
+			content.WriteString("<")
+			for _, r := range run.text {
+				fmt.Fprintf(&content, "%04X", pf.subset.glyphs[r])
+			}
+			content.WriteString("> Tj\n")
+		}
+	}
+	content.WriteString("ET\n")
+
+	for y, runs := range rows {
+		for _, run := range runs {
+			if run.attrs&canvas.AttrUnderline == 0 {
+				continue
+			}
+			fmt.Fprintf(&content, "%s rg %s %s %s %s re f\n", pdfColor(run.fg),
+				pdfNumber(float64(run.start)*cellW), pdfNumber(float64(y)*lineH+ascent+fontSize*0.1),
+				pdfNumber(float64(len(run.text))*cellW), pdfNumber(fontSize/20))
+		}
+	}
+
+	return os.WriteFile(outPath, buildPDF(pf, content.String(), pageW, pageH), 0o644)
+}
+
+func canvasRunes(c *canvas.Canvas) []rune {
+	seen := make(map[rune]bool)
+	var runes []rune
+	for y := 0; y < c.Height(); y++ {
+		for _, cell := range c.Row(y) {
+			if !seen[cell.Rune] {
+				seen[cell.Rune] = true
+				runes = append(runes, cell.Rune)
+			}
+		}
+	}
+	return runes
+}
+
+func newPDFFont(fontBytes []byte, runes []rune) (*pdfFont, error) {
+	subset, err := subsetTrueType(fontBytes, runes)
+	if err != nil {
+		return nil, fmt.Errorf("pdf export needs a TrueType font: %w", err)
+	}
+
+	f, err := sfnt.Parse(fontBytes)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := readSFNTTables(fontBytes)
+	if err != nil {
+		return nil, err
+	}
+	head := tables["head"]
+	unitsPerEm := float64(binary.BigEndian.Uint16(head[18:20]))
+	if unitsPerEm == 0 {
+		return nil, fmt.Errorf("font has no units per em")
+	}
+	toThousandths := func(v float64) float64 { return v * 1000 / unitsPerEm }
+
+	var buf sfnt.Buffer
+	ppem := fixed.Int26_6(int(unitsPerEm) << 6)
+	metrics, err := f.Metrics(&buf, ppem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	gid, err := f.GlyphIndex(&buf, 'M')
+	if err != nil {
+		return nil, err
+	}
+	advance, err := f.GlyphAdvance(&buf, gid, ppem, font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+
+	postScript, err := f.Name(&buf, sfnt.NameIDPostScript)
+	if err != nil || postScript == "" {
+		postScript = strings.ReplaceAll(embeddedFontFamily, " ", "")
+	}
+	postScript = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, postScript)
+
+	pf := &pdfFont{
+		subset:     subset,
+		postScript: postScript,
+		advance:    int(math.Round(toThousandths(float64(advance) / 64))),
+		ascent:     toThousandths(float64(metrics.Ascent) / 64),
+		descent:    toThousandths(float64(metrics.Descent) / 64),
+		lineHeight: toThousandths(float64(metrics.Height) / 64),
+	}
+	for i := range pf.bbox {
+		pf.bbox[i] = int(math.Round(toThousandths(float64(int16(binary.BigEndian.Uint16(head[36+2*i:]))))))
+	}
+	if pf.advance < 1 || pf.lineHeight <= 0 {
+		return nil, fmt.Errorf("font has no usable metrics")
+	}
+	return pf, nil
+}
+
+// buildPDF lays out the objects of a one page document: the page, its content and a Type0 font with
+// Identity-H encoding, so the content addresses glyphs by their ids in the embedded subset.
+func buildPDF(pf *pdfFont, content string, pageW, pageH float64) []byte {
+	var w pdfWriter
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	w.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(2, "<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	w.object(3, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+		pdfNumber(pageW), pdfNumber(pageH)))
+	w.stream(4, "", []byte(content))
+
+	baseFont := "MZTONE+" + pf.postScript
+	w.object(5, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [6 0 R] /ToUnicode 9 0 R >>", baseFont))
+	// Every glyph is given the cell advance, so runs stay on the grid whatever the glyph widths are.
+	w.object(6, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor 7 0 R /DW %d /CIDToGIDMap /Identity >>",
+		baseFont, pf.advance))
+	w.object(7, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 33 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 8 0 R >>",
+		baseFont, pf.bbox[0], pf.bbox[1], pf.bbox[2], pf.bbox[3], pdfNumber(pf.ascent), pdfNumber(-pf.descent), pdfNumber(pf.ascent)))
+	w.stream(8, fmt.Sprintf("/Length1 %d", len(pf.subset.data)), pf.subset.data)
+	w.stream(9, "", []byte(toUnicodeCMap(pf.subset.glyphs)))
+	w.object(10, "<< /Producer (Mezzotone) >>")
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R /Info 10 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+	return w.buf.Bytes()
+}
+
+// toUnicodeCMap maps glyph ids back to runes for copy and search.
+func toUnicodeCMap(glyphs map[rune]uint16) string {
+	byGlyph := make(map[uint16]rune, len(glyphs))
+	for r, gid := range glyphs {
+		if current, ok := byGlyph[gid]; !ok || r < current {
+			byGlyph[gid] = r
+		}
+	}
+	gids := make([]uint16, 0, len(byGlyph))
+	for gid := range byGlyph {
+		gids = append(gids, gid)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+
+	var sb strings.Builder
+	sb.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	sb.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	sb.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	sb.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(gids); start += 100 {
+		block := gids[start:min(start+100, len(gids))]
+		fmt.Fprintf(&sb, "%d beginbfchar\n", len(block))
+		for _, gid := range block {
+			fmt.Fprintf(&sb, "<%04X> <", gid)
+			for _, unit := range utf16Units(byGlyph[gid]) {
+				fmt.Fprintf(&sb, "%04X", unit)
+			}
+			sb.WriteString(">\n")
+		}
+		sb.WriteString("endbfchar\n")
+	}
+	sb.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return sb.String()
+}
+
+func utf16Units(r rune) []uint16 {
+	if r < 0x10000 {
+		return []uint16{uint16(r)}
+	}
+	r -= 0x10000
+	return []uint16{uint16(0xd800 + r>>10), uint16(0xdc00 + r&0x3ff)}
+}
+
+// pdfWriter appends numbered objects and records their offsets for the xref table.
+// Objects must be written in id order starting at 1.
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfWriter) object(id int, body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// stream writes data Flate compressed, extraDict is added to the stream dictionary.
+func (w *pdfWriter) stream(id int, extraDict string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+
+	w.offsets = append(w.offsets, w.buf.Len())
+	if extraDict != "" {
+		extraDict = " " + extraDict
+	}
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode%s >>\nstream\n", id, compressed.Len(), extraDict)
+	w.buf.Write(compressed.Bytes())
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// pdfColor formats c as PDF RGB components, alpha is ignored.
+func pdfColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return pdfNumber(float64(n.R)/255) + " " + pdfNumber(float64(n.G)/255) + " " + pdfNumber(float64(n.B)/255)
+}
+
+func pdfNumber(v float64) string {
+	if v == 0 {
+		return "0"
+	}
+	return formatSVGNumber(v)
+}
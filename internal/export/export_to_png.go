@@ -1,16 +1,16 @@
 package export
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	"image/png"
+	"math"
 	"os"
 
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
 	xdraw "golang.org/x/image/draw"
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/opentype"
-	"golang.org/x/image/math/fixed"
 
 	_ "embed"
 )
@@ -19,13 +19,43 @@ import (
 var Font []byte
 
 type ASCIIExportOptions struct {
-	FontSize     int
-	DPI          int
-	BG           color.Color
-	FG           color.Color
-	FontTTFPath  string
-	TargetAspect float64
-	RenderColor  bool
+	FontSize    int
+	DPI         int
+	BG          color.Color
+	FG          color.Color
+	FontTTFPath string
+	// FallbackFontPaths are .ttf, .otf or .ttc fonts tried in order for runes the export font lacks.
+	FallbackFontPaths []string
+	TargetAspect      float64
+	RenderColor       bool
+	// Padding is a border of BG pixels around raster exports, added after the aspect correction.
+	Padding int
+	// Scale resizes raster exports as a last step, values <= 0 keep 1.
+	Scale float64
+
+	// FontWeight and FontWidth set the wght and wdth axes of variable export fonts, 0 keeps the default instance.
+	FontWeight float64
+	FontWidth  float64
+	// FitWidthToAspect picks the wdth axis value whose cells match TargetAspect, instead of FontWidth.
+	FitWidthToAspect bool
+	// CellWidth and CellHeight set the raster cell size in pixels, 0 uses the font metrics.
+	// Glyphs are centered in the cell, an explicit cell width replaces the aspect correction.
+	CellWidth  int
+	CellHeight int
+	// LineSpacing multiplies the font line height when CellHeight is 0, values <= 0 keep 1.
+	LineSpacing float64
+
+	// EmbedFont embeds the used glyphs of the export font in vector exports, otherwise FontFamily is referenced.
+	EmbedFont  bool
+	FontFamily string
+}
+
+// vectorOptions drops the axis and cell options, which only apply to raster exports. Vector exports
+// reference or embed the font as it is, so their layout has to follow its default instance.
+func (opt ASCIIExportOptions) vectorOptions() ASCIIExportOptions {
+	opt.FontWeight, opt.FontWidth, opt.FitWidthToAspect = 0, 0, false
+	opt.CellWidth, opt.CellHeight, opt.LineSpacing = 0, 0, 0
+	return opt
 }
 
 func loadExportFontBytes(fontPath string) ([]byte, error) {
@@ -41,116 +71,94 @@ func loadExportFontBytes(fontPath string) ([]byte, error) {
 	return fontBytes, nil
 }
 
-func ASCIIToPNG(runeArray [][]rune, colorArray [][]color.NRGBA, outPath string, opt ASCIIExportOptions) error {
-	if opt.DPI <= 0 {
-		opt.DPI = 72
-	}
-	if opt.FontSize <= 0 {
-		opt.FontSize = 14
-	}
-	if opt.BG == nil {
-		opt.BG = color.Black
-	}
-	if opt.FG == nil {
-		opt.FG = color.White
+func ASCIIToPNG(c *canvas.Canvas, outPath string, opt ASCIIExportOptions) error {
+	if c == nil {
+		return fmt.Errorf("no canvas to export")
 	}
 
-	fontBytes, err := loadExportFontBytes(opt.FontTTFPath)
+	renderer, err := newASCIIRenderer(opt)
 	if err != nil {
 		return err
 	}
+	defer renderer.Close()
 
-	tt, err := opentype.Parse(fontBytes)
-	if err != nil {
-		return err
-	}
+	fontVars := renderer.fontVariables(c.Width(), c.Height())
+	img := renderer.RenderFrame(c, fontVars)
+
+	img = finishASCIIImage(img, fontVars, opt)
 
-	face, err := opentype.NewFace(tt, &opentype.FaceOptions{
-		Size:    float64(opt.FontSize),
-		DPI:     float64(opt.DPI),
-		Hinting: font.HintingFull,
-	})
+	f, err := os.Create(outPath)
 	if err != nil {
 		return err
 	}
-	defer face.Close()
-
-	d := &font.Drawer{Face: face}
+	defer func() { _ = f.Close() }()
 
-	metrics := face.Metrics()
-	lineH := metrics.Height.Round()
-	ascent := metrics.Ascent.Ceil()
+	return png.Encode(f, img)
+}
 
-	rows := len(runeArray)
-	cols := 0
-	for _, row := range runeArray {
-		if len(row) > cols {
-			cols = len(row)
-		}
+// finishASCIIImage applies the aspect correction, padding and scale of opt to a rendered frame.
+// Frames whose cells already have their final shape are not stretched.
+func finishASCIIImage(img *image.RGBA, fontVars fontVariables, opt ASCIIExportOptions) *image.RGBA {
+	if !fontVars.exact {
+		img = applyTargetAspect(img, fontVars, opt.TargetAspect)
 	}
+	img = applyPadding(img, opt.Padding, opt.BG)
+	return applyScale(img, opt.Scale)
+}
 
-	if rows < 1 {
-		rows = 1
+// applyPadding surrounds img with padding pixels of bg.
+func applyPadding(img *image.RGBA, padding int, bg color.Color) *image.RGBA {
+	if padding <= 0 {
+		return img
 	}
-	if cols < 1 {
-		cols = 1
+	if bg == nil {
+		bg = color.Black
 	}
 
-	cellW := d.MeasureString("M").Ceil()
-	if cellW < 1 {
-		cellW = 1
-	}
-	if lineH < 1 {
-		lineH = 1
+	b := img.Bounds()
+	padded := image.NewRGBA(image.Rect(0, 0, b.Dx()+2*padding, b.Dy()+2*padding))
+	draw.Draw(padded, padded.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+	draw.Draw(padded, image.Rect(padding, padding, padding+b.Dx(), padding+b.Dy()), img, b.Min, draw.Src)
+	return padded
+}
+
+// applyScale resizes img by scale. Whole factors use nearest neighbor so glyph edges stay sharp.
+func applyScale(img *image.RGBA, scale float64) *image.RGBA {
+	if scale <= 0 || scale == 1 {
+		return img
 	}
 
-	w := cols * cellW
-	h := rows * lineH
-
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	draw.Draw(img, img.Bounds(), &image.Uniform{C: opt.BG}, image.Point{}, draw.Src)
-
-	d.Dst = img
-	d.Src = image.NewUniform(opt.FG)
-
-	if !opt.RenderColor {
-		for y, row := range runeArray {
-			baselineY := y*lineH + ascent
-			d.Dot = fixed.P(0, baselineY)
-			d.DrawString(string(row))
-		}
-	} else {
-		for y, row := range runeArray {
-			baselineY := y*lineH + ascent
-			for x, r := range row {
-				d.Src = image.NewUniform(colorArray[y][x])
-				d.Dot = fixed.P(x*cellW, baselineY)
-				d.DrawString(string(r))
-			}
-		}
+	b := img.Bounds()
+	w := max(1, int(math.Round(float64(b.Dx())*scale)))
+	h := max(1, int(math.Round(float64(b.Dy())*scale)))
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	var interpolator xdraw.Interpolator = xdraw.ApproxBiLinear
+	if scale == math.Trunc(scale) {
+		interpolator = xdraw.NearestNeighbor
 	}
+	interpolator.Scale(scaled, scaled.Bounds(), img, b, draw.Src, nil)
+	return scaled
+}
 
-	// aspect correction
-	if opt.TargetAspect > 0 {
-		currentAspect := float64(cellW) / float64(lineH)
-		scaleX := opt.TargetAspect / currentAspect
-
-		if scaleX > 0.01 && scaleX < 100 {
-			newW := int(float64(img.Bounds().Dx()) * scaleX)
-			if newW < 1 {
-				newW = 1
-			}
-			scaled := image.NewRGBA(image.Rect(0, 0, newW, img.Bounds().Dy()))
-			xdraw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Over, nil)
-			img = scaled
-		}
+// applyTargetAspect stretches img horizontally so a cell has the targetAspect width/height ratio.
+// A targetAspect <= 0 keeps the font cell shape.
+func applyTargetAspect(img *image.RGBA, fontVars fontVariables, targetAspect float64) *image.RGBA {
+	if targetAspect <= 0 {
+		return img
 	}
 
-	f, err := os.Create(outPath)
-	if err != nil {
-		return err
+	currentAspect := float64(fontVars.cellW) / float64(fontVars.lineH)
+	scaleX := targetAspect / currentAspect
+	if scaleX <= 0.01 || scaleX >= 100 {
+		return img
 	}
-	defer func() { _ = f.Close() }()
 
-	return png.Encode(f, img)
+	newW := int(float64(img.Bounds().Dx()) * scaleX)
+	if newW < 1 {
+		newW = 1
+	}
+	scaled := image.NewRGBA(image.Rect(0, 0, newW, img.Bounds().Dy()))
+	xdraw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Over, nil)
+	return scaled
 }
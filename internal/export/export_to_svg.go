@@ -0,0 +1,242 @@
+package export
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+const (
+	defaultExportFontFamily = "'Noto Sans Mono', monospace"
+	embeddedFontFamily      = "Mezzotone Mono"
+)
+
+// svgLayout holds the cell geometry shared by every frame of a vector export, in px.
+type svgLayout struct {
+	cellW, lineH, ascent int
+	fontSize             float64
+	cols, rows           int
+	scaleX               float64
+	fontFamily           string
+	fontFace             string
+}
+
+func (l svgLayout) width() float64  { return float64(l.cols*l.cellW) * l.scaleX }
+func (l svgLayout) height() float64 { return float64(l.rows * l.lineH) }
+
+// newSVGLayout measures the export font like the PNG exporter and resolves the font to reference or embed.
+func newSVGLayout(canvases []*canvas.Canvas, opt ASCIIExportOptions) (svgLayout, error) {
+	renderer, err := newASCIIRenderer(opt.vectorOptions())
+	if err != nil {
+		return svgLayout{}, err
+	}
+	defer renderer.Close()
+
+	cols, rows := 1, 1
+	for i, c := range canvases {
+		if c == nil {
+			return svgLayout{}, fmt.Errorf("frame %d has no canvas", i)
+		}
+		cols, rows = max(cols, c.Width()), max(rows, c.Height())
+	}
+
+	fontVars := renderer.fontVariables(cols, rows)
+	layout := svgLayout{
+		cellW:      fontVars.cellW,
+		lineH:      fontVars.lineH,
+		ascent:     fontVars.ascent,
+		fontSize:   float64(renderer.opt.FontSize) * float64(renderer.opt.DPI) / 72,
+		cols:       cols,
+		rows:       rows,
+		scaleX:     targetAspectScale(fontVars, opt.TargetAspect),
+		fontFamily: opt.FontFamily,
+	}
+	if layout.fontFamily == "" {
+		layout.fontFamily = defaultExportFontFamily
+	}
+
+	if opt.EmbedFont {
+		fontFace, err := embeddedFontFace(canvases, opt.FontTTFPath)
+		if err != nil {
+			return svgLayout{}, err
+		}
+		layout.fontFace = fontFace
+		layout.fontFamily = "'" + embeddedFontFamily + "', " + layout.fontFamily
+	}
+	return layout, nil
+}
+
+// targetAspectScale is the horizontal scale applyTargetAspect would use, 1 when no correction applies.
+func targetAspectScale(fontVars fontVariables, targetAspect float64) float64 {
+	if targetAspect <= 0 {
+		return 1
+	}
+	scaleX := targetAspect / (float64(fontVars.cellW) / float64(fontVars.lineH))
+	if scaleX <= 0.01 || scaleX >= 100 {
+		return 1
+	}
+	return scaleX
+}
+
+// embeddedFontFace returns an @font-face rule with the glyphs used by canvases as a base64 WOFF.
+// Fonts that cannot be subset (CFF outlines) are embedded whole.
+func embeddedFontFace(canvases []*canvas.Canvas, fontPath string) (string, error) {
+	fontBytes, err := loadExportFontBytes(fontPath)
+	if err != nil {
+		return "", err
+	}
+
+	seen := make(map[rune]bool)
+	var runes []rune
+	for _, c := range canvases {
+		for y := 0; y < c.Height(); y++ {
+			for _, cell := range c.Row(y) {
+				if !seen[cell.Rune] {
+					seen[cell.Rune] = true
+					runes = append(runes, cell.Rune)
+				}
+			}
+		}
+	}
+
+	data, mime, format := fontBytes, "font/ttf", "truetype"
+	if subset, err := subsetTrueType(fontBytes, runes); err == nil {
+		if woff, err := sfntToWOFF(subset.data); err == nil {
+			data, mime, format = woff, "font/woff", "woff"
+		}
+	}
+
+	return fmt.Sprintf("@font-face{font-family:'%s';src:url(data:%s;base64,%s) format('%s')}",
+		embeddedFontFamily, mime, base64.StdEncoding.EncodeToString(data), format), nil
+}
+
+// ASCIIToSVG writes c as SVG text, one <text> per row with a <tspan> per color run.
+// Cell backgrounds become rects and TargetAspect is applied as a horizontal scale transform,
+// so the output stays vector and the text stays selectable.
+func ASCIIToSVG(c *canvas.Canvas, outPath string, opt ASCIIExportOptions) error {
+	if c == nil {
+		return fmt.Errorf("no canvas to export")
+	}
+
+	layout, err := newSVGLayout([]*canvas.Canvas{c}, opt)
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	writeSVGHeader(&sb, layout, opt, "")
+	sb.WriteString(`<g transform="scale(` + formatSVGNumber(layout.scaleX) + ` 1)">` + "\n")
+	writeSVGCanvas(&sb, c, layout, opt)
+	sb.WriteString("</g>\n</svg>\n")
+
+	return writeStringFile(outPath, sb.String())
+}
+
+// writeSVGHeader opens the svg element and writes the style sheet (plus extraCSS) and the background.
+func writeSVGHeader(sb *strings.Builder, layout svgLayout, opt ASCIIExportOptions, extraCSS string) {
+	w, h := formatSVGNumber(layout.width()), formatSVGNumber(layout.height())
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(sb, `<svg xmlns="http://www.w3.org/2000/svg" xml:space="preserve" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n", w, h, w, h)
+
+	sb.WriteString("<style>")
+	sb.WriteString(layout.fontFace)
+	fmt.Fprintf(sb, "text{font-family:%s;font-size:%spx;white-space:pre}", layout.fontFamily, formatSVGNumber(layout.fontSize))
+	sb.WriteString(extraCSS)
+	sb.WriteString("</style>\n")
+
+	fmt.Fprintf(sb, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", cssColor(exportBG(opt)))
+}
+
+// writeSVGCanvas writes the background rects and text rows of c in unscaled cell coordinates.
+func writeSVGCanvas(sb *strings.Builder, c *canvas.Canvas, layout svgLayout, opt ASCIIExportOptions) {
+	fg := exportFG(opt)
+	rows := make([][]styledRun, c.Height())
+	for y := range rows {
+		rows[y] = rowRuns(c, y, fg, opt.RenderColor)
+	}
+
+	for y, runs := range rows {
+		for i := 0; i < len(runs); i++ {
+			if runs[i].bg.A == 0 {
+				continue
+			}
+			start, end := runs[i].start, runs[i].start+len(runs[i].text)
+			for i+1 < len(runs) && runs[i+1].bg == runs[i].bg {
+				i++
+				end = runs[i].start + len(runs[i].text)
+			}
+			fmt.Fprintf(sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+				start*layout.cellW, y*layout.lineH, (end-start)*layout.cellW, layout.lineH, cssColor(runs[i].bg))
+		}
+	}
+
+	defaultFG := color.NRGBAModel.Convert(fg).(color.NRGBA)
+	fmt.Fprintf(sb, `<g fill="%s">`+"\n", cssColor(defaultFG))
+	for y, runs := range rows {
+		fmt.Fprintf(sb, `<text x="0" y="%d"`, y*layout.lineH+layout.ascent)
+		if c.Width() > 0 {
+			// Keeps columns on the grid when the viewer falls back to a font with a different advance.
+			fmt.Fprintf(sb, ` textLength="%d"`, c.Width()*layout.cellW)
+		}
+		sb.WriteString(">")
+		for _, run := range runs {
+			text := escapeMarkup(run.text)
+			if run.fg == defaultFG && run.attrs == 0 {
+				sb.WriteString(text)
+				continue
+			}
+			sb.WriteString("<tspan")
+			if run.fg != defaultFG {
+				fmt.Fprintf(sb, ` fill="%s"`, cssColor(run.fg))
+			}
+			if run.attrs&canvas.AttrBold != 0 {
+				sb.WriteString(` font-weight="bold"`)
+			}
+			if run.attrs&canvas.AttrUnderline != 0 {
+				sb.WriteString(` text-decoration="underline"`)
+			}
+			sb.WriteString(">" + text + "</tspan>")
+		}
+		sb.WriteString("</text>\n")
+	}
+	sb.WriteString("</g>\n")
+}
+
+func exportFG(opt ASCIIExportOptions) color.Color {
+	if opt.FG == nil {
+		return color.White
+	}
+	return opt.FG
+}
+
+func exportBG(opt ASCIIExportOptions) color.Color {
+	if opt.BG == nil {
+		return color.Black
+	}
+	return opt.BG
+}
+
+func formatSVGNumber(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+}
+
+func writeStringFile(outPath, content string) error {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(content); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,376 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	mjpegQuality = 90
+
+	// minVideoFrameDuration replaces zero or negative frame durations, matching the 10ms GIF delay floor.
+	minVideoFrameDuration = 10 * time.Millisecond
+	// maxVideoTicks bounds how many timebase ticks an exact timebase may produce before falling back.
+	maxVideoTicks = 1 << 18
+
+	aviKeyframe = 0x10
+	aviHasIndex = 0x10
+)
+
+// videoTiming maps variable frame durations onto the constant frame rate of AVI and Y4M.
+// Every frame lasts a whole number of ticks, frames are repeated to fill them.
+type videoTiming struct {
+	tick  time.Duration
+	rate  uint32
+	scale uint32
+	ticks []int
+}
+
+// newVideoTiming picks the timebase for frames.
+// When the durations share a common divisor of at least 1ms that divisor is the tick and timing is exact.
+// Otherwise the shortest duration is the tick and frames are placed on rounded cumulative timestamps,
+// which never drift by more than half a tick.
+func newVideoTiming(frames []ASCIIGIFFrame) videoTiming {
+	durations := make([]time.Duration, len(frames))
+	var total time.Duration
+	for i, frame := range frames {
+		durations[i] = max(frame.Duration, minVideoFrameDuration)
+		total += durations[i]
+	}
+
+	tick := durations[0]
+	for _, d := range durations[1:] {
+		tick = gcdDuration(tick, d)
+	}
+	if tick < time.Millisecond || total/tick > maxVideoTicks {
+		tick = durations[0]
+		for _, d := range durations[1:] {
+			tick = min(tick, d)
+		}
+	}
+
+	ticks := make([]int, len(durations))
+	var elapsed time.Duration
+	placed := 0
+	for i, d := range durations {
+		elapsed += d
+		end := int(math.Round(float64(elapsed) / float64(tick)))
+		ticks[i] = max(1, end-placed)
+		placed += ticks[i]
+	}
+
+	g := gcdDuration(tick, time.Second)
+	return videoTiming{
+		tick:  tick,
+		rate:  uint32(time.Second / g),
+		scale: uint32(tick / g),
+		ticks: ticks,
+	}
+}
+
+func (t videoTiming) totalTicks() int {
+	total := 0
+	for _, n := range t.ticks {
+		total += n
+	}
+	return total
+}
+
+func gcdDuration(a, b time.Duration) time.Duration {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// prepareVideoFrame applies the export aspect correction, padding and scale and pads to even dimensions,
+// which 4:2:0 decoders expect.
+func prepareVideoFrame(img *image.RGBA, fontVars fontVariables, opt ASCIIExportOptions) *image.RGBA {
+	img = finishASCIIImage(img, fontVars, opt)
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	if w%2 == 0 && h%2 == 0 {
+		return img
+	}
+
+	bg := opt.BG
+	if bg == nil {
+		bg = color.Black
+	}
+	padded := image.NewRGBA(image.Rect(0, 0, w+w%2, h+h%2))
+	draw.Draw(padded, padded.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+	draw.Draw(padded, img.Bounds(), img, img.Bounds().Min, draw.Src)
+	return padded
+}
+
+// ASCIIFramesToAVI writes frames as Motion-JPEG in an AVI container.
+// Frames longer than one tick are repeated with empty chunks, so durations are kept without re-encoding.
+func ASCIIFramesToAVI(frames []ASCIIGIFFrame, outPath string, opt ASCIIExportOptions) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to export")
+	}
+
+	timing := newVideoTiming(frames)
+	jpegFrames := make([][]byte, len(frames))
+	width, height := 0, 0
+
+	err := renderASCIIFrames(frames, opt,
+		func(_ int, img *image.RGBA, fontVars fontVariables) ([]byte, error) {
+			img = prepareVideoFrame(img, fontVars, opt)
+
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: mjpegQuality}); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		},
+		func(frameIdx int, data []byte) error {
+			if frameIdx == 0 {
+				cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+				if err != nil {
+					return err
+				}
+				width, height = cfg.Width, cfg.Height
+			}
+			jpegFrames[frameIdx] = data
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriter(f)
+	if err := writeAVI(w, width, height, timing, jpegFrames); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+type aviChunk struct {
+	data  []byte
+	flags uint32
+}
+
+func writeAVI(w io.Writer, width, height int, timing videoTiming, jpegFrames [][]byte) error {
+	chunks := make([]aviChunk, 0, timing.totalTicks())
+	maxChunk := 0
+	for i, data := range jpegFrames {
+		chunks = append(chunks, aviChunk{data: data, flags: aviKeyframe})
+		for range timing.ticks[i] - 1 {
+			chunks = append(chunks, aviChunk{})
+		}
+		maxChunk = max(maxChunk, len(data))
+	}
+
+	moviSize := 4
+	for _, c := range chunks {
+		moviSize += 8 + len(c.data) + len(c.data)%2
+	}
+	const hdrlSize = 4 + (8 + 56) + (8 + 4 + (8 + 56) + (8 + 40))
+	idxSize := 16 * len(chunks)
+	riffSize := 4 + (8 + hdrlSize) + (8 + moviSize) + (8 + idxSize)
+	if riffSize > math.MaxUint32 {
+		return fmt.Errorf("video is too large for an avi file")
+	}
+
+	bw := &binaryWriter{w: w}
+	bw.fourCC("RIFF")
+	bw.u32(uint32(riffSize))
+	bw.fourCC("AVI ")
+
+	bw.fourCC("LIST")
+	bw.u32(hdrlSize)
+	bw.fourCC("hdrl")
+
+	// MainAVIHeader
+	bw.fourCC("avih")
+	bw.u32(56)
+	bw.u32(uint32(timing.tick / time.Microsecond))
+	bw.u32(uint32(min(uint64(maxChunk)*uint64(timing.rate)/uint64(timing.scale), math.MaxUint32)))
+	bw.u32(0)
+	bw.u32(aviHasIndex)
+	bw.u32(uint32(len(chunks)))
+	bw.u32(0)
+	bw.u32(1)
+	bw.u32(uint32(maxChunk))
+	bw.u32(uint32(width))
+	bw.u32(uint32(height))
+	bw.zeros(16)
+
+	bw.fourCC("LIST")
+	bw.u32(4 + (8 + 56) + (8 + 40))
+	bw.fourCC("strl")
+
+	// AVIStreamHeader
+	bw.fourCC("strh")
+	bw.u32(56)
+	bw.fourCC("vids")
+	bw.fourCC("MJPG")
+	bw.u32(0)
+	bw.u16(0)
+	bw.u16(0)
+	bw.u32(0)
+	bw.u32(timing.scale)
+	bw.u32(timing.rate)
+	bw.u32(0)
+	bw.u32(uint32(len(chunks)))
+	bw.u32(uint32(maxChunk))
+	bw.u32(math.MaxUint32)
+	bw.u32(0)
+	bw.u16(0)
+	bw.u16(0)
+	bw.u16(uint16(width))
+	bw.u16(uint16(height))
+
+	// BITMAPINFOHEADER
+	bw.fourCC("strf")
+	bw.u32(40)
+	bw.u32(40)
+	bw.u32(uint32(width))
+	bw.u32(uint32(height))
+	bw.u16(1)
+	bw.u16(24)
+	bw.fourCC("MJPG")
+	bw.u32(uint32(width * height * 3))
+	bw.zeros(16)
+
+	bw.fourCC("LIST")
+	bw.u32(uint32(moviSize))
+	bw.fourCC("movi")
+	for _, c := range chunks {
+		bw.fourCC("00dc")
+		bw.u32(uint32(len(c.data)))
+		bw.bytes(c.data)
+		if len(c.data)%2 == 1 {
+			bw.zeros(1)
+		}
+	}
+
+	// idx1 offsets are relative to the "movi" list type.
+	bw.fourCC("idx1")
+	bw.u32(uint32(idxSize))
+	offset := 4
+	for _, c := range chunks {
+		bw.fourCC("00dc")
+		bw.u32(c.flags)
+		bw.u32(uint32(offset))
+		bw.u32(uint32(len(c.data)))
+		offset += 8 + len(c.data) + len(c.data)%2
+	}
+
+	return bw.err
+}
+
+// ASCIIFramesToY4M writes frames as an uncompressed 4:4:4 full range YUV4MPEG2 stream.
+// Y4M has no frame repeat, frames longer than one tick are written once per tick.
+func ASCIIFramesToY4M(frames []ASCIIGIFFrame, outPath string, opt ASCIIExportOptions) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to export")
+	}
+
+	timing := newVideoTiming(frames)
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriter(f)
+	err = renderASCIIFrames(frames, opt,
+		func(_ int, img *image.RGBA, fontVars fontVariables) (y4mFrame, error) {
+			return rgbaToY4M444(prepareVideoFrame(img, fontVars, opt)), nil
+		},
+		func(frameIdx int, frame y4mFrame) error {
+			if frameIdx == 0 {
+				if _, err := fmt.Fprintf(w, "YUV4MPEG2 W%d H%d F%d:%d Ip A1:1 C444 XCOLORRANGE=FULL\n", frame.width, frame.height, timing.rate, timing.scale); err != nil {
+					return err
+				}
+			}
+			for range timing.ticks[frameIdx] {
+				if _, err := w.WriteString("FRAME\n"); err != nil {
+					return err
+				}
+				if _, err := w.Write(frame.planes); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+type y4mFrame struct {
+	width, height int
+	planes        []byte
+}
+
+// rgbaToY4M444 converts img into consecutive Y, Cb and Cr planes (JFIF/BT.601 full range).
+func rgbaToY4M444(img *image.RGBA) y4mFrame {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	planes := make([]byte, 3*w*h)
+
+	yPlane := planes[:w*h]
+	cbPlane := planes[w*h : 2*w*h]
+	crPlane := planes[2*w*h:]
+	for y := 0; y < h; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < w; x++ {
+			yy, cb, cr := color.RGBToYCbCr(row[x*4], row[x*4+1], row[x*4+2])
+			yPlane[y*w+x] = yy
+			cbPlane[y*w+x] = cb
+			crPlane[y*w+x] = cr
+		}
+	}
+	return y4mFrame{width: w, height: h, planes: planes}
+}
+
+// binaryWriter writes little endian RIFF fields and keeps the first error.
+type binaryWriter struct {
+	w   io.Writer
+	err error
+	buf [4]byte
+}
+
+func (b *binaryWriter) bytes(p []byte) {
+	if b.err == nil && len(p) > 0 {
+		_, b.err = b.w.Write(p)
+	}
+}
+
+func (b *binaryWriter) fourCC(s string) { b.bytes([]byte(s)) }
+
+func (b *binaryWriter) u32(v uint32) {
+	binary.LittleEndian.PutUint32(b.buf[:], v)
+	b.bytes(b.buf[:4])
+}
+
+func (b *binaryWriter) u16(v uint16) {
+	binary.LittleEndian.PutUint16(b.buf[:], v)
+	b.bytes(b.buf[:2])
+}
+
+func (b *binaryWriter) zeros(n int) { b.bytes(make([]byte, n)) }
@@ -0,0 +1,125 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+// loadFontChain parses the export font followed by the fallback fonts in order. A custom export font is
+// backed by the embedded font last, so glyphs it lacks still come out of the same chain.
+// Variable fonts of the chain are instanced at the FontWeight and FontWidth of opt.
+func loadFontChain(opt ASCIIExportOptions) ([]*opentype.Font, error) {
+	fontBytes, err := loadExportFontBytes(opt.FontTTFPath)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := parseFontInstance(fontBytes, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range opt.FallbackFontPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fonts, err := parseFontInstance(data, opt)
+		if err != nil {
+			return nil, fmt.Errorf("fallback font %s: %w", filepath.Base(path), err)
+		}
+		chain = append(chain, fonts...)
+	}
+
+	if opt.FontTTFPath != "" {
+		embedded, err := parseFontInstance(Font, opt)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, embedded...)
+	}
+	return chain, nil
+}
+
+// parseFontInstance parses a font file like parseFontFile, with a static instance of variable fonts.
+func parseFontInstance(data []byte, opt ASCIIExportOptions) ([]*opentype.Font, error) {
+	data, err := instanceFontData(data, fontAxisValues(opt))
+	if err != nil {
+		return nil, err
+	}
+	return parseFontFile(data)
+}
+
+// parseFontFile parses a .ttf or .otf font, or every font of a .ttc collection in collection order.
+func parseFontFile(data []byte) ([]*opentype.Font, error) {
+	if !bytes.HasPrefix(data, []byte("ttcf")) {
+		f, err := opentype.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*opentype.Font{f}, nil
+	}
+
+	collection, err := opentype.ParseCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	fonts := make([]*opentype.Font, 0, collection.NumFonts())
+	for i := 0; i < collection.NumFonts(); i++ {
+		f, err := collection.Font(i)
+		if err != nil {
+			return nil, err
+		}
+		fonts = append(fonts, f)
+	}
+	return fonts, nil
+}
+
+// fontForRune returns the index of the first font in chain with a glyph for r, -1 when none has one.
+func fontForRune(chain []*opentype.Font, buf *sfnt.Buffer, r rune) int {
+	for i, f := range chain {
+		if index, err := f.GlyphIndex(buf, r); err == nil && index != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// UncoveredRunes lists the runes of canvases that neither the export font nor its fallbacks have a glyph for.
+// Raster exports draw them as the missing glyph box of the export font.
+func UncoveredRunes(canvases []*canvas.Canvas, opt ASCIIExportOptions) ([]rune, error) {
+	// Instances keep the cmap of their font, so coverage does not depend on the axes.
+	opt.FontWeight, opt.FontWidth = 0, 0
+	chain, err := loadFontChain(opt)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf sfnt.Buffer
+	checked := make(map[rune]bool)
+	var uncovered []rune
+	for _, c := range canvases {
+		if c == nil {
+			continue
+		}
+		for y := 0; y < c.Height(); y++ {
+			for _, cell := range c.Row(y) {
+				if cell.Rune == ' ' || checked[cell.Rune] {
+					continue
+				}
+				checked[cell.Rune] = true
+				if fontForRune(chain, &buf, cell.Rune) < 0 {
+					uncovered = append(uncovered, cell.Rune)
+				}
+			}
+		}
+	}
+	slices.Sort(uncovered)
+	return uncovered, nil
+}
@@ -0,0 +1,1019 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// FontAxisWeight and FontAxisWidth are the variation axes the export options can set.
+	FontAxisWeight = "wght"
+	FontAxisWidth  = "wdth"
+)
+
+// instanceDropTables are removed from an instance: the variation tables no longer apply, and the layout
+// tables may carry variation data the static outlines do not match.
+var instanceDropTables = []string{"fvar", "gvar", "avar", "cvar", "HVAR", "VVAR", "MVAR", "STAT", "GDEF", "GPOS", "GSUB"}
+
+const (
+	simpleOnCurve      = 0x01
+	simpleXShort       = 0x02
+	simpleYShort       = 0x04
+	simpleRepeat       = 0x08
+	simpleXSame        = 0x10
+	simpleYSame        = 0x20
+	compositeArgsAreXY = 0x0002
+	compositeHaveInstr = 0x0100
+)
+
+// fontAxis is an fvar axis in user units, like 100..900 for wght.
+type fontAxis struct {
+	tag           string
+	min, def, max float64
+}
+
+// variableFont is a TrueType variable font that can be turned into static instances.
+// Only glyf outlines are varied, through gvar. Metrics tables other than hmtx keep their defaults.
+type variableFont struct {
+	tables      map[string][]byte
+	axes        []fontAxis
+	avar        [][][2]float64
+	numGlyphs   int
+	numHMetrics int
+	longLoca    bool
+}
+
+type glyphPoint struct {
+	x, y    float64
+	onCurve bool
+}
+
+type glyphComponent struct {
+	flags     uint16
+	glyph     uint16
+	dx, dy    float64
+	args      []byte
+	transform []byte
+}
+
+// parsedGlyph is a glyf entry split into its points, or the offsets of its components for composites.
+type parsedGlyph struct {
+	points     []glyphPoint
+	endPts     []int
+	components []glyphComponent
+	bbox       [4]int16
+	overlap    bool
+}
+
+// parseVariableFont reads the variation tables of data. It returns nil without error when data is not a
+// TrueType variable font, such fonts are used as they are.
+func parseVariableFont(data []byte) (*variableFont, error) {
+	if bytes.HasPrefix(data, []byte("ttcf")) || bytes.HasPrefix(data, []byte("OTTO")) {
+		return nil, nil
+	}
+	tables, err := readSFNTTables(data)
+	if err != nil {
+		return nil, err
+	}
+	fvar, gvar := tables["fvar"], tables["gvar"]
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if fvar == nil || gvar == nil || tables["glyf"] == nil || tables["loca"] == nil || tables["hmtx"] == nil {
+		return nil, nil
+	}
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 || len(fvar) < 16 || len(gvar) < 20 {
+		return nil, fmt.Errorf("font variation tables are truncated")
+	}
+
+	v := &variableFont{
+		tables:      tables,
+		numGlyphs:   int(binary.BigEndian.Uint16(maxp[4:6])),
+		numHMetrics: int(binary.BigEndian.Uint16(hhea[34:36])),
+		longLoca:    binary.BigEndian.Uint16(head[50:52]) == 1,
+	}
+
+	axesOffset := int(binary.BigEndian.Uint16(fvar[4:6]))
+	axisCount := int(binary.BigEndian.Uint16(fvar[8:10]))
+	axisSize := int(binary.BigEndian.Uint16(fvar[10:12]))
+	if axisSize < 20 || axesOffset+axisCount*axisSize > len(fvar) {
+		return nil, fmt.Errorf("font fvar table is truncated")
+	}
+	for i := 0; i < axisCount; i++ {
+		a := fvar[axesOffset+i*axisSize:]
+		v.axes = append(v.axes, fontAxis{
+			tag: string(a[0:4]),
+			min: fixed16(a[4:8]),
+			def: fixed16(a[8:12]),
+			max: fixed16(a[12:16]),
+		})
+	}
+	if int(binary.BigEndian.Uint16(gvar[4:6])) != axisCount {
+		return nil, fmt.Errorf("font gvar axis count does not match fvar")
+	}
+
+	if avar := tables["avar"]; len(avar) >= 8 && int(binary.BigEndian.Uint16(avar[6:8])) == axisCount {
+		p := 8
+		for i := 0; i < axisCount; i++ {
+			if p+2 > len(avar) {
+				return nil, fmt.Errorf("font avar table is truncated")
+			}
+			count := int(binary.BigEndian.Uint16(avar[p:]))
+			p += 2
+			if p+4*count > len(avar) {
+				return nil, fmt.Errorf("font avar table is truncated")
+			}
+			segments := make([][2]float64, count)
+			for j := range segments {
+				segments[j] = [2]float64{f2dot14(avar[p:]), f2dot14(avar[p+2:])}
+				p += 4
+			}
+			v.avar = append(v.avar, segments)
+		}
+	}
+	return v, nil
+}
+
+// fontAxisValues are the axis values opt sets, in user units.
+func fontAxisValues(opt ASCIIExportOptions) map[string]float64 {
+	values := make(map[string]float64)
+	if opt.FontWeight != 0 {
+		values[FontAxisWeight] = opt.FontWeight
+	}
+	if opt.FontWidth != 0 {
+		values[FontAxisWidth] = opt.FontWidth
+	}
+	return values
+}
+
+// instanceFontData returns a static instance of data at values. Fonts without variations, collections and
+// values that select the default instance return data as it is.
+func instanceFontData(data []byte, values map[string]float64) ([]byte, error) {
+	if len(values) == 0 {
+		return data, nil
+	}
+	v, err := parseVariableFont(data)
+	if err != nil || v == nil {
+		return data, err
+	}
+	if !slices.ContainsFunc(v.normalize(values), func(c float64) bool { return c != 0 }) {
+		return data, nil
+	}
+	return v.instance(values)
+}
+
+// fitFontWidth returns the widest wdth axis value of the export font whose 'M' fits the cell width of opt,
+// the explicit CellWidth or TargetAspect times the line height. 0 means the font has no width axis or
+// there is no width to fit. When the axis cannot get narrow enough its minimum is returned.
+func fitFontWidth(opt ASCIIExportOptions) (float64, error) {
+	data, err := loadExportFontBytes(opt.FontTTFPath)
+	if err != nil {
+		return 0, err
+	}
+	v, err := parseVariableFont(data)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	axis, ok := v.axis(FontAxisWidth)
+	if !ok {
+		return 0, nil
+	}
+
+	f, err := opentype.Parse(data)
+	if err != nil {
+		return 0, err
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    float64(opt.FontSize),
+		DPI:     float64(opt.DPI),
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = face.Close() }()
+
+	target := opt.CellWidth
+	if target <= 0 {
+		lineH, _ := cellHeight(face.Metrics(), opt)
+		target = int(math.Round(opt.TargetAspect * float64(lineH)))
+	}
+	if target <= 0 {
+		return 0, nil
+	}
+
+	var buf sfnt.Buffer
+	gid, err := f.GlyphIndex(&buf, 'M')
+	if err != nil {
+		return 0, err
+	}
+	upem := fixed.Int26_6(binary.BigEndian.Uint16(v.tables["head"][18:20]))
+	ppem := fixed.Int26_6(0.5 + float64(opt.FontSize)*float64(opt.DPI)*64/72)
+	values := fontAxisValues(opt)
+
+	// advancePx is the whole pixel advance a HintingFull face of the instance measures.
+	advancePx := func(width float64) (int, error) {
+		values[FontAxisWidth] = width
+		units, err := v.advanceAt(int(gid), v.normalize(values))
+		if err != nil {
+			return 0, err
+		}
+		adv := fixed.Int26_6(math.Round(units)) * ppem
+		adv = (adv + upem/2) / upem
+		return int((adv + 32) >> 6), nil
+	}
+
+	lo, hi := axis.min, axis.max
+	if px, err := advancePx(hi); err != nil || px <= target {
+		return hi, err
+	}
+	if px, err := advancePx(lo); err != nil || px > target {
+		return lo, err
+	}
+	// The advance grows with the width, bisect for the last value that still fits.
+	for i := 0; i < 20; i++ {
+		mid := (lo + hi) / 2
+		px, err := advancePx(mid)
+		if err != nil {
+			return 0, err
+		}
+		if px <= target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// axis returns the axis with tag.
+func (v *variableFont) axis(tag string) (fontAxis, bool) {
+	for _, a := range v.axes {
+		if a.tag == tag {
+			return a, true
+		}
+	}
+	return fontAxis{}, false
+}
+
+// normalize maps user axis values to normalized coordinates in -1..1, through avar when the font has one.
+// Axes missing from values, or set to 0, stay at their default.
+func (v *variableFont) normalize(values map[string]float64) []float64 {
+	coords := make([]float64, len(v.axes))
+	for i, a := range v.axes {
+		value, ok := values[a.tag]
+		if !ok || value == 0 {
+			continue
+		}
+		value = min(max(value, a.min), a.max)
+		switch {
+		case value < a.def && a.def > a.min:
+			coords[i] = (value - a.def) / (a.def - a.min)
+		case value > a.def && a.max > a.def:
+			coords[i] = (value - a.def) / (a.max - a.def)
+		}
+
+		if i < len(v.avar) && len(v.avar[i]) > 1 {
+			coords[i] = mapSegments(v.avar[i], coords[i])
+		}
+		// Coordinates are F2DOT14 in the font, rounding keeps instances identical to other instancers.
+		coords[i] = math.Round(coords[i]*16384) / 16384
+	}
+	return coords
+}
+
+func mapSegments(segments [][2]float64, x float64) float64 {
+	if x <= segments[0][0] {
+		return segments[0][1]
+	}
+	for i := 1; i < len(segments); i++ {
+		from, to := segments[i-1], segments[i]
+		if x <= to[0] {
+			if to[0] == from[0] {
+				return to[1]
+			}
+			return from[1] + (x-from[0])*(to[1]-from[1])/(to[0]-from[0])
+		}
+	}
+	return segments[len(segments)-1][1]
+}
+
+func (v *variableFont) glyphData(gid int) ([]byte, error) {
+	loca, glyf := v.tables["loca"], v.tables["glyf"]
+	var start, end int
+	if v.longLoca {
+		if len(loca) < 4*(gid+2) {
+			return nil, fmt.Errorf("font loca table is truncated")
+		}
+		start = int(binary.BigEndian.Uint32(loca[4*gid:]))
+		end = int(binary.BigEndian.Uint32(loca[4*gid+4:]))
+	} else {
+		if len(loca) < 2*(gid+2) {
+			return nil, fmt.Errorf("font loca table is truncated")
+		}
+		start = 2 * int(binary.BigEndian.Uint16(loca[2*gid:]))
+		end = 2 * int(binary.BigEndian.Uint16(loca[2*gid+2:]))
+	}
+	if start > end || end > len(glyf) {
+		return nil, fmt.Errorf("font glyph %d is out of range", gid)
+	}
+	return glyf[start:end], nil
+}
+
+func (v *variableFont) horizontalMetrics(gid int) (advance float64, lsb float64) {
+	hmtx := v.tables["hmtx"]
+	last := max(v.numHMetrics-1, 0)
+	if 4*last+4 > len(hmtx) {
+		return 0, 0
+	}
+	advance = float64(binary.BigEndian.Uint16(hmtx[4*min(gid, last):]))
+	if gid <= last {
+		return advance, float64(int16(binary.BigEndian.Uint16(hmtx[4*gid+2:])))
+	}
+	p := 4*v.numHMetrics + 2*(gid-v.numHMetrics)
+	if p+2 > len(hmtx) {
+		return advance, 0
+	}
+	return advance, float64(int16(binary.BigEndian.Uint16(hmtx[p:])))
+}
+
+// advanceAt is the advance width of gid in font units at the normalized coordinates.
+func (v *variableFont) advanceAt(gid int, coords []float64) (float64, error) {
+	g, err := v.parseGlyph(gid)
+	if err != nil {
+		return 0, err
+	}
+	points := v.withPhantoms(gid, g)
+	deltas, err := v.glyphDeltas(gid, coords, points, g.endPts)
+	if err != nil {
+		return 0, err
+	}
+	n := len(points) - 4
+	return (points[n+1].x + deltas[n+1][0]) - (points[n].x + deltas[n][0]), nil
+}
+
+// withPhantoms returns the points gvar varies for g: its outline points or component offsets, then
+// the four phantom points that carry the horizontal and vertical metrics.
+func (v *variableFont) withPhantoms(gid int, g parsedGlyph) []glyphPoint {
+	points := slices.Clone(g.points)
+	for _, c := range g.components {
+		points = append(points, glyphPoint{x: c.dx, y: c.dy})
+	}
+	advance, lsb := v.horizontalMetrics(gid)
+	originX := float64(g.bbox[0]) - lsb
+	return append(points,
+		glyphPoint{x: originX},
+		glyphPoint{x: originX + advance},
+		glyphPoint{},
+		glyphPoint{},
+	)
+}
+
+func (v *variableFont) parseGlyph(gid int) (parsedGlyph, error) {
+	data, err := v.glyphData(gid)
+	if err != nil || len(data) == 0 {
+		return parsedGlyph{}, err
+	}
+	if len(data) < 10 {
+		return parsedGlyph{}, fmt.Errorf("font glyph %d is truncated", gid)
+	}
+
+	var g parsedGlyph
+	for i := range g.bbox {
+		g.bbox[i] = int16(binary.BigEndian.Uint16(data[2+2*i:]))
+	}
+	contours := int(int16(binary.BigEndian.Uint16(data[0:2])))
+	if contours < 0 {
+		g.components, err = parseComponents(data)
+		return g, err
+	}
+	return g, parseSimpleGlyph(data, contours, &g)
+}
+
+func parseSimpleGlyph(data []byte, contours int, g *parsedGlyph) error {
+	truncated := fmt.Errorf("font glyph is truncated")
+	p := 10
+	if p+2*contours+2 > len(data) {
+		return truncated
+	}
+	for i := 0; i < contours; i++ {
+		g.endPts = append(g.endPts, int(binary.BigEndian.Uint16(data[p:])))
+		p += 2
+	}
+	numPoints := 0
+	if contours > 0 {
+		numPoints = g.endPts[contours-1] + 1
+	}
+	p += 2 + int(binary.BigEndian.Uint16(data[p:]))
+
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		if p >= len(data) {
+			return truncated
+		}
+		flag := data[p]
+		p++
+		flags = append(flags, flag)
+		if flag&simpleRepeat != 0 {
+			if p >= len(data) {
+				return truncated
+			}
+			for repeat := int(data[p]); repeat > 0 && len(flags) < numPoints; repeat-- {
+				flags = append(flags, flag)
+			}
+			p++
+		}
+	}
+	if numPoints > 0 {
+		g.overlap = flags[0]&0x40 != 0
+	}
+
+	g.points = make([]glyphPoint, numPoints)
+	readCoords := func(short, same byte, set func(i int, v float64)) error {
+		value := 0
+		for i, flag := range flags {
+			switch {
+			case flag&short != 0:
+				if p >= len(data) {
+					return truncated
+				}
+				if flag&same != 0 {
+					value += int(data[p])
+				} else {
+					value -= int(data[p])
+				}
+				p++
+			case flag&same == 0:
+				if p+2 > len(data) {
+					return truncated
+				}
+				value += int(int16(binary.BigEndian.Uint16(data[p:])))
+				p += 2
+			}
+			set(i, float64(value))
+		}
+		return nil
+	}
+	if err := readCoords(simpleXShort, simpleXSame, func(i int, v float64) { g.points[i].x = v }); err != nil {
+		return err
+	}
+	if err := readCoords(simpleYShort, simpleYSame, func(i int, v float64) { g.points[i].y = v }); err != nil {
+		return err
+	}
+	for i, flag := range flags {
+		g.points[i].onCurve = flag&simpleOnCurve != 0
+	}
+	return nil
+}
+
+func parseComponents(data []byte) ([]glyphComponent, error) {
+	var components []glyphComponent
+	p := 10
+	for {
+		if p+4 > len(data) {
+			return nil, fmt.Errorf("font composite glyph is truncated")
+		}
+		c := glyphComponent{
+			flags: binary.BigEndian.Uint16(data[p:]),
+			glyph: binary.BigEndian.Uint16(data[p+2:]),
+		}
+		p += 4
+
+		argSize := 2
+		if c.flags&compositeArgsAreWords != 0 {
+			argSize = 4
+		}
+		transformSize := 0
+		switch {
+		case c.flags&compositeHaveScale != 0:
+			transformSize = 2
+		case c.flags&compositeHaveXYScale != 0:
+			transformSize = 4
+		case c.flags&compositeHaveTwoByTwo != 0:
+			transformSize = 8
+		}
+		if p+argSize+transformSize > len(data) {
+			return nil, fmt.Errorf("font composite glyph is truncated")
+		}
+
+		c.args = data[p : p+argSize]
+		if c.flags&compositeArgsAreXY != 0 {
+			if argSize == 4 {
+				c.dx = float64(int16(binary.BigEndian.Uint16(c.args[0:])))
+				c.dy = float64(int16(binary.BigEndian.Uint16(c.args[2:])))
+			} else {
+				c.dx = float64(int8(c.args[0]))
+				c.dy = float64(int8(c.args[1]))
+			}
+		}
+		p += argSize
+		c.transform = data[p : p+transformSize]
+		p += transformSize
+
+		components = append(components, c)
+		if c.flags&compositeMoreComponents == 0 {
+			return components, nil
+		}
+	}
+}
+
+// glyphDeltas sums the gvar deltas of gid at coords for points, which includes the phantom points.
+// endPts are the contour ends of a simple glyph, used to infer the deltas of points a tuple leaves out.
+func (v *variableFont) glyphDeltas(gid int, coords []float64, points []glyphPoint, endPts []int) ([][2]float64, error) {
+	deltas := make([][2]float64, len(points))
+	if !slices.ContainsFunc(coords, func(c float64) bool { return c != 0 }) {
+		return deltas, nil
+	}
+
+	gvar := v.tables["gvar"]
+	truncated := fmt.Errorf("font gvar table is truncated")
+	axisCount := len(v.axes)
+	sharedCount := int(binary.BigEndian.Uint16(gvar[6:8]))
+	sharedOffset := int(binary.BigEndian.Uint32(gvar[8:12]))
+	glyphCount := int(binary.BigEndian.Uint16(gvar[12:14]))
+	longOffsets := binary.BigEndian.Uint16(gvar[14:16])&1 != 0
+	dataOffset := int(binary.BigEndian.Uint32(gvar[16:20]))
+	if gid >= glyphCount {
+		return deltas, nil
+	}
+
+	var start, end int
+	if longOffsets {
+		if 20+4*(gid+2) > len(gvar) {
+			return nil, truncated
+		}
+		start = int(binary.BigEndian.Uint32(gvar[20+4*gid:]))
+		end = int(binary.BigEndian.Uint32(gvar[20+4*gid+4:]))
+	} else {
+		if 20+2*(gid+2) > len(gvar) {
+			return nil, truncated
+		}
+		start = 2 * int(binary.BigEndian.Uint16(gvar[20+2*gid:]))
+		end = 2 * int(binary.BigEndian.Uint16(gvar[20+2*gid+2:]))
+	}
+	if start == end {
+		return deltas, nil
+	}
+	if dataOffset+end > len(gvar) || start > end || sharedOffset+2*axisCount*sharedCount > len(gvar) {
+		return nil, truncated
+	}
+	data := gvar[dataOffset+start : dataOffset+end]
+	if len(data) < 4 {
+		return nil, truncated
+	}
+
+	tupleCount := int(binary.BigEndian.Uint16(data[0:2]))
+	serialized := int(binary.BigEndian.Uint16(data[2:4]))
+	if serialized > len(data) {
+		return nil, truncated
+	}
+	readTuple := func(b []byte) []float64 {
+		t := make([]float64, axisCount)
+		for i := range t {
+			t[i] = f2dot14(b[2*i:])
+		}
+		return t
+	}
+
+	var sharedPoints []int
+	body := serialized
+	if tupleCount&0x8000 != 0 {
+		var n int
+		var err error
+		sharedPoints, n, err = readPackedPoints(data[body:])
+		if err != nil {
+			return nil, err
+		}
+		body += n
+	}
+
+	header := 4
+	for t := 0; t < tupleCount&0x0FFF; t++ {
+		if header+4 > len(data) {
+			return nil, truncated
+		}
+		size := int(binary.BigEndian.Uint16(data[header:]))
+		index := binary.BigEndian.Uint16(data[header+2:])
+		header += 4
+
+		var peak, regionStart, regionEnd []float64
+		if index&0x8000 != 0 {
+			if header+2*axisCount > len(data) {
+				return nil, truncated
+			}
+			peak = readTuple(data[header:])
+			header += 2 * axisCount
+		} else {
+			shared := int(index & 0x0FFF)
+			if shared >= sharedCount {
+				return nil, truncated
+			}
+			peak = readTuple(gvar[sharedOffset+2*axisCount*shared:])
+		}
+		if index&0x4000 != 0 {
+			if header+4*axisCount > len(data) {
+				return nil, truncated
+			}
+			regionStart = readTuple(data[header:])
+			regionEnd = readTuple(data[header+2*axisCount:])
+			header += 4 * axisCount
+		}
+
+		if body+size > len(data) {
+			return nil, truncated
+		}
+		tuple := data[body : body+size]
+		body += size
+
+		scalar := tupleScalar(coords, peak, regionStart, regionEnd)
+		if scalar == 0 {
+			continue
+		}
+
+		pointNumbers := sharedPoints
+		if index&0x2000 != 0 {
+			var n int
+			var err error
+			pointNumbers, n, err = readPackedPoints(tuple)
+			if err != nil {
+				return nil, err
+			}
+			tuple = tuple[n:]
+		}
+		count := len(pointNumbers)
+		if pointNumbers == nil {
+			count = len(points)
+		}
+		xs, n, err := readPackedDeltas(tuple, count)
+		if err != nil {
+			return nil, err
+		}
+		ys, _, err := readPackedDeltas(tuple[n:], count)
+		if err != nil {
+			return nil, err
+		}
+
+		tupleDeltas := make([][2]float64, len(points))
+		touched := make([]bool, len(points))
+		for i := 0; i < count; i++ {
+			point := i
+			if pointNumbers != nil {
+				point = pointNumbers[i]
+			}
+			if point < len(points) {
+				tupleDeltas[point] = [2]float64{xs[i], ys[i]}
+				touched[point] = true
+			}
+		}
+		if pointNumbers != nil && len(endPts) > 0 {
+			interpolateUntouched(points, endPts, tupleDeltas, touched)
+		}
+		for i := range deltas {
+			deltas[i][0] += scalar * tupleDeltas[i][0]
+			deltas[i][1] += scalar * tupleDeltas[i][1]
+		}
+	}
+	return deltas, nil
+}
+
+// tupleScalar is how much of a tuple's deltas apply at coords, nil region bounds use the peak's own range.
+func tupleScalar(coords, peak, regionStart, regionEnd []float64) float64 {
+	scalar := 1.0
+	for i, p := range peak {
+		if p == 0 {
+			continue
+		}
+		c := coords[i]
+		if c == 0 {
+			return 0
+		}
+		lo, hi := min(p, 0), max(p, 0)
+		if regionStart != nil {
+			lo, hi = regionStart[i], regionEnd[i]
+		}
+		if c < lo || c > hi {
+			return 0
+		}
+		switch {
+		case c < p && p != lo:
+			scalar *= (c - lo) / (p - lo)
+		case c > p && p != hi:
+			scalar *= (hi - c) / (hi - p)
+		}
+	}
+	return scalar
+}
+
+// readPackedPoints decodes gvar packed point numbers, nil means every point.
+func readPackedPoints(b []byte) ([]int, int, error) {
+	truncated := fmt.Errorf("font gvar point numbers are truncated")
+	if len(b) < 1 {
+		return nil, 0, truncated
+	}
+	count := int(b[0])
+	p := 1
+	if count&0x80 != 0 {
+		if len(b) < 2 {
+			return nil, 0, truncated
+		}
+		count = (count&0x7F)<<8 | int(b[1])
+		p = 2
+	}
+	if count == 0 {
+		return nil, p, nil
+	}
+
+	points := make([]int, 0, count)
+	last := 0
+	for len(points) < count {
+		if p >= len(b) {
+			return nil, 0, truncated
+		}
+		control := b[p]
+		p++
+		run := int(control&0x7F) + 1
+		for i := 0; i < run && len(points) < count; i++ {
+			if control&0x80 != 0 {
+				if p+2 > len(b) {
+					return nil, 0, truncated
+				}
+				last += int(binary.BigEndian.Uint16(b[p:]))
+				p += 2
+			} else {
+				if p >= len(b) {
+					return nil, 0, truncated
+				}
+				last += int(b[p])
+				p++
+			}
+			points = append(points, last)
+		}
+	}
+	return points, p, nil
+}
+
+// readPackedDeltas decodes count gvar packed deltas.
+func readPackedDeltas(b []byte, count int) ([]float64, int, error) {
+	truncated := fmt.Errorf("font gvar deltas are truncated")
+	deltas := make([]float64, 0, count)
+	p := 0
+	for len(deltas) < count {
+		if p >= len(b) {
+			return nil, 0, truncated
+		}
+		control := b[p]
+		p++
+		run := int(control&0x3F) + 1
+		for i := 0; i < run && len(deltas) < count; i++ {
+			switch {
+			case control&0x80 != 0:
+				deltas = append(deltas, 0)
+			case control&0x40 != 0:
+				if p+2 > len(b) {
+					return nil, 0, truncated
+				}
+				deltas = append(deltas, float64(int16(binary.BigEndian.Uint16(b[p:]))))
+				p += 2
+			default:
+				if p >= len(b) {
+					return nil, 0, truncated
+				}
+				deltas = append(deltas, float64(int8(b[p])))
+				p++
+			}
+		}
+	}
+	return deltas, p, nil
+}
+
+// interpolateUntouched infers the deltas of the outline points a tuple leaves out from the touched points
+// around them in the same contour (IUP). Phantom points are never inferred.
+func interpolateUntouched(points []glyphPoint, endPts []int, deltas [][2]float64, touched []bool) {
+	first := 0
+	for _, last := range endPts {
+		if last >= len(points) || last < first {
+			return
+		}
+		var refs []int
+		for i := first; i <= last; i++ {
+			if touched[i] {
+				refs = append(refs, i)
+			}
+		}
+		switch len(refs) {
+		case 0:
+		case 1:
+			for i := first; i <= last; i++ {
+				deltas[i] = deltas[refs[0]]
+			}
+		default:
+			for k, ref := range refs {
+				next := refs[(k+1)%len(refs)]
+				// Untouched points between ref and next, wrapping around the contour end.
+				for i := ref + 1; ; i++ {
+					if i > last {
+						i = first
+					}
+					if i == next {
+						break
+					}
+					deltas[i][0] = interpolateDelta(points[i].x, points[ref].x, points[next].x, deltas[ref][0], deltas[next][0])
+					deltas[i][1] = interpolateDelta(points[i].y, points[ref].y, points[next].y, deltas[ref][1], deltas[next][1])
+				}
+			}
+		}
+		first = last + 1
+	}
+}
+
+func interpolateDelta(v, a, b, da, db float64) float64 {
+	if a == b {
+		if da == db {
+			return da
+		}
+		return 0
+	}
+	if a > b {
+		a, b, da, db = b, a, db, da
+	}
+	switch {
+	case v <= a:
+		return da
+	case v >= b:
+		return db
+	default:
+		return da + (v-a)*(db-da)/(b-a)
+	}
+}
+
+// instance returns a static TrueType font of v at the user axis values. Glyph origins stay where they
+// were, advances follow the phantom point deltas. Hinting instructions are dropped with the variations.
+func (v *variableFont) instance(values map[string]float64) ([]byte, error) {
+	coords := v.normalize(values)
+
+	var glyf bytes.Buffer
+	loca := make([]byte, 4*(v.numGlyphs+1))
+	hmtx := make([]byte, 4*v.numGlyphs)
+	advanceMax := 0
+	bbox := [4]int{math.MaxInt16, math.MaxInt16, math.MinInt16, math.MinInt16}
+
+	for gid := 0; gid < v.numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(loca[4*gid:], uint32(glyf.Len()))
+
+		g, err := v.parseGlyph(gid)
+		if err != nil {
+			return nil, err
+		}
+		points := v.withPhantoms(gid, g)
+		deltas, err := v.glyphDeltas(gid, coords, points, g.endPts)
+		if err != nil {
+			return nil, err
+		}
+		n := len(points) - 4
+		originShift := deltas[n][0]
+		moved := make([]glyphPoint, n)
+		for i := range moved {
+			moved[i] = glyphPoint{
+				x:       math.Round(points[i].x + deltas[i][0] - originShift),
+				y:       math.Round(points[i].y + deltas[i][1]),
+				onCurve: points[i].onCurve,
+			}
+		}
+		advance := int(math.Round((points[n+1].x + deltas[n+1][0]) - (points[n].x + deltas[n][0])))
+		advance = max(advance, 0)
+		advanceMax = max(advanceMax, advance)
+
+		var encoded []byte
+		lsb := 0
+		switch {
+		case len(g.components) > 0:
+			encoded = encodeComposite(g, moved)
+			lsb = int(g.bbox[0])
+		case len(g.points) > 0:
+			var glyphBox [4]int
+			encoded, glyphBox = encodeSimple(g, moved)
+			lsb = glyphBox[0] - int(points[n].x)
+			bbox = [4]int{min(bbox[0], glyphBox[0]), min(bbox[1], glyphBox[1]), max(bbox[2], glyphBox[2]), max(bbox[3], glyphBox[3])}
+		}
+		glyf.Write(encoded)
+		for glyf.Len()%4 != 0 {
+			glyf.WriteByte(0)
+		}
+
+		binary.BigEndian.PutUint16(hmtx[4*gid:], uint16(advance))
+		binary.BigEndian.PutUint16(hmtx[4*gid+2:], uint16(int16(lsb)))
+	}
+	binary.BigEndian.PutUint32(loca[4*v.numGlyphs:], uint32(glyf.Len()))
+
+	head := bytes.Clone(v.tables["head"])
+	binary.BigEndian.PutUint32(head[8:12], 0)
+	binary.BigEndian.PutUint16(head[50:52], 1)
+	if bbox[0] <= bbox[2] {
+		for i, value := range bbox {
+			binary.BigEndian.PutUint16(head[36+2*i:], uint16(int16(value)))
+		}
+	}
+	hhea := bytes.Clone(v.tables["hhea"])
+	binary.BigEndian.PutUint16(hhea[10:12], uint16(advanceMax))
+	binary.BigEndian.PutUint16(hhea[34:36], uint16(v.numGlyphs))
+
+	var out []sfntTable
+	for tag, data := range v.tables {
+		if slices.Contains(instanceDropTables, tag) {
+			continue
+		}
+		switch tag {
+		case "glyf":
+			data = glyf.Bytes()
+		case "loca":
+			data = loca
+		case "hmtx":
+			data = hmtx
+		case "head":
+			data = head
+		case "hhea":
+			data = hhea
+		}
+		out = append(out, sfntTable{tag: tag, data: data})
+	}
+	return writeSFNT(out), nil
+}
+
+// encodeSimple writes a simple glyph with the moved points, coordinates as words and without instructions.
+func encodeSimple(g parsedGlyph, moved []glyphPoint) ([]byte, [4]int) {
+	box := [4]int{math.MaxInt16, math.MaxInt16, math.MinInt16, math.MinInt16}
+	for _, p := range moved {
+		x, y := int(p.x), int(p.y)
+		box = [4]int{min(box[0], x), min(box[1], y), max(box[2], x), max(box[3], y)}
+	}
+
+	out := make([]byte, 10, 12+2*len(g.endPts)+5*len(moved))
+	binary.BigEndian.PutUint16(out[0:], uint16(len(g.endPts)))
+	for i, value := range box {
+		binary.BigEndian.PutUint16(out[2+2*i:], uint16(int16(value)))
+	}
+	for _, end := range g.endPts {
+		out = binary.BigEndian.AppendUint16(out, uint16(end))
+	}
+	out = binary.BigEndian.AppendUint16(out, 0)
+
+	for i, p := range moved {
+		var flag byte
+		if p.onCurve {
+			flag = simpleOnCurve
+		}
+		if i == 0 && g.overlap {
+			flag |= 0x40
+		}
+		out = append(out, flag)
+	}
+	previous := 0
+	for _, p := range moved {
+		out = binary.BigEndian.AppendUint16(out, uint16(int16(int(p.x)-previous)))
+		previous = int(p.x)
+	}
+	previous = 0
+	for _, p := range moved {
+		out = binary.BigEndian.AppendUint16(out, uint16(int16(int(p.y)-previous)))
+		previous = int(p.y)
+	}
+	return out, box
+}
+
+// encodeComposite writes a composite glyph with moved component offsets. The bounding box is kept, the
+// rasterizer measures the outlines itself.
+func encodeComposite(g parsedGlyph, moved []glyphPoint) []byte {
+	out := make([]byte, 10)
+	binary.BigEndian.PutUint16(out[0:], 0xFFFF)
+	for i, value := range g.bbox {
+		binary.BigEndian.PutUint16(out[2+2*i:], uint16(value))
+	}
+	for i, c := range g.components {
+		flags := c.flags &^ compositeHaveInstr
+		args := c.args
+		if c.flags&compositeArgsAreXY != 0 {
+			flags |= compositeArgsAreWords
+			args = binary.BigEndian.AppendUint16(nil, uint16(int16(moved[i].x)))
+			args = binary.BigEndian.AppendUint16(args, uint16(int16(moved[i].y)))
+		}
+		out = binary.BigEndian.AppendUint16(out, flags)
+		out = binary.BigEndian.AppendUint16(out, c.glyph)
+		out = append(out, args...)
+		out = append(out, c.transform...)
+	}
+	return out
+}
+
+func fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+func f2dot14(b []byte) float64 {
+	return float64(int16(binary.BigEndian.Uint16(b))) / 16384
+}
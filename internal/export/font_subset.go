@@ -0,0 +1,401 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sort"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// subsetTables are the TrueType tables kept by subsetTrueType. Variation and layout tables are dropped,
+// so a variable font becomes its default instance.
+var subsetTables = []string{"OS/2", "cmap", "cvt ", "fpgm", "gasp", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "name", "post", "prep"}
+
+const (
+	compositeArgsAreWords   = 0x0001
+	compositeHaveScale      = 0x0008
+	compositeMoreComponents = 0x0020
+	compositeHaveXYScale    = 0x0040
+	compositeHaveTwoByTwo   = 0x0080
+)
+
+type fontSubset struct {
+	data   []byte
+	glyphs map[rune]uint16
+}
+
+type sfntTable struct {
+	tag  string
+	data []byte
+}
+
+// subsetTrueType keeps the outlines of the glyphs needed for runes and empties every other glyph.
+// Glyph ids are unchanged, so the subset can be addressed by the ids of the original font.
+// The cmap only maps runes, glyph names are removed from post.
+func subsetTrueType(fontBytes []byte, runes []rune) (*fontSubset, error) {
+	f, err := sfnt.Parse(fontBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := readSFNTTables(fontBytes)
+	if err != nil {
+		return nil, err
+	}
+	head, maxp, loca, glyf := tables["head"], tables["maxp"], tables["loca"], tables["glyf"]
+	if glyf == nil || loca == nil || len(head) < 54 || len(maxp) < 6 {
+		return nil, fmt.Errorf("font has no TrueType outlines to subset")
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:6]))
+	longLoca := binary.BigEndian.Uint16(head[50:52]) == 1
+	glyphRange := func(gid int) (int, int, error) {
+		var start, end int
+		if longLoca {
+			if len(loca) < 4*(gid+2) {
+				return 0, 0, fmt.Errorf("font loca table is truncated")
+			}
+			start = int(binary.BigEndian.Uint32(loca[4*gid:]))
+			end = int(binary.BigEndian.Uint32(loca[4*gid+4:]))
+		} else {
+			if len(loca) < 2*(gid+2) {
+				return 0, 0, fmt.Errorf("font loca table is truncated")
+			}
+			start = 2 * int(binary.BigEndian.Uint16(loca[2*gid:]))
+			end = 2 * int(binary.BigEndian.Uint16(loca[2*gid+2:]))
+		}
+		if start > end || end > len(glyf) {
+			return 0, 0, fmt.Errorf("font glyph %d is out of range", gid)
+		}
+		return start, end, nil
+	}
+
+	var buf sfnt.Buffer
+	glyphs := make(map[rune]uint16)
+	keep := map[int]bool{0: true}
+	pending := []int{0}
+	for _, r := range runes {
+		if _, ok := glyphs[r]; ok {
+			continue
+		}
+		gid, err := f.GlyphIndex(&buf, r)
+		if err != nil || gid == 0 {
+			continue
+		}
+		glyphs[r] = uint16(gid)
+		if !keep[int(gid)] {
+			keep[int(gid)] = true
+			pending = append(pending, int(gid))
+		}
+	}
+
+	// Composite glyphs need their components.
+	for len(pending) > 0 {
+		gid := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		start, end, err := glyphRange(gid)
+		if err != nil {
+			return nil, err
+		}
+		for _, component := range compositeComponents(glyf[start:end]) {
+			if component < numGlyphs && !keep[component] {
+				keep[component] = true
+				pending = append(pending, component)
+			}
+		}
+	}
+
+	var newGlyf bytes.Buffer
+	newLoca := make([]byte, 4*(numGlyphs+1))
+	for gid := 0; gid < numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(newLoca[4*gid:], uint32(newGlyf.Len()))
+		if !keep[gid] {
+			continue
+		}
+		start, end, err := glyphRange(gid)
+		if err != nil {
+			return nil, err
+		}
+		newGlyf.Write(glyf[start:end])
+		for newGlyf.Len()%4 != 0 {
+			newGlyf.WriteByte(0)
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[4*numGlyphs:], uint32(newGlyf.Len()))
+
+	newHead := bytes.Clone(head)
+	binary.BigEndian.PutUint16(newHead[50:52], 1)
+	binary.BigEndian.PutUint32(newHead[8:12], 0)
+
+	var out []sfntTable
+	for _, tag := range subsetTables {
+		data, ok := tables[tag]
+		if !ok {
+			continue
+		}
+		switch tag {
+		case "head":
+			data = newHead
+		case "glyf":
+			data = newGlyf.Bytes()
+		case "loca":
+			data = newLoca
+		case "cmap":
+			data = buildCmap(glyphs)
+		case "post":
+			if len(data) >= 32 {
+				data = bytes.Clone(data[:32])
+				binary.BigEndian.PutUint32(data[0:4], 0x00030000)
+			}
+		}
+		out = append(out, sfntTable{tag: tag, data: data})
+	}
+
+	return &fontSubset{data: writeSFNT(out), glyphs: glyphs}, nil
+}
+
+func readSFNTTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, fmt.Errorf("font is truncated")
+	}
+	if version := binary.BigEndian.Uint32(data[0:4]); version != 0x00010000 && version != 0x74727565 {
+		return nil, fmt.Errorf("only TrueType fonts can be subset")
+	}
+
+	numTables := int(binary.BigEndian.Uint16(data[4:6]))
+	if len(data) < 12+16*numTables {
+		return nil, fmt.Errorf("font table directory is truncated")
+	}
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		entry := data[12+16*i:]
+		offset := int(binary.BigEndian.Uint32(entry[8:12]))
+		length := int(binary.BigEndian.Uint32(entry[12:16]))
+		if offset < 0 || length < 0 || offset+length > len(data) {
+			return nil, fmt.Errorf("font table %q is out of range", entry[0:4])
+		}
+		tables[string(entry[0:4])] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// compositeComponents returns the glyph ids referenced by a composite glyph, nil for simple glyphs.
+func compositeComponents(glyph []byte) []int {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph[0:2])) >= 0 {
+		return nil
+	}
+
+	var components []int
+	p := 10
+	for p+4 <= len(glyph) {
+		flags := binary.BigEndian.Uint16(glyph[p:])
+		components = append(components, int(binary.BigEndian.Uint16(glyph[p+2:])))
+		p += 4
+		if flags&compositeArgsAreWords != 0 {
+			p += 4
+		} else {
+			p += 2
+		}
+		switch {
+		case flags&compositeHaveScale != 0:
+			p += 2
+		case flags&compositeHaveXYScale != 0:
+			p += 4
+		case flags&compositeHaveTwoByTwo != 0:
+			p += 8
+		}
+		if flags&compositeMoreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// buildCmap writes a Windows BMP (format 4) and full repertoire (format 12) subtable for glyphs.
+func buildCmap(glyphs map[rune]uint16) []byte {
+	codes := make([]rune, 0, len(glyphs))
+	for r := range glyphs {
+		codes = append(codes, r)
+	}
+	slices.Sort(codes)
+
+	// Format 4, one segment per BMP rune plus the closing 0xFFFF segment.
+	var bmp []rune
+	for _, r := range codes {
+		if r < 0xFFFF {
+			bmp = append(bmp, r)
+		}
+	}
+	segCount := len(bmp) + 1
+	format4 := make([]byte, 16+8*segCount)
+	binary.BigEndian.PutUint16(format4[0:], 4)
+	binary.BigEndian.PutUint16(format4[2:], uint16(len(format4)))
+	binary.BigEndian.PutUint16(format4[6:], uint16(2*segCount))
+	searchRange, entrySelector := 2, 0
+	for searchRange*2 <= 2*segCount {
+		searchRange *= 2
+		entrySelector++
+	}
+	binary.BigEndian.PutUint16(format4[8:], uint16(searchRange))
+	binary.BigEndian.PutUint16(format4[10:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(format4[12:], uint16(2*segCount-searchRange))
+	endCodes := format4[14:]
+	startCodes := format4[16+2*segCount:]
+	deltas := format4[16+4*segCount:]
+	for i, r := range bmp {
+		binary.BigEndian.PutUint16(endCodes[2*i:], uint16(r))
+		binary.BigEndian.PutUint16(startCodes[2*i:], uint16(r))
+		binary.BigEndian.PutUint16(deltas[2*i:], glyphs[r]-uint16(r))
+	}
+	last := segCount - 1
+	binary.BigEndian.PutUint16(endCodes[2*last:], 0xFFFF)
+	binary.BigEndian.PutUint16(startCodes[2*last:], 0xFFFF)
+	binary.BigEndian.PutUint16(deltas[2*last:], 1)
+
+	// Format 12, runs of consecutive runes mapped to consecutive glyphs share a group.
+	type group struct {
+		start, end rune
+		gid        uint16
+	}
+	var groups []group
+	for _, r := range codes {
+		if n := len(groups); n > 0 && groups[n-1].end+1 == r && uint16(int(groups[n-1].gid)+int(r-groups[n-1].start)) == glyphs[r] {
+			groups[n-1].end = r
+			continue
+		}
+		groups = append(groups, group{start: r, end: r, gid: glyphs[r]})
+	}
+	format12 := make([]byte, 16+12*len(groups))
+	binary.BigEndian.PutUint16(format12[0:], 12)
+	binary.BigEndian.PutUint32(format12[4:], uint32(len(format12)))
+	binary.BigEndian.PutUint32(format12[12:], uint32(len(groups)))
+	for i, g := range groups {
+		entry := format12[16+12*i:]
+		binary.BigEndian.PutUint32(entry[0:], uint32(g.start))
+		binary.BigEndian.PutUint32(entry[4:], uint32(g.end))
+		binary.BigEndian.PutUint32(entry[8:], uint32(g.gid))
+	}
+
+	cmap := make([]byte, 4+2*8)
+	binary.BigEndian.PutUint16(cmap[2:], 2)
+	binary.BigEndian.PutUint16(cmap[4:], 3)
+	binary.BigEndian.PutUint16(cmap[6:], 1)
+	binary.BigEndian.PutUint32(cmap[8:], uint32(len(cmap)))
+	binary.BigEndian.PutUint16(cmap[12:], 3)
+	binary.BigEndian.PutUint16(cmap[14:], 10)
+	binary.BigEndian.PutUint32(cmap[16:], uint32(len(cmap)+len(format4)))
+	cmap = append(cmap, format4...)
+	return append(cmap, format12...)
+}
+
+func sfntChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// writeSFNT assembles tables into a TrueType file and fixes up head.checkSumAdjustment.
+func writeSFNT(tables []sfntTable) []byte {
+	sort.Slice(tables, func(i, j int) bool { return tables[i].tag < tables[j].tag })
+
+	numTables := len(tables)
+	searchRange, entrySelector := 16, 0
+	for searchRange*2 <= 16*numTables {
+		searchRange *= 2
+		entrySelector++
+	}
+
+	header := make([]byte, 12+16*numTables)
+	binary.BigEndian.PutUint32(header[0:], 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(16*numTables-searchRange))
+
+	var body bytes.Buffer
+	headOffset := -1
+	for i, table := range tables {
+		offset := len(header) + body.Len()
+		entry := header[12+16*i:]
+		copy(entry[0:4], table.tag)
+		binary.BigEndian.PutUint32(entry[4:], sfntChecksum(table.data))
+		binary.BigEndian.PutUint32(entry[8:], uint32(offset))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(table.data)))
+		if table.tag == "head" {
+			headOffset = offset
+		}
+		body.Write(table.data)
+		for body.Len()%4 != 0 {
+			body.WriteByte(0)
+		}
+	}
+
+	data := append(header, body.Bytes()...)
+	if headOffset >= 0 {
+		binary.BigEndian.PutUint32(data[headOffset+8:], 0xB1B0AFBA-sfntChecksum(data))
+	}
+	return data
+}
+
+// sfntToWOFF wraps a TrueType file in WOFF 1.0, compressing tables where that makes them smaller.
+func sfntToWOFF(data []byte) ([]byte, error) {
+	tables, err := readSFNTTables(data)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	header := make([]byte, 44+20*len(tags))
+	var body bytes.Buffer
+	totalSfntSize := 12 + 16*len(tags)
+	for i, tag := range tags {
+		table := tables[tag]
+		totalSfntSize += (len(table) + 3) &^ 3
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(table); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		stored := table
+		if compressed.Len() < len(table) {
+			stored = compressed.Bytes()
+		}
+
+		entry := header[44+20*i:]
+		copy(entry[0:4], tag)
+		binary.BigEndian.PutUint32(entry[4:], uint32(len(header)+body.Len()))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(stored)))
+		binary.BigEndian.PutUint32(entry[12:], uint32(len(table)))
+		binary.BigEndian.PutUint32(entry[16:], sfntChecksum(table))
+		body.Write(stored)
+		for body.Len()%4 != 0 {
+			body.WriteByte(0)
+		}
+	}
+
+	woff := append(header, body.Bytes()...)
+	copy(woff[0:4], "wOFF")
+	copy(woff[4:8], data[0:4])
+	binary.BigEndian.PutUint32(woff[8:], uint32(len(woff)))
+	binary.BigEndian.PutUint16(woff[12:], uint16(len(tags)))
+	binary.BigEndian.PutUint32(woff[16:], uint32(totalSfntSize))
+	binary.BigEndian.PutUint16(woff[20:], 1)
+	return woff, nil
+}
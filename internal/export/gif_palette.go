@@ -0,0 +1,465 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"math"
+	"slices"
+)
+
+const (
+	GIFPaletteMedianCut = "MEDIAN_CUT"
+	GIFPaletteOctree    = "OCTREE"
+	GIFPalettePlan9     = "PLAN9"
+)
+
+// gifBackgroundIndex is the palette slot reserved for the export background, so it is never shifted by quantization.
+const gifBackgroundIndex = 0
+
+// gifTransparentIndex is the palette slot of adaptive palettes that marks pixels left unchanged from the
+// previous frame. The fixed PLAN9 palette has no free slot for it.
+const gifTransparentIndex = 1
+
+// localPaletteGain is how much lower the error of a frame's own palette must be than the error with the
+// global palette before the frame gets a local color table.
+const localPaletteGain = 0.8
+
+// octreeDepth is the deepest level of the octree quantizer, 6 bits per channel.
+const octreeDepth = 6
+
+// GIFPalettes lists the palette builders of the gif export.
+func GIFPalettes() []string {
+	return []string{GIFPaletteMedianCut, GIFPaletteOctree, GIFPalettePlan9}
+}
+
+// maxHistogramColors caps the distinct colors of a histogram, bigger ones are folded into 5 bit per channel bins.
+const maxHistogramColors = 1 << 15
+
+// coarseBinFlag marks the key of a folded 5 bit bin, exact colors use the plain 24 bit value.
+const coarseBinFlag = 1 << 24
+
+// colorBin accumulates the pixels of one color or bin, sums keep the mean exact.
+type colorBin struct {
+	count   int
+	r, g, b int
+}
+
+type colorHistogram map[uint32]*colorBin
+
+// weightedColor is the mean color of a histogram bin and its pixel count.
+type weightedColor struct {
+	r, g, b float64
+	count   int
+}
+
+// histogramRGBA counts the colors of img, pixels of exactly bg are left out since they get the reserved index.
+// Text renders have few distinct colors, so they are usually counted exactly.
+func histogramRGBA(img *image.RGBA, bg color.Color) colorHistogram {
+	bgR, bgG, bgB := rgb8(bg)
+	hist := make(colorHistogram)
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[(y-b.Min.Y)*img.Stride : (y-b.Min.Y)*img.Stride+4*b.Dx()]
+		for i := 0; i < len(row); i += 4 {
+			r, g, bl := row[i], row[i+1], row[i+2]
+			if r == bgR && g == bgG && bl == bgB {
+				continue
+			}
+			key := uint32(r)<<16 | uint32(g)<<8 | uint32(bl)
+			bin, ok := hist[key]
+			if !ok {
+				bin = &colorBin{}
+				hist[key] = bin
+			}
+			bin.count++
+			bin.r += int(r)
+			bin.g += int(g)
+			bin.b += int(bl)
+		}
+	}
+	return hist.capped()
+}
+
+// capped folds h into 5 bit per channel bins when it has more than maxHistogramColors colors.
+func (h colorHistogram) capped() colorHistogram {
+	if len(h) <= maxHistogramColors {
+		return h
+	}
+	coarse := make(colorHistogram)
+	for _, bin := range h {
+		r, g, b := bin.r/bin.count, bin.g/bin.count, bin.b/bin.count
+		key := coarseBinFlag | uint32(r>>3)<<10 | uint32(g>>3)<<5 | uint32(b>>3)
+		if existing, ok := coarse[key]; ok {
+			existing.count += bin.count
+			existing.r += bin.r
+			existing.g += bin.g
+			existing.b += bin.b
+			continue
+		}
+		copied := *bin
+		coarse[key] = &copied
+	}
+	return coarse
+}
+
+// merge adds the bins of other into h, the caller caps the result.
+func (h colorHistogram) merge(other colorHistogram) {
+	for key, bin := range other {
+		if existing, ok := h[key]; ok {
+			existing.count += bin.count
+			existing.r += bin.r
+			existing.g += bin.g
+			existing.b += bin.b
+			continue
+		}
+		copied := *bin
+		h[key] = &copied
+	}
+}
+
+func (h colorHistogram) colors() []weightedColor {
+	colors := make([]weightedColor, 0, len(h))
+	for _, bin := range h {
+		n := float64(bin.count)
+		colors = append(colors, weightedColor{
+			r:     float64(bin.r) / n,
+			g:     float64(bin.g) / n,
+			b:     float64(bin.b) / n,
+			count: bin.count,
+		})
+	}
+	// Map order is random, sorting keeps the palettes and so the output reproducible.
+	slices.SortFunc(colors, func(a, b weightedColor) int {
+		if c := compareFloat(a.r, b.r); c != 0 {
+			return c
+		}
+		if c := compareFloat(a.g, b.g); c != 0 {
+			return c
+		}
+		return compareFloat(a.b, b.b)
+	})
+	return colors
+}
+
+// buildGIFPalette returns bg at gifBackgroundIndex, the transparent color at gifTransparentIndex and up to
+// 254 colors picked by method from colors.
+func buildGIFPalette(bg color.Color, colors []weightedColor, method string) color.Palette {
+	if method == GIFPalettePlan9 {
+		return palette.Plan9
+	}
+
+	bgR, bgG, bgB := rgb8(bg)
+	p := color.Palette{color.RGBA{R: bgR, G: bgG, B: bgB, A: 255}, color.RGBA{}}
+
+	size := 256 - len(p)
+	var picked []weightedColor
+	switch {
+	case len(colors) <= size:
+		picked = colors
+	case method == GIFPaletteOctree:
+		picked = octreeQuantize(colors, size)
+	default:
+		picked = medianCut(colors, size)
+	}
+
+	for _, c := range picked {
+		p = append(p, color.RGBA{R: roundChannel(c.r), G: roundChannel(c.g), B: roundChannel(c.b), A: 255})
+	}
+	return p
+}
+
+// paletteError is the pixel weighted squared distance of colors to their nearest opaque palette entry.
+func paletteError(colors []weightedColor, p color.Palette) float64 {
+	entries := make([][3]float64, 0, len(p))
+	for _, c := range p {
+		if _, _, _, a := c.RGBA(); a == 0 {
+			continue
+		}
+		r, g, b := rgb8(c)
+		entries = append(entries, [3]float64{float64(r), float64(g), float64(b)})
+	}
+
+	total := 0.0
+	for _, c := range colors {
+		best := math.MaxFloat64
+		for _, e := range entries {
+			dr, dg, db := c.r-e[0], c.g-e[1], c.b-e[2]
+			best = min(best, dr*dr+dg*dg+db*db)
+		}
+		total += best * float64(c.count)
+	}
+	return total
+}
+
+// medianCut splits the color box with the largest squared error along its widest channel, at the point
+// where the two halves have the lowest error, until there are n boxes or none can be split.
+// Each box becomes its weighted mean.
+func medianCut(colors []weightedColor, n int) []weightedColor {
+	boxes := [][]weightedColor{slices.Clone(colors)}
+	for len(boxes) < n {
+		bestBox, bestScore := -1, 0.0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if score := boxError(box); score > bestScore {
+				bestBox, bestScore = i, score
+			}
+		}
+		if bestBox < 0 {
+			break
+		}
+
+		box := boxes[bestBox]
+		channel, _ := widestChannel(box)
+		slices.SortFunc(box, func(a, b weightedColor) int {
+			return compareFloat(channelValue(a, channel), channelValue(b, channel))
+		})
+		split := bestSplit(box, channel)
+		boxes[bestBox] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	means := make([]weightedColor, 0, len(boxes))
+	for _, box := range boxes {
+		means = append(means, meanColor(box))
+	}
+	return means
+}
+
+// boxError is the pixel weighted squared distance of the colors in box to their mean.
+func boxError(box []weightedColor) float64 {
+	mean := meanColor(box)
+	total := 0.0
+	for _, c := range box {
+		dr, dg, db := c.r-mean.r, c.g-mean.g, c.b-mean.b
+		total += (dr*dr + dg*dg + db*db) * float64(c.count)
+	}
+	return total
+}
+
+// bestSplit returns the index that splits box, sorted by channel, into the two halves with the lowest
+// summed variance along channel.
+func bestSplit(box []weightedColor, channel int) int {
+	var totalW, totalS, totalSS float64
+	for _, c := range box {
+		w, v := float64(c.count), channelValue(c, channel)
+		totalW += w
+		totalS += w * v
+		totalSS += w * v * v
+	}
+
+	split, bestErr := 1, math.MaxFloat64
+	var w, sum, sq float64
+	for i, c := range box[:len(box)-1] {
+		cw, v := float64(c.count), channelValue(c, channel)
+		w += cw
+		sum += cw * v
+		sq += cw * v * v
+		rw, rs, rsq := totalW-w, totalS-sum, totalSS-sq
+		if err := (sq - sum*sum/w) + (rsq - rs*rs/rw); err < bestErr {
+			split, bestErr = i+1, err
+		}
+	}
+	return split
+}
+
+func widestChannel(box []weightedColor) (channel int, spread float64) {
+	for ch := 0; ch < 3; ch++ {
+		lo, hi := math.MaxFloat64, -math.MaxFloat64
+		for _, c := range box {
+			v := channelValue(c, ch)
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		if hi-lo > spread {
+			channel, spread = ch, hi-lo
+		}
+	}
+	return channel, spread
+}
+
+func channelValue(c weightedColor, channel int) float64 {
+	switch channel {
+	case 0:
+		return c.r
+	case 1:
+		return c.g
+	default:
+		return c.b
+	}
+}
+
+func pixelCount(colors []weightedColor) int {
+	total := 0
+	for _, c := range colors {
+		total += c.count
+	}
+	return total
+}
+
+func meanColor(colors []weightedColor) weightedColor {
+	var mean weightedColor
+	for _, c := range colors {
+		w := float64(c.count)
+		mean.r += c.r * w
+		mean.g += c.g * w
+		mean.b += c.b * w
+		mean.count += c.count
+	}
+	if mean.count > 0 {
+		n := float64(mean.count)
+		mean.r, mean.g, mean.b = mean.r/n, mean.g/n, mean.b/n
+	}
+	return mean
+}
+
+type octreeNode struct {
+	children [8]*octreeNode
+	leaf     bool
+	count    int
+	r, g, b  float64
+}
+
+// octreeQuantize inserts colors into an octree and folds the least used deepest nodes into their parent
+// until at most n leaves are left. Each leaf becomes the mean of its colors.
+func octreeQuantize(colors []weightedColor, n int) []weightedColor {
+	root := &octreeNode{}
+	levels := make([][]*octreeNode, octreeDepth)
+	leaves := 0
+
+	for _, c := range colors {
+		r, g, b := roundChannel(c.r), roundChannel(c.g), roundChannel(c.b)
+		node := root
+		for depth := 0; depth < octreeDepth; depth++ {
+			shift := 7 - depth
+			idx := (r>>shift&1)<<2 | (g>>shift&1)<<1 | (b >> shift & 1)
+			child := node.children[idx]
+			if child == nil {
+				child = &octreeNode{leaf: depth == octreeDepth-1}
+				node.children[idx] = child
+				if child.leaf {
+					leaves++
+				} else {
+					levels[depth+1] = append(levels[depth+1], child)
+				}
+			}
+			node = child
+		}
+		w := float64(c.count)
+		node.count += c.count
+		node.r += c.r * w
+		node.g += c.g * w
+		node.b += c.b * w
+	}
+	levels[0] = []*octreeNode{root}
+
+	for depth := octreeDepth - 1; depth >= 0 && leaves > n; depth-- {
+		level := levels[depth]
+		for _, node := range level {
+			node.count = 0
+			for _, child := range node.children {
+				if child != nil {
+					node.count += subtreeCount(child)
+				}
+			}
+		}
+		slices.SortFunc(level, func(a, b *octreeNode) int { return a.count - b.count })
+
+		for _, node := range level {
+			if leaves <= n {
+				break
+			}
+			folded := 0
+			node.count = 0
+			for i, child := range node.children {
+				if child == nil {
+					continue
+				}
+				node.count += child.count
+				node.r += child.r
+				node.g += child.g
+				node.b += child.b
+				node.children[i] = nil
+				folded++
+			}
+			node.leaf = true
+			leaves -= folded - 1
+		}
+	}
+
+	var picked []weightedColor
+	var collect func(node *octreeNode)
+	collect = func(node *octreeNode) {
+		if node.leaf {
+			if node.count > 0 {
+				w := float64(node.count)
+				picked = append(picked, weightedColor{r: node.r / w, g: node.g / w, b: node.b / w, count: node.count})
+			}
+			return
+		}
+		for _, child := range node.children {
+			if child != nil {
+				collect(child)
+			}
+		}
+	}
+	collect(root)
+	return picked
+}
+
+func subtreeCount(node *octreeNode) int {
+	if node.leaf {
+		return node.count
+	}
+	total := 0
+	for _, child := range node.children {
+		if child != nil {
+			total += subtreeCount(child)
+		}
+	}
+	return total
+}
+
+// hasTransparentIndex reports whether p has the transparent slot of adaptive palettes.
+func hasTransparentIndex(p color.Palette) bool {
+	if len(p) <= gifTransparentIndex {
+		return false
+	}
+	_, _, _, a := p[gifTransparentIndex].RGBA()
+	return a == 0
+}
+
+// opaqueGIFPalette returns p with the transparent slot replaced by the background, so drawing never maps
+// a rendered pixel to it: the first of equally close entries wins and the background comes first.
+func opaqueGIFPalette(p color.Palette) color.Palette {
+	if !hasTransparentIndex(p) {
+		return p
+	}
+	opaque := slices.Clone(p)
+	opaque[gifTransparentIndex] = p[gifBackgroundIndex]
+	return opaque
+}
+
+func rgb8(c color.Color) (r, g, b uint8) {
+	if c == nil {
+		return 0, 0, 0
+	}
+	r32, g32, b32, _ := c.RGBA()
+	return uint8(r32 >> 8), uint8(g32 >> 8), uint8(b32 >> 8)
+}
+
+func roundChannel(v float64) uint8 {
+	return uint8(max(0, min(255, math.Round(v))))
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
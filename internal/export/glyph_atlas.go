@@ -0,0 +1,115 @@
+package export
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// glyphMask is the coverage of one rune, rect is relative to the baseline origin of the cell.
+type glyphMask struct {
+	mask *image.Alpha
+	rect image.Rectangle
+}
+
+// glyphAtlas caches the coverage masks of one font size, so each rune is rasterized once per export
+// instead of once per cell. The faces of all workers render the same masks, so one atlas serves them all.
+// Each rune comes from the first font of chain that has it, runes no font has get the missing glyph
+// box of the export font.
+type glyphAtlas struct {
+	chain  []*opentype.Font
+	mu     sync.RWMutex
+	glyphs map[rune]*glyphMask
+}
+
+func newGlyphAtlas(chain []*opentype.Font) *glyphAtlas {
+	return &glyphAtlas{chain: chain, glyphs: make(map[rune]*glyphMask)}
+}
+
+// mask returns the coverage of r, rasterizing it on first use with the face of the font that has it.
+// faces parallels the chain of the atlas. nil means there is nothing to draw.
+func (a *glyphAtlas) mask(faces []font.Face, r rune) *glyphMask {
+	a.mu.RLock()
+	g, ok := a.glyphs[r]
+	a.mu.RUnlock()
+	if ok {
+		return g
+	}
+
+	var buf sfnt.Buffer
+	face := faces[max(0, fontForRune(a.chain, &buf, r))]
+
+	// The face reuses its mask buffer, the atlas keeps a copy.
+	dr, mask, maskp, _, ok := face.Glyph(fixed.P(0, 0), r)
+	if ok && !dr.Empty() {
+		alpha := image.NewAlpha(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+		draw.Draw(alpha, alpha.Bounds(), mask, maskp, draw.Src)
+		g = &glyphMask{mask: alpha, rect: dr}
+	}
+
+	a.mu.Lock()
+	a.glyphs[r] = g
+	a.mu.Unlock()
+	return g
+}
+
+// atlasDrawer draws glyphs from a glyphAtlas, faces rasterize the runes the atlas does not have yet.
+// offset moves every glyph from its cell origin.
+type atlasDrawer struct {
+	faces  []font.Face
+	atlas  *glyphAtlas
+	offset image.Point
+}
+
+func (d atlasDrawer) DrawGlyph(dst draw.Image, dot image.Point, r rune, c color.Color) {
+	g := d.atlas.mask(d.faces, r)
+	if g == nil {
+		return
+	}
+
+	rect := g.rect.Add(dot.Add(d.offset))
+	clipped := rect.Intersect(dst.Bounds())
+	if clipped.Empty() {
+		return
+	}
+	mp := clipped.Min.Sub(rect.Min)
+
+	rgba, ok := dst.(*image.RGBA)
+	if !ok {
+		draw.DrawMask(dst, clipped, image.NewUniform(c), image.Point{}, g.mask, mp, draw.Over)
+		return
+	}
+	compositeGlyph(rgba, clipped, c, g.mask, mp)
+}
+
+// compositeGlyph blends c through mask into dst like draw.DrawMask with draw.Over, without allocating a
+// uniform source for every cell.
+func compositeGlyph(dst *image.RGBA, r image.Rectangle, c color.Color, mask *image.Alpha, mp image.Point) {
+	const m = 1<<16 - 1
+	sr, sg, sb, sa := c.RGBA()
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := dst.PixOffset(r.Min.X, y)
+		mi := mask.PixOffset(mp.X, mp.Y+y-r.Min.Y)
+		for x := r.Min.X; x < r.Max.X; x, i, mi = x+1, i+4, mi+1 {
+			ma := uint32(mask.Pix[mi])
+			if ma == 0 {
+				continue
+			}
+			ma |= ma << 8
+
+			a := (m - (sa * ma / m)) * 0x101
+			d := dst.Pix[i : i+4 : i+4]
+			d[0] = uint8((uint32(d[0])*a + sr*ma) / m >> 8)
+			d[1] = uint8((uint32(d[1])*a + sg*ma) / m >> 8)
+			d[2] = uint8((uint32(d[2])*a + sb*ma) / m >> 8)
+			d[3] = uint8((uint32(d[3])*a + sa*ma) / m >> 8)
+		}
+	}
+}
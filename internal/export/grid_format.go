@@ -0,0 +1,362 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+// Cell grid files store renders as data for other tools, either as JSON or as the binary layout below.
+// All integers are little endian.
+//
+//	offset  size  field
+//	0       6     magic "MZGRID"
+//	6       2     version, 1
+//	8       2     cols
+//	10      2     rows
+//	12      4     frame count
+//	16      4     flags, bit 0: cells carry luminance
+//	20      4     ramp length R
+//	24      R     ramp, UTF-8 glyphs from dark to bright
+//	24+R    4     options length O
+//	28+R    O     render options, UTF-8 "key=value" lines sorted by key
+//
+// Then every frame: a uint32 duration in microseconds followed by cols*rows cells in row order, 16 bytes each:
+// uint32 rune, fg R G B A, bg R G B A, uint16 luminance (0..65535 for 0..1), uint8 attrs, one reserved byte.
+const (
+	GridJSONExtension   = ".json"
+	GridBinaryExtension = ".mzg"
+
+	gridFormatName    = "mezzotone-grid"
+	gridFormatVersion = 1
+	gridMagic         = "MZGRID"
+	gridHeaderSize    = 20
+	gridCellSize      = 16
+	gridFlagLuminance = 1
+
+	maxGridFrames = 100000
+)
+
+// GridDocument is a render as a cell grid: the frames, the ramp they were drawn from and the render options.
+type GridDocument struct {
+	Ramp    string
+	Options map[string]string
+	Frames  []ASCIIGIFFrame
+}
+
+type gridJSON struct {
+	Format  string            `json:"format"`
+	Version int               `json:"version"`
+	Cols    int               `json:"cols"`
+	Rows    int               `json:"rows"`
+	Ramp    string            `json:"ramp"`
+	Options map[string]string `json:"options"`
+	Frames  []gridJSONFrame   `json:"frames"`
+}
+
+type gridJSONFrame struct {
+	DurationMs float64        `json:"durationMs"`
+	Cells      []gridJSONCell `json:"cells"`
+}
+
+type gridJSONCell struct {
+	Rune      string   `json:"rune"`
+	FG        string   `json:"fg,omitempty"`
+	BG        string   `json:"bg,omitempty"`
+	Luminance *float64 `json:"luminance,omitempty"`
+	Attrs     uint8    `json:"attrs,omitempty"`
+}
+
+// gridSize returns the grid dimensions shared by every frame.
+func gridSize(doc GridDocument) (cols, rows int, err error) {
+	if len(doc.Frames) == 0 {
+		return 0, 0, fmt.Errorf("no frames to export")
+	}
+	for i, frame := range doc.Frames {
+		if frame.Canvas == nil {
+			return 0, 0, fmt.Errorf("frame %d has no canvas", i)
+		}
+		if i == 0 {
+			cols, rows = frame.Canvas.Width(), frame.Canvas.Height()
+			continue
+		}
+		if frame.Canvas.Width() != cols || frame.Canvas.Height() != rows {
+			return 0, 0, fmt.Errorf("frame %d is %dx%d, expected %dx%d", i, frame.Canvas.Width(), frame.Canvas.Height(), cols, rows)
+		}
+	}
+	if cols > math.MaxUint16 || rows > math.MaxUint16 {
+		return 0, 0, fmt.Errorf("grid %dx%d is too large", cols, rows)
+	}
+	return cols, rows, nil
+}
+
+// GridToJSON writes doc as a JSON cell grid.
+func GridToJSON(doc GridDocument, outPath string) error {
+	cols, rows, err := gridSize(doc)
+	if err != nil {
+		return err
+	}
+
+	out := gridJSON{
+		Format:  gridFormatName,
+		Version: gridFormatVersion,
+		Cols:    cols,
+		Rows:    rows,
+		Ramp:    doc.Ramp,
+		Options: doc.Options,
+	}
+	for _, frame := range doc.Frames {
+		jsonFrame := gridJSONFrame{
+			DurationMs: float64(frame.Duration) / float64(time.Millisecond),
+			Cells:      make([]gridJSONCell, 0, cols*rows),
+		}
+		for y := 0; y < rows; y++ {
+			for x, cell := range frame.Canvas.Row(y) {
+				jsonCell := gridJSONCell{Rune: string(cell.Rune), Attrs: uint8(cell.Attrs)}
+				if cell.FG.A > 0 {
+					jsonCell.FG = gridHex(cell.FG)
+				}
+				if cell.BG.A > 0 {
+					jsonCell.BG = gridHex(cell.BG)
+				}
+				if luminance, ok := frame.Canvas.Luminance(x, y); ok {
+					rounded := math.Round(luminance*1e4) / 1e4
+					jsonCell.Luminance = &rounded
+				}
+				jsonFrame.Cells = append(jsonFrame.Cells, jsonCell)
+			}
+		}
+		out.Frames = append(out.Frames, jsonFrame)
+	}
+
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outPath, append(data, '\n'), 0o644)
+}
+
+// GridToBinary writes doc in the binary cell grid layout.
+func GridToBinary(doc GridDocument, outPath string) error {
+	cols, rows, err := gridSize(doc)
+	if err != nil {
+		return err
+	}
+
+	var flags uint32
+	if doc.Frames[0].Canvas.HasLuminance() {
+		flags |= gridFlagLuminance
+	}
+	options := encodeGridOptions(doc.Options)
+
+	buf := bytes.NewBuffer(make([]byte, 0, gridHeaderSize+len(doc.Frames)*(4+cols*rows*gridCellSize)))
+	buf.WriteString(gridMagic)
+	le := binary.LittleEndian
+	var header [14]byte
+	le.PutUint16(header[0:], gridFormatVersion)
+	le.PutUint16(header[2:], uint16(cols))
+	le.PutUint16(header[4:], uint16(rows))
+	le.PutUint32(header[6:], uint32(len(doc.Frames)))
+	le.PutUint32(header[10:], flags)
+	buf.Write(header[:])
+	buf.Write(le.AppendUint32(nil, uint32(len(doc.Ramp))))
+	buf.WriteString(doc.Ramp)
+	buf.Write(le.AppendUint32(nil, uint32(len(options))))
+	buf.WriteString(options)
+
+	var cellBytes [gridCellSize]byte
+	for _, frame := range doc.Frames {
+		buf.Write(le.AppendUint32(nil, uint32(min(frame.Duration.Microseconds(), math.MaxUint32))))
+		for y := 0; y < rows; y++ {
+			for x, cell := range frame.Canvas.Row(y) {
+				le.PutUint32(cellBytes[0:], uint32(cell.Rune))
+				copy(cellBytes[4:8], []byte{cell.FG.R, cell.FG.G, cell.FG.B, cell.FG.A})
+				copy(cellBytes[8:12], []byte{cell.BG.R, cell.BG.G, cell.BG.B, cell.BG.A})
+				luminance, _ := frame.Canvas.Luminance(x, y)
+				le.PutUint16(cellBytes[12:], uint16(math.Round(min(max(luminance, 0), 1)*math.MaxUint16)))
+				cellBytes[14] = uint8(cell.Attrs)
+				cellBytes[15] = 0
+				buf.Write(cellBytes[:])
+			}
+		}
+	}
+	return os.WriteFile(outPath, buf.Bytes(), 0o644)
+}
+
+// ReadGridFile reads a JSON or binary cell grid written by GridToJSON or GridToBinary.
+func ReadGridFile(path string) (*GridDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte(gridMagic)) {
+		return decodeGridBinary(data)
+	}
+	return decodeGridJSON(data)
+}
+
+func decodeGridJSON(data []byte) (*GridDocument, error) {
+	var in gridJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid grid json: %w", err)
+	}
+	if in.Format != gridFormatName {
+		return nil, fmt.Errorf("not a mezzotone grid file")
+	}
+	if in.Version != gridFormatVersion {
+		return nil, fmt.Errorf("unsupported grid version %d", in.Version)
+	}
+	if in.Cols < 0 || in.Rows < 0 || in.Cols > math.MaxUint16 || in.Rows > math.MaxUint16 || len(in.Frames) == 0 {
+		return nil, fmt.Errorf("invalid grid size %dx%d with %d frames", in.Cols, in.Rows, len(in.Frames))
+	}
+
+	doc := &GridDocument{Ramp: in.Ramp, Options: in.Options}
+	for i, frame := range in.Frames {
+		if len(frame.Cells) != in.Cols*in.Rows {
+			return nil, fmt.Errorf("grid frame %d has %d cells, expected %d", i, len(frame.Cells), in.Cols*in.Rows)
+		}
+		c := canvas.New(in.Cols, in.Rows)
+		for j, jsonCell := range frame.Cells {
+			r, size := utf8.DecodeRuneInString(jsonCell.Rune)
+			if size == 0 || size != len(jsonCell.Rune) {
+				return nil, fmt.Errorf("grid frame %d cell %d must hold exactly one rune", i, j)
+			}
+			cell := canvas.Cell{Rune: r, Attrs: canvas.Attr(jsonCell.Attrs)}
+			var err error
+			if cell.FG, err = parseGridHex(jsonCell.FG); err != nil {
+				return nil, err
+			}
+			if cell.BG, err = parseGridHex(jsonCell.BG); err != nil {
+				return nil, err
+			}
+			x, y := j%in.Cols, j/in.Cols
+			c.Set(x, y, cell)
+			if jsonCell.Luminance != nil {
+				c.SetLuminance(x, y, *jsonCell.Luminance)
+			}
+		}
+		doc.Frames = append(doc.Frames, ASCIIGIFFrame{
+			Canvas:   c,
+			Duration: time.Duration(frame.DurationMs * float64(time.Millisecond)),
+		})
+	}
+	return doc, nil
+}
+
+func decodeGridBinary(data []byte) (*GridDocument, error) {
+	le := binary.LittleEndian
+	if len(data) < gridHeaderSize+8 {
+		return nil, fmt.Errorf("grid file is truncated")
+	}
+	if version := le.Uint16(data[6:]); version != gridFormatVersion {
+		return nil, fmt.Errorf("unsupported grid version %d", version)
+	}
+	cols, rows := int(le.Uint16(data[8:])), int(le.Uint16(data[10:]))
+	frameCount := int(le.Uint32(data[12:]))
+	flags := le.Uint32(data[16:])
+	if frameCount == 0 || frameCount > maxGridFrames {
+		return nil, fmt.Errorf("invalid grid frame count %d", frameCount)
+	}
+
+	rest := data[gridHeaderSize:]
+	readBlock := func(name string) (string, error) {
+		if len(rest) < 4 || int(le.Uint32(rest)) > len(rest)-4 {
+			return "", fmt.Errorf("grid %s is truncated", name)
+		}
+		n := int(le.Uint32(rest))
+		block := string(rest[4 : 4+n])
+		rest = rest[4+n:]
+		return block, nil
+	}
+	ramp, err := readBlock("ramp")
+	if err != nil {
+		return nil, err
+	}
+	options, err := readBlock("options")
+	if err != nil {
+		return nil, err
+	}
+
+	frameSize := 4 + cols*rows*gridCellSize
+	if len(rest) != frameCount*frameSize {
+		return nil, fmt.Errorf("grid file has %d bytes of frames, expected %d", len(rest), frameCount*frameSize)
+	}
+
+	doc := &GridDocument{Ramp: ramp, Options: decodeGridOptions(options)}
+	for i := 0; i < frameCount; i++ {
+		frame := rest[i*frameSize : (i+1)*frameSize]
+		c := canvas.New(cols, rows)
+		for j := 0; j < cols*rows; j++ {
+			b := frame[4+j*gridCellSize:]
+			x, y := j%cols, j/cols
+			c.Set(x, y, canvas.Cell{
+				Rune:  rune(le.Uint32(b[0:])),
+				FG:    color.NRGBA{R: b[4], G: b[5], B: b[6], A: b[7]},
+				BG:    color.NRGBA{R: b[8], G: b[9], B: b[10], A: b[11]},
+				Attrs: canvas.Attr(b[14]),
+			})
+			if flags&gridFlagLuminance != 0 {
+				c.SetLuminance(x, y, float64(le.Uint16(b[12:]))/math.MaxUint16)
+			}
+		}
+		doc.Frames = append(doc.Frames, ASCIIGIFFrame{
+			Canvas:   c,
+			Duration: time.Duration(le.Uint32(frame)) * time.Microsecond,
+		})
+	}
+	return doc, nil
+}
+
+func encodeGridOptions(options map[string]string) string {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		// Keys and values are single line settings, line breaks would split the entry.
+		value := strings.NewReplacer("\n", " ", "\r", " ").Replace(options[key])
+		sb.WriteString(key + "=" + value + "\n")
+	}
+	return sb.String()
+}
+
+func decodeGridOptions(s string) map[string]string {
+	options := make(map[string]string)
+	for _, line := range strings.Split(s, "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			options[key] = value
+		}
+	}
+	return options
+}
+
+func gridHex(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+func parseGridHex(s string) (color.NRGBA, error) {
+	if s == "" {
+		return color.NRGBA{}, nil
+	}
+	var c color.NRGBA
+	if len(s) != 9 || s[0] != '#' {
+		return c, fmt.Errorf("invalid grid color %q", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A); err != nil {
+		return c, fmt.Errorf("invalid grid color %q", s)
+	}
+	return c, nil
+}
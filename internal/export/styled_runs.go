@@ -0,0 +1,91 @@
+package export
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+// styledRun is a horizontal run of cells sharing foreground, background and attributes.
+type styledRun struct {
+	start int
+	text  []rune
+	fg    color.NRGBA
+	bg    color.NRGBA
+	attrs canvas.Attr
+
+	// onlySpaces runs take the style of the next glyph, spaces have no visible foreground.
+	onlySpaces bool
+}
+
+// rowRuns splits row y of c into styled runs. Without useCellColors every cell uses fg and no background.
+// Plain spaces join a neighboring run with the same background, which keeps the number of runs low.
+func rowRuns(c *canvas.Canvas, y int, fg color.Color, useCellColors bool) []styledRun {
+	defaultFG := color.NRGBAModel.Convert(fg).(color.NRGBA)
+
+	var runs []styledRun
+	for x, cell := range c.Row(y) {
+		run := styledRun{start: x, text: []rune{cell.Rune}, fg: defaultFG, attrs: cell.Attrs}
+		if useCellColors {
+			if cell.FG.A > 0 {
+				run.fg = cell.FG
+			}
+			run.bg = cell.BG
+		}
+		run.onlySpaces = cell.Rune == ' ' && cell.Attrs&canvas.AttrUnderline == 0
+
+		if len(runs) > 0 {
+			last := &runs[len(runs)-1]
+			switch {
+			case last.bg != run.bg:
+			case run.onlySpaces, last.fg == run.fg && last.attrs == run.attrs:
+				last.text = append(last.text, cell.Rune)
+				last.onlySpaces = last.onlySpaces && run.onlySpaces
+				continue
+			case last.onlySpaces:
+				last.text = append(last.text, cell.Rune)
+				last.fg, last.attrs, last.onlySpaces = run.fg, run.attrs, false
+				continue
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	for i := range runs {
+		if runs[i].onlySpaces {
+			runs[i].fg, runs[i].attrs = defaultFG, 0
+		}
+	}
+	return runs
+}
+
+// cssColor formats c as #rrggbb, or rgba() when it is translucent.
+func cssColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+	}
+	return fmt.Sprintf("rgba(%d,%d,%d,%.3g)", n.R, n.G, n.B, float64(n.A)/255)
+}
+
+// escapeMarkup escapes text for XML and HTML and replaces control characters, which XML cannot hold.
+func escapeMarkup(text []rune) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '&':
+			sb.WriteString("&amp;")
+		case r == '<':
+			sb.WriteString("&lt;")
+		case r == '>':
+			sb.WriteString("&gt;")
+		case r < 0x20 || r == 0x7f || r == 0xfffe || r == 0xffff:
+			sb.WriteByte(' ')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ColorEstimator selects the statistic used to reduce the pixels of a cell to a single color.
+type ColorEstimator string
+
+const (
+	// ColorEstimatorMean averages every pixel of the cell.
+	ColorEstimatorMean ColorEstimator = "MEAN"
+	// ColorEstimatorMedian takes the per channel median, robust against small outliers.
+	ColorEstimatorMedian ColorEstimator = "MEDIAN"
+	// ColorEstimatorSaturated picks the most saturated pixel of the cell.
+	ColorEstimatorSaturated ColorEstimator = "SATURATED"
+	// ColorEstimatorDominant clusters the cell with k-means (k=2..3) and keeps the biggest cluster.
+	ColorEstimatorDominant ColorEstimator = "DOMINANT"
+	// ColorEstimatorInk averages the pixels weighted by where the chosen glyph has ink.
+	ColorEstimatorInk ColorEstimator = "INK"
+)
+
+var availableColorEstimators = []ColorEstimator{
+	ColorEstimatorMean,
+	ColorEstimatorMedian,
+	ColorEstimatorSaturated,
+	ColorEstimatorDominant,
+	ColorEstimatorInk,
+}
+
+func AvailableColorEstimators() []string {
+	names := make([]string, 0, len(availableColorEstimators))
+	for _, estimator := range availableColorEstimators {
+		names = append(names, string(estimator))
+	}
+	return names
+}
+
+func ParseColorEstimator(s string) (ColorEstimator, error) {
+	if s == "" {
+		return ColorEstimatorMean, nil
+	}
+	if !slices.Contains(availableColorEstimators, ColorEstimator(s)) {
+		return "", fmt.Errorf("invalid color estimator: %s", s)
+	}
+	return ColorEstimator(s), nil
+}
+
+// cellSample is one opaque pixel of a cell with its position relative to the cell origin.
+type cellSample struct {
+	c    color.NRGBA
+	x, y int
+}
+
+// Reduces the samples of a cellW x cellH cell to a single color.
+// glyph is the rune chosen for the cell, only used by ColorEstimatorInk.
+func estimateCellColor(samples []cellSample, cellW, cellH int, estimator ColorEstimator, glyph rune) color.NRGBA {
+	if len(samples) == 0 {
+		return color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+	}
+
+	switch estimator {
+	case ColorEstimatorMedian:
+		return medianColor(samples)
+	case ColorEstimatorSaturated:
+		return mostSaturatedColor(samples)
+	case ColorEstimatorDominant:
+		return dominantColor(samples)
+	case ColorEstimatorInk:
+		return inkWeightedColor(samples, cellW, cellH, glyph)
+	default:
+		return meanColor(samples)
+	}
+}
+
+func meanColor(samples []cellSample) color.NRGBA {
+	var rSum, gSum, bSum float64
+	for _, s := range samples {
+		rSum += float64(s.c.R)
+		gSum += float64(s.c.G)
+		bSum += float64(s.c.B)
+	}
+	n := float64(len(samples))
+	return color.NRGBA{
+		R: uint8(rSum / n),
+		G: uint8(gSum / n),
+		B: uint8(bSum / n),
+		A: 255,
+	}
+}
+
+func medianColor(samples []cellSample) color.NRGBA {
+	var rHist, gHist, bHist [256]int
+	for _, s := range samples {
+		rHist[s.c.R]++
+		gHist[s.c.G]++
+		bHist[s.c.B]++
+	}
+
+	half := (len(samples) + 1) / 2
+	median := func(hist *[256]int) uint8 {
+		count := 0
+		for v := 0; v < 256; v++ {
+			count += hist[v]
+			if count >= half {
+				return uint8(v)
+			}
+		}
+		return 255
+	}
+
+	return color.NRGBA{R: median(&rHist), G: median(&gHist), B: median(&bHist), A: 255}
+}
+
+func mostSaturatedColor(samples []cellSample) color.NRGBA {
+	best := samples[0].c
+	bestChroma := -1
+	for _, s := range samples {
+		maxC := max(s.c.R, s.c.G, s.c.B)
+		minC := min(s.c.R, s.c.G, s.c.B)
+		chroma := int(maxC) - int(minC)
+		if chroma > bestChroma {
+			best = s.c
+			bestChroma = chroma
+		}
+	}
+	best.A = 255
+	return best
+}
+
+// Runs k-means for k=2 and k=3 and returns the centroid of the biggest cluster.
+// k=3 is only kept when it explains the cell noticeably better than k=2.
+func dominantColor(samples []cellSample) color.NRGBA {
+	centroids2, counts2, err2 := kMeans(samples, 2)
+	centroids3, counts3, err3 := kMeans(samples, 3)
+
+	centroids, counts := centroids2, counts2
+	if err3 < err2*0.7 {
+		centroids, counts = centroids3, counts3
+	}
+
+	biggest := 0
+	for i := range counts {
+		if counts[i] > counts[biggest] {
+			biggest = i
+		}
+	}
+
+	c := centroids[biggest]
+	return color.NRGBA{
+		R: uint8(math.Round(c[0])),
+		G: uint8(math.Round(c[1])),
+		B: uint8(math.Round(c[2])),
+		A: 255,
+	}
+}
+
+// Deterministic k-means in RGB space seeded with farthest point initialization.
+// Returns centroids, cluster sizes and the summed squared error.
+func kMeans(samples []cellSample, k int) ([][3]float64, []int, float64) {
+	point := func(s cellSample) [3]float64 {
+		return [3]float64{float64(s.c.R), float64(s.c.G), float64(s.c.B)}
+	}
+	dist := func(a, b [3]float64) float64 {
+		dr, dg, db := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+		return dr*dr + dg*dg + db*db
+	}
+
+	centroids := [][3]float64{point(samples[0])}
+	for len(centroids) < k {
+		farthest, farthestDist := samples[0], -1.0
+		for _, s := range samples {
+			nearest := math.MaxFloat64
+			for _, c := range centroids {
+				nearest = math.Min(nearest, dist(point(s), c))
+			}
+			if nearest > farthestDist {
+				farthest, farthestDist = s, nearest
+			}
+		}
+		centroids = append(centroids, point(farthest))
+	}
+
+	assignment := make([]int, len(samples))
+	counts := make([]int, k)
+	var sse float64
+	for iteration := 0; iteration < 8; iteration++ {
+		sums := make([][3]float64, k)
+		clear(counts)
+		sse = 0
+		for i, s := range samples {
+			p := point(s)
+			best, bestDist := 0, math.MaxFloat64
+			for c := range centroids {
+				if d := dist(p, centroids[c]); d < bestDist {
+					best, bestDist = c, d
+				}
+			}
+			assignment[i] = best
+			counts[best]++
+			sums[best][0] += p[0]
+			sums[best][1] += p[1]
+			sums[best][2] += p[2]
+			sse += bestDist
+		}
+
+		for c := range centroids {
+			if counts[c] == 0 {
+				continue
+			}
+			n := float64(counts[c])
+			centroids[c] = [3]float64{sums[c][0] / n, sums[c][1] / n, sums[c][2] / n}
+		}
+	}
+
+	return centroids, counts, sse
+}
+
+func inkWeightedColor(samples []cellSample, cellW, cellH int, glyph rune) color.NRGBA {
+	if cellW <= 0 || cellH <= 0 {
+		return meanColor(samples)
+	}
+
+	var rSum, gSum, bSum, weightSum float64
+	for _, s := range samples {
+		u := (float64(s.x) + 0.5) / float64(cellW)
+		v := (float64(s.y) + 0.5) / float64(cellH)
+		w := glyphInkCoverage(glyph, u, v)
+		rSum += float64(s.c.R) * w
+		gSum += float64(s.c.G) * w
+		bSum += float64(s.c.B) * w
+		weightSum += w
+	}
+
+	// Glyphs without ink (space) or ink falling between samples use the plain mean.
+	if weightSum <= 0 {
+		return meanColor(samples)
+	}
+	return color.NRGBA{
+		R: uint8(rSum / weightSum),
+		G: uint8(gSum / weightSum),
+		B: uint8(bSum / weightSum),
+		A: 255,
+	}
+}
+
+// Approximates how much ink glyph r has at position (u, v) of its cell, both in [0..1).
+// ASCII uses the 7x13 bitmap font, the block/line/dot glyphs of the built in ramps are described analytically.
+func glyphInkCoverage(r rune, u, v float64) float64 {
+	disc := func(radius float64) float64 {
+		du, dv := u-0.5, v-0.5
+		if du*du+dv*dv <= radius*radius {
+			return 1
+		}
+		return 0
+	}
+	band := func(d, halfWidth float64) float64 {
+		if math.Abs(d) <= halfWidth {
+			return 1
+		}
+		return 0
+	}
+
+	switch {
+	case r == ' ':
+		return 0
+	case r >= '▁' && r <= '█':
+		// Lower eighth blocks fill the cell from the bottom.
+		if v >= 1-float64(r-'▁'+1)/8 {
+			return 1
+		}
+		return 0
+	case r == '░':
+		return 0.25
+	case r == '▒':
+		return 0.5
+	case r == '▓':
+		return 0.75
+	case r == '■':
+		return band(u-0.5, 0.35) * band(v-0.5, 0.2)
+	case r == '□':
+		inner := band(u-0.5, 0.25) * band(v-0.5, 0.12)
+		return band(u-0.5, 0.35) * band(v-0.5, 0.2) * (1 - inner)
+	case r == '─':
+		return band(v-0.5, 0.08)
+	case r == '│':
+		return band(u-0.5, 0.12)
+	case r == '╱':
+		return band(u-(1-v), 0.15)
+	case r == '╲':
+		return band(u-v, 0.15)
+	case r == '●':
+		return disc(0.4)
+	case r == '•':
+		return disc(0.25)
+	case r == '∙':
+		return disc(0.15)
+	case r == '·':
+		return disc(0.1)
+	case r > ' ' && r < 0x7f:
+		return basicGlyphCoverage(r, u, v)
+	default:
+		return disc(0.3)
+	}
+}
+
+func basicGlyphCoverage(r rune, u, v float64) float64 {
+	face := basicfont.Face7x13
+	_, mask, maskp, _, ok := face.Glyph(fixed.P(0, face.Ascent), r)
+	if !ok || mask == nil {
+		return 0
+	}
+
+	x := maskp.X + int(u*float64(face.Width))
+	y := maskp.Y + int(v*float64(face.Ascent+face.Descent))
+	_, _, _, a := mask.At(x, y).RGBA()
+	return float64(a) / 0xffff
+}
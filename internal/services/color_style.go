@@ -0,0 +1,212 @@
+package services
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color modes available when RenderColor is enabled.
+const (
+	ColorModeAverage  = "AVERAGE"
+	ColorModeTint     = "TINT"
+	ColorModeDuotone  = "DUOTONE"
+	ColorModeGradient = "GRADIENT"
+)
+
+var availableColorModes = []string{ColorModeAverage, ColorModeTint, ColorModeDuotone, ColorModeGradient}
+
+// Default stops used when a gradient based mode is selected without user colors.
+var defaultTintStops = []color.NRGBA{{R: 0x33, G: 0xFF, B: 0x66, A: 255}}
+var defaultDuotoneStops = []color.NRGBA{
+	{R: 0x1B, G: 0x1B, B: 0x3A, A: 255},
+	{R: 0xF2, G: 0xC1, B: 0x4E, A: 255},
+}
+var defaultGradientStops = []color.NRGBA{
+	{R: 0x00, G: 0x00, B: 0x04, A: 255},
+	{R: 0x78, G: 0x1C, B: 0x6D, A: 255},
+	{R: 0xED, G: 0x69, B: 0x25, A: 255},
+	{R: 0xFC, G: 0xFF, B: 0xA4, A: 255},
+}
+
+// ColorStyle controls how the per cell color grid is produced.
+// The zero value keeps the raw average color untouched.
+type ColorStyle struct {
+	mode string
+	// stops: luminance 0 maps to the first stop and luminance 1 to the last.
+	stops []color.NRGBA
+	// saturation: multiplier applied to AVERAGE colors, 1 keeps them unchanged and 0 is grayscale.
+	saturation float64
+	// vibrance: -1..1, boosts (or mutes) low saturation colors more than already saturated ones.
+	vibrance float64
+}
+
+func AvailableColorModes() []string {
+	modes := make([]string, len(availableColorModes))
+	copy(modes, availableColorModes)
+	return modes
+}
+
+func NewColorStyle(mode string, stops []color.NRGBA, saturation, vibrance float64) (ColorStyle, error) {
+	if mode == "" {
+		mode = ColorModeAverage
+	}
+
+	switch mode {
+	case ColorModeAverage:
+		stops = nil
+	case ColorModeTint:
+		if len(stops) == 0 {
+			stops = defaultTintStops
+		}
+		if len(stops) != 1 {
+			return ColorStyle{}, fmt.Errorf("tint color mode takes 1 color, got %d", len(stops))
+		}
+		// A tint is a gradient from black to the tint color.
+		stops = []color.NRGBA{{A: 255}, stops[0]}
+	case ColorModeDuotone:
+		if len(stops) == 0 {
+			stops = defaultDuotoneStops
+		}
+		if len(stops) != 2 {
+			return ColorStyle{}, fmt.Errorf("duotone color mode takes 2 colors, got %d", len(stops))
+		}
+	case ColorModeGradient:
+		if len(stops) == 0 {
+			stops = defaultGradientStops
+		}
+		if len(stops) < 2 {
+			return ColorStyle{}, fmt.Errorf("gradient color mode takes at least 2 colors, got %d", len(stops))
+		}
+	default:
+		return ColorStyle{}, fmt.Errorf("invalid color mode: %s", mode)
+	}
+
+	if saturation < 0 {
+		return ColorStyle{}, fmt.Errorf("saturation must be >= 0, got %v", saturation)
+	}
+	if vibrance < -1 || vibrance > 1 {
+		return ColorStyle{}, fmt.Errorf("vibrance must be between -1 and 1, got %v", vibrance)
+	}
+
+	copied := make([]color.NRGBA, len(stops))
+	copy(copied, stops)
+
+	return ColorStyle{
+		mode:       mode,
+		stops:      copied,
+		saturation: saturation,
+		vibrance:   vibrance,
+	}, nil
+}
+
+func (s ColorStyle) Mode() string {
+	if s.mode == "" {
+		return ColorModeAverage
+	}
+	return s.mode
+}
+
+// apply returns the final cell color for the given average color and cell luminance.
+func (s ColorStyle) apply(average color.NRGBA, luminance float64) color.NRGBA {
+	switch s.mode {
+	case ColorModeTint, ColorModeDuotone, ColorModeGradient:
+		return sampleGradient(s.stops, luminance)
+	case ColorModeAverage:
+		return adjustSaturation(average, s.saturation, s.vibrance)
+	default:
+		return average
+	}
+}
+
+// Linearly interpolates evenly spaced stops at position t in [0..1].
+func sampleGradient(stops []color.NRGBA, t float64) color.NRGBA {
+	if len(stops) == 0 {
+		return color.NRGBA{A: 255}
+	}
+	if len(stops) == 1 {
+		return stops[0]
+	}
+
+	t = clamp01(t)
+	pos := t * float64(len(stops)-1)
+	i := int(pos)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	f := pos - float64(i)
+
+	a, b := stops[i], stops[i+1]
+	lerp := func(x, y uint8) uint8 {
+		return uint8(math.Round(float64(x) + (float64(y)-float64(x))*f))
+	}
+	return color.NRGBA{
+		R: lerp(a.R, b.R),
+		G: lerp(a.G, b.G),
+		B: lerp(a.B, b.B),
+		A: 255,
+	}
+}
+
+// Scales chroma around the pixel luma. Vibrance scales it more for dull colors than for saturated ones.
+func adjustSaturation(c color.NRGBA, saturation, vibrance float64) color.NRGBA {
+	if saturation == 1 && vibrance == 0 {
+		return c
+	}
+
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+	luma := 0.2126*r + 0.7152*g + 0.0722*b
+
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	currentSaturation := 0.0
+	if maxC > 0 {
+		currentSaturation = (maxC - minC) / maxC
+	}
+
+	factor := saturation * (1 + vibrance*(1-currentSaturation))
+	if factor < 0 {
+		factor = 0
+	}
+
+	channel := func(v float64) uint8 {
+		return uint8(math.Round(clamp01((luma+(v-luma)*factor)/255) * 255))
+	}
+	return color.NRGBA{R: channel(r), G: channel(g), B: channel(b), A: c.A}
+}
+
+// ParseHexColor parses #RGB or #RRGGBB (the leading # is optional).
+func ParseHexColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.NRGBA{}, fmt.Errorf("invalid hex color: %q", s)
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid hex color: %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
+
+// ParseHexColorList parses a comma or space separated list of hex colors.
+func ParseHexColorList(s string) ([]color.NRGBA, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';'
+	})
+
+	colors := make([]color.NRGBA, 0, len(fields))
+	for _, field := range fields {
+		c, err := ParseHexColor(field)
+		if err != nil {
+			return nil, err
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
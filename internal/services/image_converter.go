@@ -1,9 +1,8 @@
 package services
 
 import (
+	"context"
 	"fmt"
-	"slices"
-	"strings"
 
 	"image"
 	"image/color"
@@ -12,7 +11,8 @@ import (
 	_ "image/png"
 	"math"
 
-	"charm.land/lipgloss/v2"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+
 	_ "golang.org/x/image/bmp"
 	_ "golang.org/x/image/tiff"
 	_ "golang.org/x/image/webp"
@@ -38,6 +38,10 @@ type RenderOptions struct {
 	highContrast bool
 	RenderColor  bool
 	runeMode     string
+	// ColorStyle: how the color grid is derived when RenderColor is on (average, tint, duotone, gradient map).
+	ColorStyle ColorStyle
+	// ColorEstimator: statistic used to reduce a cell to one color, the zero value is the mean.
+	ColorEstimator ColorEstimator
 }
 
 func NewRenderOptions(
@@ -50,8 +54,7 @@ func NewRenderOptions(
 	renderColor bool,
 	runeMode string,
 ) (RenderOptions, error) {
-	availableRuneMode := []string{"ASCII", "UNICODE", "DOTS", "RECTANGLES", "BARS"}
-	if !slices.Contains(availableRuneMode, runeMode) {
+	if _, ok := LookupRenderer(runeMode); !ok {
 		return RenderOptions{}, fmt.Errorf("invalid rune mode: %s", runeMode)
 	}
 
@@ -67,23 +70,16 @@ func NewRenderOptions(
 	}, nil
 }
 
-// Dark to Bright
-const asciiRampDarkToBrightStr = "$@B%8&WM#*oahkbdpqwmZO0QLCJUYXzcvunxrjtf()1{}[]?_+~<>i!lI;:,^`. "
-const unicodeRampDarkToBrightStr = "█▓▒░■□@&%$#*+=~:;!,\".^`' "
-const dotsRampDarkToBrightStr = "●∙•· "
-const rectanglesRampDarkToBrightStr = "█▓▒░ "
-const barsRampDarkToBrightStr = "█▇▆▅▄▃▂▁ "
-
-// Bright to Dark
-const asciiRampBrightToDarkStr = " .`^,:;Il!i><~+_?][}{1)(ftjrxnuvczXYUJCLQ0OZmwqpdbkhao*#MW&8%B@$"
-const unicodeRampBrightToDarkStr = " '`^.\",!;:~=+*#$%&@□■░▒▓█"
-const dotsRampBrightToDarkStr = " ·•∙●"
-const rectanglesRampBrightToDarkStr = " ░▒▓█"
-const barsRampBrightToDarkStr = " ▁▂▃▄▅▆▇█"
+// ConvertImageToString converts inputImg into a canvas of glyphs using the selected rune mode.
+// Cell foreground colors are only filled when RenderColor is on.
+func ConvertImageToString(inputImg image.Image, renderOptions RenderOptions) (*canvas.Canvas, error) {
+	return ConvertImageToStringContext(context.Background(), inputImg, renderOptions)
+}
 
-func ConvertImageToString(inputImg image.Image, renderOptions RenderOptions) ([][]rune, [][]color.NRGBA, error) {
-	var outputChars [][]rune
-	var averageColorGrid [][]color.NRGBA
+// ConvertImageToStringContext is ConvertImageToString with cancellation.
+// ctx is checked once per grid row, a canceled conversion returns ctx.Err().
+func ConvertImageToStringContext(ctx context.Context, inputImg image.Image, renderOptions RenderOptions) (*canvas.Canvas, error) {
+	var cellColorGrid [][]color.NRGBA
 
 	// Compute grid resolution (cols x rows) based on image size + character cell size.
 	cols, rows := getColsAndRows(inputImg, renderOptions.textSize, renderOptions.fontAspect)
@@ -96,27 +92,24 @@ func ConvertImageToString(inputImg image.Image, renderOptions RenderOptions) ([]
 		cellHeight = 1
 	}
 
-	outputChars = make([][]rune, rows)
-	for r := 0; r < rows; r++ {
-		outputChars[r] = make([]rune, cols)
-	}
+	output := canvas.New(cols, rows)
 
-	averageColorGrid = make([][]color.NRGBA, rows)
+	cellColorGrid = make([][]color.NRGBA, rows)
 	for r := 0; r < rows; r++ {
-		averageColorGrid[r] = make([]color.NRGBA, cols)
+		cellColorGrid[r] = make([]color.NRGBA, cols)
 	}
 
 	// Build a luminance grid (rows x cols) where each cell is 0..1.
 	// Each cell luminance is computed by averaging pixels in the corresponding image region.
-	luminanceGrid, err := buildLuminanceGrid(inputImg, cols, rows, renderOptions.highContrast)
+	luminanceGrid, err := buildLuminanceGrid(ctx, inputImg, cols, rows, renderOptions.highContrast)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	_ = Logger().Info(fmt.Sprintf("Successfully Build LumaGrid"))
 
-	if renderOptions.RenderColor {
-		averageColorGrid = buildAverageColorGrid(inputImg, cols, rows)
-		_ = Logger().Info(fmt.Sprintf("Successfully Build averageColorGrid"))
+	renderer, ok := LookupRenderer(renderOptions.runeMode)
+	if !ok {
+		return nil, fmt.Errorf("invalid rune mode: %s", renderOptions.runeMode)
 	}
 
 	edgeThreshold := 0.0
@@ -129,56 +122,78 @@ func ConvertImageToString(inputImg image.Image, renderOptions RenderOptions) ([]
 		edgeInfos = applySobelFilter(dogGrid, cellWidth, cellHeight)
 	}
 
+	// The INK estimator needs the chosen glyph, so renderers are sampled with the mean
+	// and the grid is re-estimated once the glyphs are known.
+	sampleEstimator := renderOptions.ColorEstimator
+	if sampleEstimator == ColorEstimatorInk {
+		sampleEstimator = ColorEstimatorMean
+	}
+	if renderOptions.RenderColor {
+		cellColorGrid, err = buildStyledColorGrid(ctx, inputImg, cols, rows, sampleEstimator, nil, luminanceGrid, renderOptions.ColorStyle)
+		if err != nil {
+			return nil, err
+		}
+		_ = Logger().Info(fmt.Sprintf("Successfully Build cellColorGrid"))
+	}
+
 	_ = Logger().Info(fmt.Sprintf("Beginning image conversion"))
 
-	// Convert each luminance cell to a glyph using the chosen ramp.
-	// indices are [row][col] matching outputChars.
+	// Convert each cell to a glyph using the selected renderer.
+	// glyphs indices are [row][col] matching the canvas.
+	glyphs := make([][]Glyph, rows)
 	for i := 0; i < len(luminanceGrid); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		glyphs[i] = make([]Glyph, cols)
 		for j := 0; j < len(luminanceGrid[i]); j++ {
-			//if directionalRender true and Magnitude surpasses threshold replace with directional char
-			if renderOptions.directionalRender && edgeInfos[i][j].Magnitude > edgeThreshold {
-				outputChars[i][j] = getEdgeRuneFromGradient(edgeInfos[i][j], renderOptions.runeMode)
-				if outputChars[i][j] == ' ' {
-					outputChars[i][j] = getRuneForLuminanceValue(luminanceGrid[i][j], renderOptions.runeMode, renderOptions.reverseChars)
-				}
-			} else {
-				outputChars[i][j] = getRuneForLuminanceValue(luminanceGrid[i][j], renderOptions.runeMode, renderOptions.reverseChars)
+			sample := CellSample{
+				Luminance:    luminanceGrid[i][j],
+				Color:        cellColorGrid[i][j],
+				ReverseChars: renderOptions.reverseChars,
 			}
+			if renderOptions.directionalRender {
+				sample.EdgeMagnitude = edgeInfos[i][j].Magnitude
+				sample.EdgeAngle = edgeInfos[i][j].Angle
+				//if directionalRender true and Magnitude surpasses threshold the renderer may place a directional char
+				sample.IsEdge = edgeInfos[i][j].Magnitude > edgeThreshold
+			}
+
+			glyphs[i][j] = renderer.Render(sample)
 		}
 	}
 
-	_ = Logger().Info(fmt.Sprintf("Finished image conversion"))
-	return outputChars, averageColorGrid, nil
-}
-
-func ImageRuneArrayIntoString(runeArray [][]rune, colorArray [][]color.NRGBA, renderColor bool) string {
-	var outputString strings.Builder
-
-	for x := range runeArray {
-		for y, r := range runeArray[x] {
-			if renderColor && (x < len(colorArray) && y < len(colorArray[x])) {
-				c := colorArray[x][y]
-				s := lipgloss.NewStyle().
-					Foreground(lipgloss.Color(cToHex(c)))
-				outputString.WriteString(s.Render(string(r)))
-			} else {
-				outputString.WriteRune(r)
+	if renderOptions.RenderColor && renderOptions.ColorEstimator == ColorEstimatorInk {
+		glyphRunes := make([][]rune, rows)
+		for i := range glyphs {
+			glyphRunes[i] = make([]rune, cols)
+			for j := range glyphs[i] {
+				glyphRunes[i][j] = glyphs[i][j].Rune
 			}
 		}
-		outputString.WriteByte('\n')
+		cellColorGrid, err = buildStyledColorGrid(ctx, inputImg, cols, rows, ColorEstimatorInk, glyphRunes, luminanceGrid, renderOptions.ColorStyle)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return outputString.String()
-}
+	for i := range glyphs {
+		for j, glyph := range glyphs[i] {
+			cell := canvas.Cell{Rune: glyph.Rune, Attrs: glyph.Attrs}
+			if renderOptions.RenderColor {
+				cell.FG = cellColorGrid[i][j]
+				if glyph.FG.A > 0 {
+					cell.FG = glyph.FG
+				}
+				cell.BG = glyph.BG
+			}
+			output.Set(j, i, cell)
+			output.SetLuminance(j, i, luminanceGrid[i][j])
+		}
+	}
 
-func cToHex(c color.NRGBA) string {
-	const hex = "0123456789ABCDEF"
-	return string([]byte{
-		'#',
-		hex[c.R>>4], hex[c.R&0x0F],
-		hex[c.G>>4], hex[c.G&0x0F],
-		hex[c.B>>4], hex[c.B&0x0F],
-	})
+	_ = Logger().Info(fmt.Sprintf("Finished image conversion"))
+	return output, nil
 }
 
 // Calculates Columns and Rows for given TextSize and FontAspect
@@ -209,7 +224,7 @@ func getColsAndRows(img image.Image, textSize int, fontAspect float64) (cols, ro
 }
 
 // Builds a grid of averaged luminance values in [0..1].
-func buildLuminanceGrid(inputImg image.Image, cols, rows int, highContrast bool) ([][]float64, error) {
+func buildLuminanceGrid(ctx context.Context, inputImg image.Image, cols, rows int, highContrast bool) ([][]float64, error) {
 
 	imgBounds := inputImg.Bounds()
 	imgWidth, imgHeight := imgBounds.Dx(), imgBounds.Dy()
@@ -232,6 +247,10 @@ func buildLuminanceGrid(inputImg image.Image, cols, rows int, highContrast bool)
 	}
 
 	for gridRow := 0; gridRow < rows; gridRow++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Pixel Y-range for this grid row.
 		cellRowPixelStartY := gridRow * cellHeight
 		cellRowPixelEndY := cellRowPixelStartY + cellHeight
@@ -302,7 +321,23 @@ func buildLuminanceGrid(inputImg image.Image, cols, rows int, highContrast bool)
 	return grid, nil
 }
 
-func buildAverageColorGrid(inputImg image.Image, cols, rows int) [][]color.NRGBA {
+// Builds the cell color grid and applies the color style on top of it.
+func buildStyledColorGrid(ctx context.Context, inputImg image.Image, cols, rows int, estimator ColorEstimator, glyphs [][]rune, luminanceGrid [][]float64, style ColorStyle) ([][]color.NRGBA, error) {
+	colorGrid, err := buildCellColorGrid(ctx, inputImg, cols, rows, estimator, glyphs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range colorGrid {
+		for j := range colorGrid[i] {
+			colorGrid[i][j] = style.apply(colorGrid[i][j], luminanceGrid[i][j])
+		}
+	}
+	return colorGrid, nil
+}
+
+// Builds a grid with one color per cell using the selected estimator.
+// glyphs holds the runes chosen for each cell and is only needed by ColorEstimatorInk.
+func buildCellColorGrid(ctx context.Context, inputImg image.Image, cols, rows int, estimator ColorEstimator, glyphs [][]rune) ([][]color.NRGBA, error) {
 	imgBounds := inputImg.Bounds()
 	imgWidth, imgHeight := imgBounds.Dx(), imgBounds.Dy()
 
@@ -321,7 +356,13 @@ func buildAverageColorGrid(inputImg image.Image, cols, rows int) [][]color.NRGBA
 		colorGrid[gridRow] = make([]color.NRGBA, cols)
 	}
 
+	samples := make([]cellSample, 0, cellWidth*cellHeight)
+
 	for gridRow := 0; gridRow < rows; gridRow++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		// Pixel Y-range for this grid row.
 		cellRowPixelStartY := gridRow * cellHeight
 		cellRowPixelEndY := cellRowPixelStartY + cellHeight
@@ -349,9 +390,7 @@ func buildAverageColorGrid(inputImg image.Image, cols, rows int) [][]color.NRGBA
 				continue
 			}
 
-			var rSum, gSum, bSum float64
-			var sampleCount float64
-
+			samples = samples[:0]
 			for y := cellRowPixelStartY; y < cellRowPixelEndY; y++ {
 				for x := cellColPixelStartX; x < cellColPixelEndX; x++ {
 					c := color.NRGBAModel.Convert(
@@ -363,79 +402,23 @@ func buildAverageColorGrid(inputImg image.Image, cols, rows int) [][]color.NRGBA
 						continue
 					}
 
-					rSum += float64(c.R)
-					gSum += float64(c.G)
-					bSum += float64(c.B)
-					sampleCount++
+					samples = append(samples, cellSample{
+						c: c,
+						x: x - cellColPixelStartX,
+						y: y - cellRowPixelStartY,
+					})
 				}
 			}
 
-			if sampleCount == 0 {
-				colorGrid[gridRow][gridCol] = color.NRGBA{R: 0, G: 0, B: 0, A: 255}
-			} else {
-				colorGrid[gridRow][gridCol] = color.NRGBA{
-					R: uint8(rSum / sampleCount),
-					G: uint8(gSum / sampleCount),
-					B: uint8(bSum / sampleCount),
-					A: 255,
-				}
+			glyph := ' '
+			if gridRow < len(glyphs) && gridCol < len(glyphs[gridRow]) {
+				glyph = glyphs[gridRow][gridCol]
 			}
+			colorGrid[gridRow][gridCol] = estimateCellColor(samples, cellWidth, cellHeight, estimator, glyph)
 		}
 	}
 
-	return colorGrid
-}
-
-// Get the rune correspondent to luminance in selected ramp
-func getRuneForLuminanceValue(luminance float64, runeMode string, reverseChars bool) rune {
-	var ramp []rune
-
-	switch runeMode {
-	case "UNICODE":
-		if reverseChars {
-			ramp = []rune(unicodeRampBrightToDarkStr)
-		} else {
-			ramp = []rune(unicodeRampDarkToBrightStr)
-		}
-	case "DOTS":
-		if reverseChars {
-			ramp = []rune(dotsRampBrightToDarkStr)
-		} else {
-			ramp = []rune(dotsRampDarkToBrightStr)
-		}
-	case "RECTANGLES":
-		if reverseChars {
-			ramp = []rune(rectanglesRampBrightToDarkStr)
-		} else {
-			ramp = []rune(rectanglesRampDarkToBrightStr)
-		}
-	case "BARS":
-		if reverseChars {
-			ramp = []rune(barsRampBrightToDarkStr)
-		} else {
-			ramp = []rune(barsRampDarkToBrightStr)
-		}
-	default:
-	case "ASCII":
-		if reverseChars {
-			ramp = []rune(asciiRampBrightToDarkStr)
-		} else {
-			ramp = []rune(asciiRampDarkToBrightStr)
-		}
-
-	}
-
-	// Map luminance to an index in the ramp:
-	index := int(luminance * float64(len(ramp)-1))
-
-	_ = Logger().Info(
-		fmt.Sprintf(
-			"brightness: %.2f | character: %s | character index: %d",
-			luminance, string(ramp[index]), index,
-		),
-	)
-
-	return ramp[index]
+	return colorGrid, nil
 }
 
 // Clamp to [0..1] to keep mapping stable.
@@ -556,45 +539,6 @@ func applySobelFilter(luminanceGrid [][]float64, cellWidth, cellHeight float64)
 	return edgeInfos
 }
 
-// Get Rune if directionalRender is true intead of using luminance value
-func getEdgeRuneFromGradient(edge edgeInfo, runeMode string) rune {
-	// Sobel angle is gradient direction;
-	// edge orientation is perpendicular.
-	angle := edge.Angle + (math.Pi / 2)
-	if angle < 0 {
-		angle += 2 * math.Pi
-	}
-
-	// Normalize into 0..Pi (edges are symmetric — 0 and Pi are the same edge direction)
-	if angle >= math.Pi {
-		angle -= math.Pi
-	}
-
-	if runeMode != "ASCII" {
-		switch {
-		case angle < math.Pi/8 || angle >= 7*math.Pi/8:
-			return '─'
-		case angle < 3*math.Pi/8:
-			return '╲'
-		case angle < 5*math.Pi/8:
-			return '│'
-		default:
-			return '╱'
-		}
-	}
-
-	switch {
-	case angle < math.Pi/8 || angle >= 7*math.Pi/8:
-		return '-'
-	case angle < 3*math.Pi/8:
-		return '\\'
-	case angle < 5*math.Pi/8:
-		return '|'
-	default:
-		return '/'
-	}
-}
-
 // Apply difference fo Gaussians to help with edge detections
 func differenceOfGaussiansGrid(luminanceGrid [][]float64, sigma1, sigma2 float64) [][]float64 {
 	rows := len(luminanceGrid)
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+)
+
+// CellSample is the sampled data of one grid cell handed to a Renderer.
+type CellSample struct {
+	// Luminance: averaged cell luminance in [0..1], after the optional contrast curve.
+	Luminance float64
+	// Color: estimated cell color (with ColorStyle applied). Zero when RenderColor is off.
+	Color color.NRGBA
+	// EdgeMagnitude, EdgeAngle: normalized Sobel gradient of the cell, only filled when directional render is on.
+	EdgeMagnitude float64
+	EdgeAngle     float64
+	// IsEdge: directional render is on and EdgeMagnitude is above the edge threshold.
+	IsEdge bool
+	// ReverseChars: user asked for the bright to dark ramp direction.
+	ReverseChars bool
+}
+
+// Glyph is what a Renderer produces for one cell.
+type Glyph struct {
+	Rune rune
+	// FG: foreground override, a zero alpha keeps the sampled cell color.
+	FG color.NRGBA
+	// BG: optional cell background, a zero alpha means no background.
+	BG color.NRGBA
+	// Attrs: optional bold/underline attributes.
+	Attrs canvas.Attr
+}
+
+// Renderer turns sampled cell data into a glyph. Renderers are registered by name
+// and selected through the rune mode of RenderOptions.
+type Renderer interface {
+	Name() string
+	Description() string
+	Render(sample CellSample) Glyph
+}
+
+// RampRenderer is implemented by renderers that map luminance onto a ramp of glyphs.
+type RampRenderer interface {
+	Renderer
+	// Ramp returns the glyphs from dark to bright.
+	Ramp() string
+}
+
+var (
+	rendererRegistryMu sync.RWMutex
+	rendererRegistry   = map[string]Renderer{}
+	rendererOrder      []string
+)
+
+// RegisterRenderer makes r selectable as a rune mode. Names are case-sensitive and must be unique.
+func RegisterRenderer(r Renderer) error {
+	if r == nil {
+		return fmt.Errorf("renderer is nil")
+	}
+	name := r.Name()
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("renderer name is empty")
+	}
+
+	rendererRegistryMu.Lock()
+	defer rendererRegistryMu.Unlock()
+
+	if _, exists := rendererRegistry[name]; exists {
+		return fmt.Errorf("renderer already registered: %s", name)
+	}
+	rendererRegistry[name] = r
+	rendererOrder = append(rendererOrder, name)
+	return nil
+}
+
+func mustRegisterRenderer(r Renderer) {
+	if err := RegisterRenderer(r); err != nil {
+		panic(err)
+	}
+}
+
+func LookupRenderer(name string) (Renderer, bool) {
+	rendererRegistryMu.RLock()
+	defer rendererRegistryMu.RUnlock()
+
+	r, ok := rendererRegistry[name]
+	return r, ok
+}
+
+// RendererNames lists registered renderers in registration order, built-in modes first.
+func RendererNames() []string {
+	rendererRegistryMu.RLock()
+	defer rendererRegistryMu.RUnlock()
+
+	return slices.Clone(rendererOrder)
+}
+
+// Renderers lists registered renderers in registration order.
+func Renderers() []Renderer {
+	rendererRegistryMu.RLock()
+	defer rendererRegistryMu.RUnlock()
+
+	renderers := make([]Renderer, 0, len(rendererOrder))
+	for _, name := range rendererOrder {
+		renderers = append(renderers, rendererRegistry[name])
+	}
+	return renderers
+}
+
+// EdgeRunes are the glyphs used on edges, indexed by edge orientation.
+type EdgeRunes struct {
+	Horizontal, Diagonal, Vertical, AntiDiagonal rune
+}
+
+var asciiEdgeRunes = EdgeRunes{Horizontal: '-', Diagonal: '\\', Vertical: '|', AntiDiagonal: '/'}
+var boxEdgeRunes = EdgeRunes{Horizontal: '─', Diagonal: '╲', Vertical: '│', AntiDiagonal: '╱'}
+var cp437EdgeRunes = EdgeRunes{Horizontal: '─', Diagonal: '\\', Vertical: '│', AntiDiagonal: '/'}
+
+type rampRenderer struct {
+	name         string
+	description  string
+	darkToBright []rune
+	brightToDark []rune
+	edgeRunes    EdgeRunes
+}
+
+// NewRampRenderer builds a Renderer that maps luminance onto a ramp of glyphs
+// (given from dark to bright) and uses edgeRunes on directional edges.
+func NewRampRenderer(name, description, darkToBright string, edgeRunes EdgeRunes) (Renderer, error) {
+	ramp := []rune(darkToBright)
+	if len(ramp) == 0 {
+		return nil, fmt.Errorf("ramp for renderer %s is empty", name)
+	}
+
+	reversed := slices.Clone(ramp)
+	slices.Reverse(reversed)
+
+	return &rampRenderer{
+		name:         name,
+		description:  description,
+		darkToBright: ramp,
+		brightToDark: reversed,
+		edgeRunes:    edgeRunes,
+	}, nil
+}
+
+func mustRampRenderer(name, description, darkToBright string, edgeRunes EdgeRunes) Renderer {
+	r, err := NewRampRenderer(name, description, darkToBright, edgeRunes)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r *rampRenderer) Name() string        { return r.name }
+func (r *rampRenderer) Description() string { return r.description }
+func (r *rampRenderer) Ramp() string        { return string(r.darkToBright) }
+
+func (r *rampRenderer) Render(sample CellSample) Glyph {
+	if sample.IsEdge {
+		if edgeRune := getEdgeRuneFromGradient(sample.EdgeAngle, r.edgeRunes); edgeRune != ' ' {
+			return Glyph{Rune: edgeRune}
+		}
+	}
+
+	ramp := r.darkToBright
+	if sample.ReverseChars {
+		ramp = r.brightToDark
+	}
+	return Glyph{Rune: getRuneForLuminanceValue(sample.Luminance, ramp)}
+}
+
+// Get the rune correspondent to luminance in selected ramp
+func getRuneForLuminanceValue(luminance float64, ramp []rune) rune {
+	// Map luminance to an index in the ramp:
+	index := int(clamp01(luminance) * float64(len(ramp)-1))
+
+	_ = Logger().Info(
+		fmt.Sprintf(
+			"brightness: %.2f | character: %s | character index: %d",
+			luminance, string(ramp[index]), index,
+		),
+	)
+
+	return ramp[index]
+}
+
+// Get Rune if directionalRender is true intead of using luminance value
+func getEdgeRuneFromGradient(gradientAngle float64, edgeRunes EdgeRunes) rune {
+	// Sobel angle is gradient direction;
+	// edge orientation is perpendicular.
+	angle := gradientAngle + (math.Pi / 2)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+
+	// Normalize into 0..Pi (edges are symmetric — 0 and Pi are the same edge direction)
+	if angle >= math.Pi {
+		angle -= math.Pi
+	}
+
+	switch {
+	case angle < math.Pi/8 || angle >= 7*math.Pi/8:
+		return edgeRunes.Horizontal
+	case angle < 3*math.Pi/8:
+		return edgeRunes.Diagonal
+	case angle < 5*math.Pi/8:
+		return edgeRunes.Vertical
+	default:
+		return edgeRunes.AntiDiagonal
+	}
+}
+
+// Built-in rune modes, Dark to Bright
+func init() {
+	mustRegisterRenderer(mustRampRenderer("ASCII", "Printable ASCII characters.",
+		"$@B%8&WM#*oahkbdpqwmZO0QLCJUYXzcvunxrjtf()1{}[]?_+~<>i!lI;:,^`. ", asciiEdgeRunes))
+	mustRegisterRenderer(mustRampRenderer("UNICODE", "Shades, squares and ASCII symbols.",
+		"█▓▒░■□@&%$#*+=~:;!,\".^`' ", boxEdgeRunes))
+	mustRegisterRenderer(mustRampRenderer("DOTS", "Dots of increasing size.",
+		"●∙•· ", boxEdgeRunes))
+	mustRegisterRenderer(mustRampRenderer("RECTANGLES", "Full block and shade characters.",
+		"█▓▒░ ", boxEdgeRunes))
+	mustRegisterRenderer(mustRampRenderer("BARS", "Vertical eighth blocks.",
+		"█▇▆▅▄▃▂▁ ", boxEdgeRunes))
+	mustRegisterRenderer(mustRampRenderer("CP437", "Code page 437 shades and symbols, for ANSI art.",
+		"█▓▒░■#@&%$*+=≡÷~:;∙·,. ", cp437EdgeRunes))
+}
@@ -5,13 +5,10 @@ import (
 	"image/color"
 	"os"
 	"path/filepath"
-	"strings"
 	"testing"
 
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
 	"github.com/joaoheitorgarcia/Mezzotone/internal/services"
-
-	"charm.land/lipgloss/v2"
-	"github.com/charmbracelet/colorprofile"
 )
 
 func mustRenderOptions(
@@ -46,17 +43,20 @@ func mustConvertImageToString(t *testing.T, imagePath string, opts services.Rend
 		t.Fatalf("failed decoding image: %v", err)
 	}
 
-	out, colors, err := services.ConvertImageToString(inputImg, opts)
+	out, err := services.ConvertImageToString(inputImg, opts)
 	if err != nil {
 		t.Fatalf("conversion failed: %v", err)
 	}
-	if len(out) == 0 {
+	if out.Height() == 0 {
 		t.Fatalf("expected non-empty rune grid")
 	}
-	if len(out[0]) == 0 {
+	if out.Width() == 0 {
 		t.Fatalf("expected non-empty rune grid row")
 	}
-	return services.ImageRuneArrayIntoString(out, colors, opts.RenderColor)
+	if opts.RenderColor {
+		return out.ANSI()
+	}
+	return out.PlainText()
 }
 
 func TestNewRenderOptionsRejectsInvalidRuneMode(t *testing.T) {
@@ -161,19 +161,6 @@ func TestConvertImageToStringFileErrors(t *testing.T) {
 	})
 }
 
-func TestImageRuneArrayIntoStringAddsLineBreaks(t *testing.T) {
-	in := [][]rune{
-		[]rune("ab"),
-		[]rune("cd"),
-	}
-
-	out := services.ImageRuneArrayIntoString(in, nil, false)
-	expected := "ab\ncd\n"
-	if out != expected {
-		t.Fatalf("expected %q, got %q", expected, out)
-	}
-}
-
 func TestConvertImageToStringRenderColorBuildsAverageColorGrid(t *testing.T) {
 	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
 	expected := color.NRGBA{R: 12, G: 34, B: 56, A: 255}
@@ -184,10 +171,11 @@ func TestConvertImageToStringRenderColorBuildsAverageColorGrid(t *testing.T) {
 	}
 
 	opts := mustRenderOptions(t, 8, 2.0, false, 0.6, false, false, true, "ASCII")
-	runes, colors, err := services.ConvertImageToString(img, opts)
+	out, err := services.ConvertImageToString(img, opts)
 	if err != nil {
 		t.Fatalf("conversion failed: %v", err)
 	}
+	runes, colors := out.Runes(), out.Colors()
 	if len(runes) != 1 || len(runes[0]) != 1 {
 		t.Fatalf("expected 1x1 rune grid, got %dx%d", len(runes), len(runes[0]))
 	}
@@ -200,22 +188,254 @@ func TestConvertImageToStringRenderColorBuildsAverageColorGrid(t *testing.T) {
 	}
 }
 
-func TestImageRuneArrayIntoStringRenderColorAddsANSIForeground(t *testing.T) {
-	in := [][]rune{{'X'}}
-	colors := [][]color.NRGBA{{{R: 255, G: 0, B: 0, A: 255}}}
+func TestConvertImageToStringColorModesMapLuminanceThroughStops(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			if x < 8 {
+				img.SetNRGBA(x, y, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+			} else {
+				img.SetNRGBA(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+			}
+		}
+	}
+
+	shadow := color.NRGBA{R: 10, G: 20, B: 30, A: 255}
+	highlight := color.NRGBA{R: 240, G: 200, B: 100, A: 255}
+
+	cases := []struct {
+		name      string
+		mode      string
+		stops     []color.NRGBA
+		wantDark  color.NRGBA
+		wantLight color.NRGBA
+	}{
+		{
+			name:      "tint",
+			mode:      services.ColorModeTint,
+			stops:     []color.NRGBA{highlight},
+			wantDark:  color.NRGBA{A: 255},
+			wantLight: highlight,
+		},
+		{
+			name:      "duotone",
+			mode:      services.ColorModeDuotone,
+			stops:     []color.NRGBA{shadow, highlight},
+			wantDark:  shadow,
+			wantLight: highlight,
+		},
+		{
+			name:      "gradient",
+			mode:      services.ColorModeGradient,
+			stops:     []color.NRGBA{shadow, {R: 128, G: 0, B: 0, A: 255}, highlight},
+			wantDark:  shadow,
+			wantLight: highlight,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := mustRenderOptions(t, 8, 1.0, false, 0.6, false, false, true, "ASCII")
+			style, err := services.NewColorStyle(tc.mode, tc.stops, 1, 0)
+			if err != nil {
+				t.Fatalf("NewColorStyle failed: %v", err)
+			}
+			opts.ColorStyle = style
+
+			out, err := services.ConvertImageToString(img, opts)
+			if err != nil {
+				t.Fatalf("conversion failed: %v", err)
+			}
+			colors := out.Colors()
+			if len(colors) != 1 || len(colors[0]) != 2 {
+				t.Fatalf("expected 1x2 color grid, got %v", colors)
+			}
+			if got := colors[0][0]; got != tc.wantDark {
+				t.Fatalf("dark cell: expected %v, got %v", tc.wantDark, got)
+			}
+			if got := colors[0][1]; got != tc.wantLight {
+				t.Fatalf("light cell: expected %v, got %v", tc.wantLight, got)
+			}
+		})
+	}
+}
+
+func TestConvertImageToStringZeroSaturationIsGrayscale(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+
+	opts := mustRenderOptions(t, 8, 1.0, false, 0.6, false, false, true, "ASCII")
+	style, err := services.NewColorStyle(services.ColorModeAverage, nil, 0, 0)
+	if err != nil {
+		t.Fatalf("NewColorStyle failed: %v", err)
+	}
+	opts.ColorStyle = style
+
+	out, err := services.ConvertImageToString(img, opts)
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+	colors := out.Colors()
+	got := colors[0][0]
+	if got.R != got.G || got.G != got.B {
+		t.Fatalf("expected grayscale color, got %v", got)
+	}
+}
+
+func TestNewColorStyleValidatesStops(t *testing.T) {
+	if _, err := services.NewColorStyle("INVALID", nil, 1, 0); err == nil {
+		t.Fatalf("expected error for invalid color mode")
+	}
+	if _, err := services.NewColorStyle(services.ColorModeDuotone, []color.NRGBA{{A: 255}}, 1, 0); err == nil {
+		t.Fatalf("expected error for duotone with a single stop")
+	}
+	if _, err := services.NewColorStyle(services.ColorModeAverage, nil, 1, 2); err == nil {
+		t.Fatalf("expected error for out of range vibrance")
+	}
+}
+
+func TestParseHexColorList(t *testing.T) {
+	got, err := services.ParseHexColorList("#000, 33ff66")
+	if err != nil {
+		t.Fatalf("ParseHexColorList failed: %v", err)
+	}
+	want := []color.NRGBA{{A: 255}, {R: 0x33, G: 0xFF, B: 0x66, A: 255}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
 
-	lipgloss.Writer.Profile = colorprofile.TrueColor
+	if _, err := services.ParseHexColorList("#12345"); err == nil {
+		t.Fatalf("expected error for malformed hex color")
+	}
+}
 
-	plain := services.ImageRuneArrayIntoString(in, colors, false)
-	if plain != "X\n" {
-		t.Fatalf("expected plain output %q, got %q", "X\n", plain)
+func TestConvertImageToStringColorEstimators(t *testing.T) {
+	// Left 3/4 of the cell is red, right 1/4 is blue: the mean is a muddy purple.
+	red := color.NRGBA{R: 255, G: 0, B: 0, A: 255}
+	blue := color.NRGBA{R: 0, G: 0, B: 255, A: 255}
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if x < 6 {
+				img.SetNRGBA(x, y, red)
+			} else {
+				img.SetNRGBA(x, y, blue)
+			}
+		}
 	}
+	img.SetNRGBA(0, 0, color.NRGBA{R: 200, G: 0, B: 200, A: 255})
+
+	convert := func(estimator services.ColorEstimator) color.NRGBA {
+		t.Helper()
+		opts := mustRenderOptions(t, 8, 1.0, false, 0.6, false, false, true, "ASCII")
+		opts.ColorEstimator = estimator
+		out, err := services.ConvertImageToString(img, opts)
+		if err != nil {
+			t.Fatalf("conversion failed: %v", err)
+		}
+		colors := out.Colors()
+		return colors[0][0]
+	}
+
+	if got := convert(services.ColorEstimatorMean); got.B == 0 {
+		t.Fatalf("expected mean to mix in blue, got %v", got)
+	}
+	if got := convert(services.ColorEstimatorMedian); got != red {
+		t.Fatalf("expected median to be red, got %v", got)
+	}
+	if got := convert(services.ColorEstimatorSaturated); got != red && got != blue {
+		t.Fatalf("expected most saturated pixel to be a pure color, got %v", got)
+	}
+	if got := convert(services.ColorEstimatorDominant); got.R < 240 || got.B > 15 {
+		t.Fatalf("expected dominant cluster to be red, got %v", got)
+	}
+	if got := convert(services.ColorEstimatorInk); got.A != 255 {
+		t.Fatalf("expected opaque ink sampled color, got %v", got)
+	}
+}
+
+func TestParseColorEstimator(t *testing.T) {
+	if got, err := services.ParseColorEstimator(""); err != nil || got != services.ColorEstimatorMean {
+		t.Fatalf("expected empty estimator to default to MEAN, got %q (%v)", got, err)
+	}
+	if _, err := services.ParseColorEstimator("MODE"); err == nil {
+		t.Fatalf("expected error for invalid estimator")
+	}
+}
 
-	colored := services.ImageRuneArrayIntoString(in, colors, true)
-	if !strings.Contains(colored, "\x1b[38;2;255;0;0m") {
-		t.Fatalf("expected ANSI truecolor foreground in output, got %q", colored)
+type solidTestRenderer struct{}
+
+func (solidTestRenderer) Name() string        { return "TEST_SOLID" }
+func (solidTestRenderer) Description() string { return "Test renderer drawing a fixed glyph." }
+func (solidTestRenderer) Render(sample services.CellSample) services.Glyph {
+	return services.Glyph{
+		Rune:  'Z',
+		FG:    color.NRGBA{R: 1, G: 2, B: 3, A: 255},
+		BG:    color.NRGBA{R: 4, G: 5, B: 6, A: 255},
+		Attrs: canvas.AttrBold,
+	}
+}
+
+func TestRegisteredRendererIsSelectableAsRuneMode(t *testing.T) {
+	if _, ok := services.LookupRenderer("TEST_SOLID"); !ok {
+		if err := services.RegisterRenderer(solidTestRenderer{}); err != nil {
+			t.Fatalf("RegisterRenderer failed: %v", err)
+		}
+	}
+	if err := services.RegisterRenderer(solidTestRenderer{}); err == nil {
+		t.Fatalf("expected error when registering a duplicate renderer name")
+	}
+
+	found := false
+	for _, name := range services.RendererNames() {
+		if name == "TEST_SOLID" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected registered renderer in RendererNames, got %v", services.RendererNames())
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	opts := mustRenderOptions(t, 8, 1.0, false, 0.6, false, false, true, "TEST_SOLID")
+	out, err := services.ConvertImageToString(img, opts)
+	if err != nil {
+		t.Fatalf("conversion failed: %v", err)
+	}
+	runes, colors := out.Runes(), out.Colors()
+	for i := range runes {
+		for j := range runes[i] {
+			if runes[i][j] != 'Z' {
+				t.Fatalf("expected renderer glyph 'Z' at %d,%d, got %q", i, j, runes[i][j])
+			}
+			if want := (color.NRGBA{R: 1, G: 2, B: 3, A: 255}); colors[i][j] != want {
+				t.Fatalf("expected renderer foreground %v at %d,%d, got %v", want, i, j, colors[i][j])
+			}
+			cell := out.At(j, i)
+			if want := (color.NRGBA{R: 4, G: 5, B: 6, A: 255}); cell.BG != want || cell.Attrs != canvas.AttrBold {
+				t.Fatalf("expected renderer background %v and bold at %d,%d, got %v / %v", want, i, j, cell.BG, cell.Attrs)
+			}
+		}
+	}
+}
+
+func TestNewRampRendererReversesRamp(t *testing.T) {
+	r, err := services.NewRampRenderer("TEST_RAMP", "", "#. ", services.EdgeRunes{})
+	if err != nil {
+		t.Fatalf("NewRampRenderer failed: %v", err)
+	}
+
+	if got := r.Render(services.CellSample{Luminance: 0}).Rune; got != '#' {
+		t.Fatalf("expected dark glyph '#', got %q", got)
+	}
+	if got := r.Render(services.CellSample{Luminance: 0, ReverseChars: true}).Rune; got != ' ' {
+		t.Fatalf("expected reversed dark glyph ' ', got %q", got)
 	}
-	if !strings.Contains(colored, "X") {
-		t.Fatalf("expected rendered rune in colored output, got %q", colored)
+	if _, err := services.NewRampRenderer("EMPTY", "", "", services.EdgeRunes{}); err == nil {
+		t.Fatalf("expected error for empty ramp")
 	}
 }
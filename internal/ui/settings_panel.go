@@ -19,6 +19,7 @@ const (
 	TypeFloat
 	TypeBool
 	TypeEnum
+	TypeString
 )
 
 type SettingItem struct {
@@ -27,6 +28,8 @@ type SettingItem struct {
 	Label string
 	Value string
 	Enum  []string
+	// Hidden items keep their value but are not shown or reachable with the cursor.
+	Hidden bool
 }
 
 type RenderSettingsStyles struct {
@@ -57,7 +60,7 @@ type SettingsPanel struct {
 func NewSettingsPanel(title string, items []SettingItem, styles RenderSettingsStyles) SettingsPanel {
 	ti := textinput.New()
 	ti.Prompt = ""
-	ti.CharLimit = 64
+	ti.CharLimit = 256
 
 	return SettingsPanel{
 		Title:  title,
@@ -118,24 +121,12 @@ func (m *SettingsPanel) Update(msg tea.Msg) (SettingsPanel, tea.Cmd) {
 
 		switch msg.String() {
 		case "up", "k":
-			if m.cursor > 0 {
-				m.Confirm = false
-				m.cursor--
-			}
-			if m.cursor == len(m.Items) {
-				m.Confirm = true
-			}
+			m.moveCursor(-1)
 			m.errMsg = ""
 			return *m, nil
 
 		case "down", "j":
-			if m.cursor < len(m.Items) {
-				m.Confirm = false
-				m.cursor++
-			}
-			if m.cursor == len(m.Items) {
-				m.Confirm = true
-			}
+			m.moveCursor(+1)
 			m.errMsg = ""
 			return *m, nil
 
@@ -196,6 +187,9 @@ func (m *SettingsPanel) View() string {
 	lines := []string{m.Styles.TitleStyle.Render(termtext.TruncateLinesANSI(strings.ToUpper(m.Title), labelW)), ""}
 
 	for i, it := range m.Items {
+		if it.Hidden {
+			continue
+		}
 		val := it.Value
 		if m.Editing && i == m.cursor {
 			m.input.SetWidth(valueW)
@@ -228,6 +222,19 @@ func (m *SettingsPanel) View() string {
 	return m.Styles.BoxStyle.Render(strings.Join(lines, "\n"))
 }
 
+// moveCursor steps the cursor by dir over the visible items, the confirm row follows the last item.
+func (m *SettingsPanel) moveCursor(dir int) {
+	i := m.cursor + dir
+	for i >= 0 && i < len(m.Items) && m.Items[i].Hidden {
+		i += dir
+	}
+	if i < 0 || i > len(m.Items) {
+		return
+	}
+	m.cursor = i
+	m.Confirm = m.cursor == len(m.Items)
+}
+
 func (m *SettingsPanel) toggleBool() {
 	it, ok := m.currentItem()
 	if !ok {
@@ -297,6 +304,10 @@ func validateAndSet(it *SettingItem, raw string) error {
 		}
 		return fmt.Errorf("must be one of: %s", strings.Join(it.Enum, ", "))
 
+	case TypeString:
+		it.Value = raw
+		return nil
+
 	default:
 		it.Value = raw
 		return nil
@@ -333,10 +344,25 @@ func (m *SettingsPanel) ClearActive() {
 	m.cursor = -1
 }
 
+// SetActive moves the cursor to item i, or to the first visible item after it.
 func (m *SettingsPanel) SetActive(i int) {
+	for i >= 0 && i < len(m.Items) && m.Items[i].Hidden {
+		i++
+	}
 	m.cursor = i
 }
 
+// VisibleItems counts the items that are not hidden.
+func (m *SettingsPanel) VisibleItems() int {
+	n := 0
+	for _, it := range m.Items {
+		if !it.Hidden {
+			n++
+		}
+	}
+	return n
+}
+
 func (m *SettingsPanel) ErrorMessage() string {
 	return m.errMsg
 }
@@ -1,6 +1,7 @@
 package ui_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/joaoheitorgarcia/Mezzotone/internal/ui"
@@ -62,6 +63,41 @@ func TestSettingsPanelConfirmFlagTracksConfirmRow(t *testing.T) {
 	}
 }
 
+func TestSettingsPanelSkipsHiddenItems(t *testing.T) {
+	m := newRenderSettingsPanelForTests()
+	m.Items[0].Hidden = true
+	m.Items[2].Hidden = true
+	m.SetWidth(60)
+
+	m.SetActive(0)
+	m, _ = m.Update(key(tea.KeyEnter))
+	if !m.Editing {
+		t.Fatalf("expected the first visible item (float) to start editing")
+	}
+	m, _ = m.Update(key(tea.KeyEscape))
+
+	m, _ = m.Update(keyRunes("j"))
+	m, _ = m.Update(key(tea.KeyEnter))
+	if got := m.Items[3].Value; got != "UNICODE" {
+		t.Fatalf("expected down to skip the hidden bool and cycle the enum, got %q", got)
+	}
+	m, _ = m.Update(keyRunes("k"))
+	m, _ = m.Update(keyRunes("k"))
+	m, _ = m.Update(keyRunes("j"))
+	m, _ = m.Update(keyRunes("j"))
+	if !m.Confirm {
+		t.Fatalf("expected two visible rows between the first item and confirm")
+	}
+
+	view := m.View()
+	if strings.Contains(view, "Text Size") || strings.Contains(view, "Directional Render") || !strings.Contains(view, "Font Aspect") {
+		t.Fatalf("expected hidden items to be left out of the view, got %q", view)
+	}
+	if m.VisibleItems() != 2 {
+		t.Fatalf("expected 2 visible items, got %d", m.VisibleItems())
+	}
+}
+
 func TestSettingsPanelEnterStartsEditingForInt(t *testing.T) {
 	m := newRenderSettingsPanelForTests()
 	m.SetActive(0)
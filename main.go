@@ -4,17 +4,42 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/joaoheitorgarcia/Mezzotone/internal/app"
 	"github.com/joaoheitorgarcia/Mezzotone/internal/services"
+	"github.com/joaoheitorgarcia/Mezzotone/mezzotone"
 
 	tea "charm.land/bubbletea/v2"
 )
 
 func main() {
 	debug := flag.Bool("debug", false, "enable debug logging")
-	fontTTF := flag.String("font-ttf", "", "path to a .ttf font used for image/gif export rendering")
+	fontTTF := flag.String("font-ttf", "", "path to a .ttf font used for image/gif/apng export rendering")
+	fontFallback := flag.String("font-fallback", "", "fallback .ttf/.otf/.ttc fonts for runes the export font lacks, separated like PATH")
+	runeMode := flag.String("rune-mode", "ASCII", "default rune mode, one of: "+strings.Join(mezzotone.RuneModes(), ", "))
+	listRuneModes := flag.Bool("list-rune-modes", false, "print the available rune modes and exit")
+	input := flag.String("input", "", "open an image, gif, apng, animated webp, y4m, frame directory, glob (frames/*.png) or pattern (frame_%04d.png) directly")
+	fps := flag.Float64("fps", 12, "playback rate of image sequences")
+	outputDir := flag.String("output-dir", "", "directory exports are written to (default: home directory)")
+	fileName := flag.String("filename", "", "export file name template: {name} {mode} {cols} {rows} {date} {n} {uuid}")
+	exportAPNG := flag.String("export-apng", "", "render -input to this animated png and exit without opening the TUI")
 	flag.Parse()
+
+	if *listRuneModes {
+		for _, renderer := range mezzotone.Renderers() {
+			fmt.Printf("%-12s %s\n", renderer.Name(), renderer.Description())
+		}
+		return
+	}
+	if _, ok := mezzotone.LookupRenderer(*runeMode); !ok {
+		fmt.Printf("Unknown rune mode %q. Available: %s\n", *runeMode, strings.Join(mezzotone.RuneModes(), ", "))
+		os.Exit(2)
+	}
+	if *fps <= 0 {
+		fmt.Printf("Invalid fps %v, must be > 0\n", *fps)
+		os.Exit(2)
+	}
 	if *debug {
 		err := services.InitLogger("logs.log")
 		if err != nil {
@@ -22,9 +47,30 @@ func main() {
 		}
 	}
 
-	p := tea.NewProgram(app.NewMezzotoneModelWithConfig(app.MezzotoneModelConfig{
-		ExportFontTTFPath: *fontTTF,
-	}))
+	config := app.MezzotoneModelConfig{
+		ExportFontTTFPath:   *fontTTF,
+		ExportFallbackFonts: *fontFallback,
+		DefaultRuneMode:     *runeMode,
+		InputPath:           *input,
+		SequenceFPS:         *fps,
+		OutputDir:           *outputDir,
+		FileNameTemplate:    *fileName,
+	}
+
+	if *exportAPNG != "" {
+		if *input == "" {
+			fmt.Printf("-export-apng requires -input\n")
+			os.Exit(2)
+		}
+		if err := app.ExportAPNG(config, *exportAPNG); err != nil {
+			fmt.Printf("Export failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Successfully exported to %s !\n", *exportAPNG)
+		return
+	}
+
+	p := tea.NewProgram(app.NewMezzotoneModelWithConfig(config))
 	if _, err := p.Run(); err != nil {
 		_ = services.Logger().Error("Unexpected Error. Unable to recover")
 		fmt.Printf("An unexpected error has occurred.\n")
@@ -0,0 +1,28 @@
+package mezzotone
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNilImage is returned when Convert is called without an image.
+	ErrNilImage = errors.New("mezzotone: image is nil")
+	// ErrInvalidOption is matched (errors.Is) by every *OptionError.
+	ErrInvalidOption = errors.New("mezzotone: invalid option")
+)
+
+// OptionError reports an option value rejected by Validate or Convert.
+type OptionError struct {
+	Option string
+	Value  any
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("mezzotone: invalid %s %v: %v", e.Option, e.Value, e.Err)
+}
+
+func (e *OptionError) Unwrap() error { return e.Err }
+
+func (e *OptionError) Is(target error) bool { return target == ErrInvalidOption }
@@ -0,0 +1,52 @@
+package mezzotone_test
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/joaoheitorgarcia/Mezzotone/mezzotone"
+)
+
+func ExampleConvert() {
+	// A 20x20 image split into a black and a white half.
+	img := image.NewGray(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 10; x < 20; x++ {
+			img.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	art, err := mezzotone.Convert(img,
+		mezzotone.WithTextSize(5),
+		mezzotone.WithFontAspect(2),
+		mezzotone.WithReverseChars(false),
+		mezzotone.WithHighContrast(false),
+	)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(art.PlainText())
+	// Output:
+	// $$..
+	// $$..
+}
+
+func ExampleRegisterRenderer() {
+	blocks, err := mezzotone.NewRampRenderer("EXAMPLE_BLOCKS", "Full and half blocks.", "█▄ ", mezzotone.EdgeRunes{})
+	if err != nil {
+		panic(err)
+	}
+	if err := mezzotone.RegisterRenderer(blocks); err != nil {
+		panic(err)
+	}
+
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	art, err := mezzotone.Convert(img, mezzotone.WithTextSize(4), mezzotone.WithFontAspect(1), mezzotone.WithRuneMode("EXAMPLE_BLOCKS"), mezzotone.WithReverseChars(false))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(art.PlainText())
+	// Output:
+	// █
+}
@@ -0,0 +1,154 @@
+// Package mezzotone converts images into ASCII/Unicode art.
+//
+// It is the library behind the Mezzotone TUI:
+//
+//	art, err := mezzotone.Convert(img, mezzotone.WithTextSize(8), mezzotone.WithRuneMode("UNICODE"))
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Print(art.String())
+//
+// Exported identifiers follow semantic versioning, see Version. While the major version is 0 the API
+// may still change: a breaking change bumps the minor version, anything else bumps the patch version.
+// 1.0.0 freezes the API, after that only a new major version may break it.
+package mezzotone
+
+import (
+	"context"
+	"image"
+	"image/color"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/canvas"
+	"github.com/joaoheitorgarcia/Mezzotone/internal/services"
+)
+
+// Version of the library API, bumped together with the package as described in the package comment.
+const Version = "0.1.0"
+
+// Result is a converted image: a canvas of glyphs and, when color is enabled, one color per glyph.
+type Result struct {
+	canvas      *canvas.Canvas
+	renderColor bool
+}
+
+// Convert turns img into a glyph grid. Options are applied on top of DefaultOptions.
+func Convert(img image.Image, opts ...Option) (*Result, error) {
+	return ConvertContext(context.Background(), img, opts...)
+}
+
+// ConvertContext is Convert with cancellation, a canceled conversion returns ctx.Err().
+func ConvertContext(ctx context.Context, img image.Image, opts ...Option) (*Result, error) {
+	if img == nil {
+		return nil, ErrNilImage
+	}
+
+	options := DefaultOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	renderOptions, err := options.renderOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := services.ConvertImageToStringContext(ctx, img, renderOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		canvas:      c,
+		renderColor: options.renderColor,
+	}, nil
+}
+
+// Cols is the grid width in characters.
+func (r *Result) Cols() int { return r.canvas.Width() }
+
+// Rows is the grid height in characters.
+func (r *Result) Rows() int { return r.canvas.Height() }
+
+// Canvas returns the converted cells. The canvas is owned by the Result.
+func (r *Result) Canvas() *Canvas { return r.canvas }
+
+// Runes returns a copy of the glyph grid indexed [row][col].
+func (r *Result) Runes() [][]rune { return r.canvas.Runes() }
+
+// Colors returns a copy of the color grid indexed [row][col].
+func (r *Result) Colors() [][]color.NRGBA { return r.canvas.Colors() }
+
+// HasColor reports whether the Result was converted with color.
+func (r *Result) HasColor() bool { return r.renderColor }
+
+// PlainText returns the glyphs as text, one line per row, without escape sequences.
+func (r *Result) PlainText() string { return r.canvas.PlainText() }
+
+// String returns the art as it is shown in a terminal, with ANSI colors when HasColor.
+func (r *Result) String() string {
+	if !r.renderColor {
+		return r.canvas.PlainText()
+	}
+	return r.canvas.ANSI()
+}
+
+// Canvas is a fixed size grid of cells, it serializes to text, ANSI and images.
+type Canvas = canvas.Canvas
+
+// Cell is one character position of a Canvas.
+type Cell = canvas.Cell
+
+// Attr is a set of text attributes of a Cell.
+type Attr = canvas.Attr
+
+const (
+	AttrBold      = canvas.AttrBold
+	AttrUnderline = canvas.AttrUnderline
+)
+
+// NewCanvas returns a width x height canvas filled with spaces.
+func NewCanvas(width, height int) *Canvas { return canvas.New(width, height) }
+
+// Renderer turns sampled cell data into a glyph, see RegisterRenderer.
+type Renderer = services.Renderer
+
+// RampRenderer is a Renderer built from a luminance ramp, like the ones NewRampRenderer returns.
+type RampRenderer = services.RampRenderer
+
+// CellSample is the data of one cell handed to a Renderer.
+type CellSample = services.CellSample
+
+// Glyph is the output of a Renderer for one cell.
+type Glyph = services.Glyph
+
+// EdgeRunes are the glyphs a ramp renderer places on edges.
+type EdgeRunes = services.EdgeRunes
+
+// RegisterRenderer adds a rune mode. It is picked up by the TUI, its help screen and the CLI.
+func RegisterRenderer(r Renderer) error { return services.RegisterRenderer(r) }
+
+// NewRampRenderer builds a Renderer from a dark to bright glyph ramp.
+func NewRampRenderer(name, description, darkToBright string, edgeRunes EdgeRunes) (Renderer, error) {
+	return services.NewRampRenderer(name, description, darkToBright, edgeRunes)
+}
+
+// LookupRenderer finds a registered renderer by name.
+func LookupRenderer(name string) (Renderer, bool) { return services.LookupRenderer(name) }
+
+// RuneModes lists the registered rune modes in registration order.
+func RuneModes() []string { return services.RendererNames() }
+
+// Renderers lists the registered renderers in registration order.
+func Renderers() []Renderer { return services.Renderers() }
+
+// ColorModes lists the available color modes.
+func ColorModes() []string { return services.AvailableColorModes() }
+
+// ColorEstimators lists the available color estimators.
+func ColorEstimators() []string { return services.AvailableColorEstimators() }
+
+// ParseHexColor parses a #RGB / #RRGGBB color, the leading # is optional.
+func ParseHexColor(s string) (color.NRGBA, error) { return services.ParseHexColor(s) }
+
+// ParseHexColorList parses a comma separated list of #RGB / #RRGGBB colors.
+func ParseHexColorList(s string) ([]color.NRGBA, error) { return services.ParseHexColorList(s) }
@@ -0,0 +1,122 @@
+package mezzotone_test
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/joaoheitorgarcia/Mezzotone/mezzotone"
+)
+
+func gradientImage(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := uint8((x * 255) / max(1, width-1))
+			img.SetNRGBA(x, y, color.NRGBA{R: v, G: v, B: 255 - v, A: 255})
+		}
+	}
+	return img
+}
+
+func TestConvertUsesDefaultOptions(t *testing.T) {
+	res, err := mezzotone.Convert(gradientImage(100, 46))
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	// Default text size 10 and font aspect 2.3 give 10x2 cells.
+	if res.Cols() != 10 || res.Rows() != 2 {
+		t.Fatalf("expected 10x2 grid, got %dx%d", res.Cols(), res.Rows())
+	}
+	if res.HasColor() {
+		t.Fatalf("expected color to be off by default")
+	}
+	if got := res.String(); got != res.PlainText() {
+		t.Fatalf("expected String to equal PlainText without color, got %q vs %q", got, res.PlainText())
+	}
+}
+
+func TestConvertAppliesFunctionalOptions(t *testing.T) {
+	img := gradientImage(64, 16)
+
+	res, err := mezzotone.Convert(img,
+		mezzotone.WithTextSize(8),
+		mezzotone.WithFontAspect(1),
+		mezzotone.WithRuneMode("BARS"),
+		mezzotone.WithColorMode(mezzotone.ColorModeTint, color.NRGBA{R: 0x33, G: 0xFF, B: 0x66, A: 255}),
+	)
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if res.Cols() != 8 || res.Rows() != 2 {
+		t.Fatalf("expected 8x2 grid, got %dx%d", res.Cols(), res.Rows())
+	}
+	if !res.HasColor() || len(res.Colors()) != res.Rows() {
+		t.Fatalf("expected a color grid matching the rune grid")
+	}
+	if strings.ContainsAny(res.PlainText(), "$@#") {
+		t.Fatalf("expected BARS glyphs only, got %q", res.PlainText())
+	}
+}
+
+func TestConvertReturnsTypedErrors(t *testing.T) {
+	if _, err := mezzotone.Convert(nil); !errors.Is(err, mezzotone.ErrNilImage) {
+		t.Fatalf("expected ErrNilImage, got %v", err)
+	}
+
+	_, err := mezzotone.Convert(gradientImage(8, 8), mezzotone.WithRuneMode("NOPE"))
+	if !errors.Is(err, mezzotone.ErrInvalidOption) {
+		t.Fatalf("expected ErrInvalidOption, got %v", err)
+	}
+	var optionErr *mezzotone.OptionError
+	if !errors.As(err, &optionErr) || optionErr.Option != "RuneMode" {
+		t.Fatalf("expected *OptionError for RuneMode, got %#v", err)
+	}
+
+	_, err = mezzotone.Convert(gradientImage(8, 8), mezzotone.WithTextSize(0))
+	if !errors.As(err, &optionErr) || optionErr.Option != "TextSize" {
+		t.Fatalf("expected *OptionError for TextSize, got %#v", err)
+	}
+}
+
+func TestConvertContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := mezzotone.ConvertContext(ctx, gradientImage(64, 64), mezzotone.WithTextSize(4))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestOptionsGettersAndSetters(t *testing.T) {
+	opts := mezzotone.DefaultOptions()
+	opts.SetRuneMode("DOTS")
+	opts.SetColorMode(mezzotone.ColorModeDuotone, color.NRGBA{A: 255}, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	opts.SetVibrance(0.5)
+
+	if opts.RuneMode() != "DOTS" || opts.ColorMode() != mezzotone.ColorModeDuotone || opts.Vibrance() != 0.5 {
+		t.Fatalf("setters did not update options: %+v", opts)
+	}
+
+	stops := opts.ColorStops()
+	stops[0] = color.NRGBA{R: 9}
+	if opts.ColorStops()[0] == stops[0] {
+		t.Fatalf("expected ColorStops to return a copy")
+	}
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("expected valid options, got %v", err)
+	}
+
+	res, err := mezzotone.Convert(gradientImage(40, 40), mezzotone.WithOptions(opts), mezzotone.WithTextSize(20))
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if res.Cols() != 2 {
+		t.Fatalf("expected later options to override WithOptions, got %d cols", res.Cols())
+	}
+}
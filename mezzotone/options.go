@@ -0,0 +1,217 @@
+package mezzotone
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+
+	"github.com/joaoheitorgarcia/Mezzotone/internal/services"
+)
+
+// Color modes, see Options.SetColorMode.
+const (
+	ColorModeAverage  = services.ColorModeAverage
+	ColorModeTint     = services.ColorModeTint
+	ColorModeDuotone  = services.ColorModeDuotone
+	ColorModeGradient = services.ColorModeGradient
+)
+
+// Color estimators, see Options.SetColorEstimator.
+const (
+	ColorEstimatorMean      = string(services.ColorEstimatorMean)
+	ColorEstimatorMedian    = string(services.ColorEstimatorMedian)
+	ColorEstimatorSaturated = string(services.ColorEstimatorSaturated)
+	ColorEstimatorDominant  = string(services.ColorEstimatorDominant)
+	ColorEstimatorInk       = string(services.ColorEstimatorInk)
+)
+
+// Options holds every conversion setting. The zero value is not usable,
+// start from DefaultOptions (Convert does this for you).
+type Options struct {
+	textSize          int
+	fontAspect        float64
+	directionalRender bool
+	edgeThreshold     float64
+	reverseChars      bool
+	highContrast      bool
+	renderColor       bool
+	runeMode          string
+	colorEstimator    string
+	colorMode         string
+	colorStops        []color.NRGBA
+	saturation        float64
+	vibrance          float64
+}
+
+// DefaultOptions returns the same defaults the TUI starts with.
+func DefaultOptions() Options {
+	return Options{
+		textSize:       10,
+		fontAspect:     2.3,
+		edgeThreshold:  0.6,
+		reverseChars:   true,
+		highContrast:   true,
+		runeMode:       "ASCII",
+		colorEstimator: ColorEstimatorMean,
+		colorMode:      ColorModeAverage,
+		saturation:     1,
+	}
+}
+
+// TextSize is the width in source pixels of one character cell.
+func (o Options) TextSize() int { return o.textSize }
+
+func (o *Options) SetTextSize(v int) { o.textSize = v }
+
+// FontAspect is the character cell height divided by its width.
+func (o Options) FontAspect() float64 { return o.fontAspect }
+
+func (o *Options) SetFontAspect(v float64) { o.fontAspect = v }
+
+// DirectionalRender places oriented glyphs on strong edges.
+func (o Options) DirectionalRender() bool { return o.directionalRender }
+
+func (o *Options) SetDirectionalRender(v bool) { o.directionalRender = v }
+
+// EdgeThreshold is the 0..1 edge cutoff used by DirectionalRender.
+func (o Options) EdgeThreshold() float64 { return o.edgeThreshold }
+
+func (o *Options) SetEdgeThreshold(v float64) { o.edgeThreshold = v }
+
+// ReverseChars inverts the ramp direction.
+func (o Options) ReverseChars() bool { return o.reverseChars }
+
+func (o *Options) SetReverseChars(v bool) { o.reverseChars = v }
+
+// HighContrast applies a contrast curve to cell luminance.
+func (o Options) HighContrast() bool { return o.highContrast }
+
+func (o *Options) SetHighContrast(v bool) { o.highContrast = v }
+
+// RenderColor computes a color per cell.
+func (o Options) RenderColor() bool { return o.renderColor }
+
+func (o *Options) SetRenderColor(v bool) { o.renderColor = v }
+
+// RuneMode is the name of a registered Renderer, see RuneModes.
+func (o Options) RuneMode() string { return o.runeMode }
+
+func (o *Options) SetRuneMode(v string) { o.runeMode = v }
+
+// ColorEstimator is the statistic used to reduce a cell to one color.
+func (o Options) ColorEstimator() string { return o.colorEstimator }
+
+func (o *Options) SetColorEstimator(v string) { o.colorEstimator = v }
+
+// ColorMode maps cell colors: average, tint, duotone or gradient map.
+func (o Options) ColorMode() string { return o.colorMode }
+
+// ColorStops are the colors used by the tint, duotone and gradient modes.
+func (o Options) ColorStops() []color.NRGBA { return slices.Clone(o.colorStops) }
+
+// SetColorMode selects a color mode. Without stops the mode defaults are used.
+func (o *Options) SetColorMode(mode string, stops ...color.NRGBA) {
+	o.colorMode = mode
+	o.colorStops = slices.Clone(stops)
+}
+
+// Saturation scales average colors, 1 keeps them unchanged.
+func (o Options) Saturation() float64 { return o.saturation }
+
+func (o *Options) SetSaturation(v float64) { o.saturation = v }
+
+// Vibrance (-1..1) boosts dull average colors more than saturated ones.
+func (o Options) Vibrance() float64 { return o.vibrance }
+
+func (o *Options) SetVibrance(v float64) { o.vibrance = v }
+
+// Validate reports the first invalid setting as an *OptionError.
+func (o Options) Validate() error {
+	_, err := o.renderOptions()
+	return err
+}
+
+func (o Options) renderOptions() (services.RenderOptions, error) {
+	if o.textSize <= 0 {
+		return services.RenderOptions{}, &OptionError{Option: "TextSize", Value: o.textSize, Err: fmt.Errorf("must be > 0")}
+	}
+	if o.fontAspect <= 0 {
+		return services.RenderOptions{}, &OptionError{Option: "FontAspect", Value: o.fontAspect, Err: fmt.Errorf("must be > 0")}
+	}
+	if o.edgeThreshold < 0 || o.edgeThreshold > 1 {
+		return services.RenderOptions{}, &OptionError{Option: "EdgeThreshold", Value: o.edgeThreshold, Err: fmt.Errorf("must be between 0 and 1")}
+	}
+
+	renderOptions, err := services.NewRenderOptions(
+		o.textSize,
+		o.fontAspect,
+		o.directionalRender,
+		o.edgeThreshold,
+		o.reverseChars,
+		o.highContrast,
+		o.renderColor,
+		o.runeMode,
+	)
+	if err != nil {
+		return services.RenderOptions{}, &OptionError{Option: "RuneMode", Value: o.runeMode, Err: err}
+	}
+
+	estimator, err := services.ParseColorEstimator(o.colorEstimator)
+	if err != nil {
+		return services.RenderOptions{}, &OptionError{Option: "ColorEstimator", Value: o.colorEstimator, Err: err}
+	}
+	renderOptions.ColorEstimator = estimator
+
+	colorStyle, err := services.NewColorStyle(o.colorMode, o.colorStops, o.saturation, o.vibrance)
+	if err != nil {
+		return services.RenderOptions{}, &OptionError{Option: "ColorMode", Value: o.colorMode, Err: err}
+	}
+	renderOptions.ColorStyle = colorStyle
+
+	return renderOptions, nil
+}
+
+// Option changes one setting of Options, see Convert.
+type Option func(*Options)
+
+// WithOptions replaces every setting with o, later options still apply on top.
+func WithOptions(o Options) Option {
+	return func(opts *Options) {
+		*opts = o
+		opts.colorStops = slices.Clone(o.colorStops)
+	}
+}
+
+func WithTextSize(v int) Option { return func(o *Options) { o.SetTextSize(v) } }
+
+func WithFontAspect(v float64) Option { return func(o *Options) { o.SetFontAspect(v) } }
+
+// WithDirectionalRender enables edge glyphs above the given 0..1 threshold.
+func WithDirectionalRender(threshold float64) Option {
+	return func(o *Options) {
+		o.SetDirectionalRender(true)
+		o.SetEdgeThreshold(threshold)
+	}
+}
+
+func WithReverseChars(v bool) Option { return func(o *Options) { o.SetReverseChars(v) } }
+
+func WithHighContrast(v bool) Option { return func(o *Options) { o.SetHighContrast(v) } }
+
+func WithColor(v bool) Option { return func(o *Options) { o.SetRenderColor(v) } }
+
+func WithRuneMode(v string) Option { return func(o *Options) { o.SetRuneMode(v) } }
+
+func WithColorEstimator(v string) Option { return func(o *Options) { o.SetColorEstimator(v) } }
+
+// WithColorMode enables color and selects a color mode with optional stops.
+func WithColorMode(mode string, stops ...color.NRGBA) Option {
+	return func(o *Options) {
+		o.SetRenderColor(true)
+		o.SetColorMode(mode, stops...)
+	}
+}
+
+func WithSaturation(v float64) Option { return func(o *Options) { o.SetSaturation(v) } }
+
+func WithVibrance(v float64) Option { return func(o *Options) { o.SetVibrance(v) } }